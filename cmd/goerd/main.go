@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/covrom/goerd"
+	"github.com/covrom/goerd/schema"
 	_ "github.com/jackc/pgx/v4/stdlib"
 )
 
@@ -18,6 +19,10 @@ var (
 	command = flag.String("c", "print", "command: 'print' - stdout print diff queries, 'apply' - apply diff queries to target database ('to')")
 	dist    = flag.Int("d", 2, "max relations distance for plant uml")
 	drop    = flag.Bool("drop", false, "drop tables or columns when applying migration")
+	ns      = flag.String("ns", "", "target namespace (postgres schema) for all objects of the migrated schema, only this namespace is compared")
+	renames = flag.Bool("renames", false, "warn about dropped and added tables or columns that are probably renamed")
+	noData  = flag.Bool("nodata", false, "create materialized views WITH NO DATA and refresh them at the end of migration")
+	noCmnt  = flag.Bool("nocomments", false, "hide comments of tables, columns and indexes in plant uml")
 )
 
 func main() {
@@ -59,25 +64,12 @@ func main() {
 		}
 		f.Close()
 
-		qs, err := goerd.GenerateMigrationSQL(src, dst)
+		plan, err := goerd.GenerateMigrationPlan(src, dst, migrationOptions()...)
 		if err != nil {
 			log.Fatal(err)
 		}
 		if cmdIsPrint {
-			for _, q := range qs {
-				if !*drop {
-					if strings.HasPrefix(strings.ToUpper(q), "DROP") {
-						fmt.Println("--", q)
-						continue
-					}
-					if strings.Contains(strings.ToUpper(q), "DROP COLUMN") ||
-						strings.Contains(strings.ToUpper(q), "DROP CONSTRAINT") {
-						fmt.Println("--", q)
-						continue
-					}
-				}
-				fmt.Println(q)
-			}
+			printPlan(plan)
 		} else if cmdIsApply {
 			log.Fatal("cant apply diffs between two yaml schemas, only print allowed")
 		} else {
@@ -101,7 +93,7 @@ func main() {
 			log.Fatal(err)
 		}
 
-		if err := goerd.SchemaToPlantUML(src, wr, *dist); err != nil {
+		if err := goerd.SchemaToPlantUML(src, wr, *dist, plantUMLOptions()...); err != nil {
 			wr.Close()
 			log.Fatal(err)
 		}
@@ -125,59 +117,16 @@ func main() {
 			log.Fatal(err)
 		}
 
-		qs, err := goerd.GenerateMigrationSQL(src, dst)
+		plan, err := goerd.GenerateMigrationPlan(src, dst, migrationOptions()...)
 		if err != nil {
 			log.Fatal(err)
 		}
 		if cmdIsPrint {
-			for _, q := range qs {
-				if !*drop {
-					if strings.HasPrefix(strings.ToUpper(q), "DROP") {
-						fmt.Println("--", q)
-						continue
-					}
-					if strings.Contains(strings.ToUpper(q), "DROP COLUMN") ||
-						strings.Contains(strings.ToUpper(q), "DROP CONSTRAINT") {
-						fmt.Println("--", q)
-						continue
-					}
-				}
-				fmt.Println(q)
-			}
+			printPlan(plan)
 		} else if cmdIsApply {
-			db, err := sql.Open("pgx", *to)
-			if err != nil {
-				log.Fatal(err)
-			}
-			tx, err := db.Begin()
-			if err != nil {
-				log.Fatal(err)
-			}
-			for _, q := range qs {
-				if !*drop {
-					if strings.HasPrefix(strings.ToUpper(q), "DROP") {
-						fmt.Println("--", q)
-						continue
-					}
-					if strings.Contains(strings.ToUpper(q), "DROP COLUMN") ||
-						strings.Contains(strings.ToUpper(q), "DROP CONSTRAINT") {
-						fmt.Println("--", q)
-						continue
-					}
-				}
-				fmt.Println(q)
-				_, err = tx.Exec(q)
-				if err != nil {
-					_ = tx.Rollback()
-					db.Close()
-					log.Fatal(err)
-				}
-			}
-			if err = tx.Commit(); err != nil {
-				db.Close()
+			if err := applyPlan(*to, plan); err != nil {
 				log.Fatal(err)
 			}
-			db.Close()
 		} else {
 			log.Fatal("wrong command")
 		}
@@ -209,7 +158,7 @@ func main() {
 			log.Fatal(err)
 		}
 
-		if err := goerd.SchemaToPlantUML(src, wr, *dist); err != nil {
+		if err := goerd.SchemaToPlantUML(src, wr, *dist, plantUMLOptions()...); err != nil {
 			wr.Close()
 			log.Fatal(err)
 		}
@@ -225,59 +174,16 @@ func main() {
 		if err != nil {
 			log.Fatal(err)
 		}
-		qs, err := goerd.GenerateMigrationSQL(src, dst)
+		plan, err := goerd.GenerateMigrationPlan(src, dst, migrationOptions()...)
 		if err != nil {
 			log.Fatal(err)
 		}
 		if cmdIsPrint {
-			for _, q := range qs {
-				if !*drop {
-					if strings.HasPrefix(strings.ToUpper(q), "DROP") {
-						fmt.Println("--", q)
-						continue
-					}
-					if strings.Contains(strings.ToUpper(q), "DROP COLUMN") ||
-						strings.Contains(strings.ToUpper(q), "DROP CONSTRAINT") {
-						fmt.Println("--", q)
-						continue
-					}
-				}
-				fmt.Println(q)
-			}
+			printPlan(plan)
 		} else if cmdIsApply {
-			db, err := sql.Open("pgx", *to)
-			if err != nil {
-				log.Fatal(err)
-			}
-			tx, err := db.Begin()
-			if err != nil {
-				log.Fatal(err)
-			}
-			for _, q := range qs {
-				if !*drop {
-					if strings.HasPrefix(strings.ToUpper(q), "DROP") {
-						fmt.Println("--", q)
-						continue
-					}
-					if strings.Contains(strings.ToUpper(q), "DROP COLUMN") ||
-						strings.Contains(strings.ToUpper(q), "DROP CONSTRAINT") {
-						fmt.Println("--", q)
-						continue
-					}
-				}
-				fmt.Println(q)
-				_, err = tx.Exec(q)
-				if err != nil {
-					_ = tx.Rollback()
-					db.Close()
-					log.Fatal(err)
-				}
-			}
-			if err = tx.Commit(); err != nil {
-				db.Close()
+			if err := applyPlan(*to, plan); err != nil {
 				log.Fatal(err)
 			}
-			db.Close()
 		} else {
 			log.Fatal("wrong command")
 		}
@@ -285,3 +191,76 @@ func main() {
 		flag.Usage()
 	}
 }
+
+func migrationOptions() []goerd.MigrationOption {
+	var opts []goerd.MigrationOption
+	if *ns != "" {
+		opts = append(opts, goerd.WithTargetNamespace(*ns))
+	}
+	if *renames {
+		opts = append(opts, goerd.WithRenameDetection())
+	}
+	if *noData {
+		opts = append(opts, goerd.WithMatViewNoData())
+	}
+	return opts
+}
+
+func plantUMLOptions() []goerd.PlantUMLOption {
+	var opts []goerd.PlantUMLOption
+	if *noCmnt {
+		opts = append(opts, goerd.WithoutComments())
+	}
+	return opts
+}
+
+// printPlan prints plan queries, destructive queries are commented out without -drop flag
+func printPlan(plan *schema.Plan) {
+	for _, w := range plan.Warnings {
+		fmt.Println("-- warning:", w)
+	}
+	for _, op := range plan.Operations {
+		if op.Destructive && !*drop {
+			fmt.Println("--", op.SQL)
+			continue
+		}
+		fmt.Println(op.SQL)
+	}
+}
+
+// applyPlan executes plan queries in transaction, destructive queries are skipped without -drop flag,
+// queries that can not run inside a transaction block are executed between transactions
+func applyPlan(dsn string, plan *schema.Plan) error {
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	printPlan(plan)
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, op := range plan.Operations {
+		if op.Destructive && !*drop {
+			continue
+		}
+		if op.OutsideTx {
+			if err = tx.Commit(); err != nil {
+				return err
+			}
+			if _, err = db.Exec(op.SQL); err != nil {
+				return err
+			}
+			if tx, err = db.Begin(); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err = tx.Exec(op.SQL); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -3,6 +3,7 @@ package postgres
 import (
 	"database/sql"
 	"fmt"
+	"reflect"
 	"regexp"
 	"strings"
 
@@ -11,9 +12,17 @@ import (
 	"github.com/pkg/errors"
 )
 
-var reFK = regexp.MustCompile(`(?is)FOREIGN\s+KEY\s*\((.+)\)\s*REFERENCES\s+(\S+)\s*\((.+)\)(\s*ON\s+DELETE\s+((CASCADE)|(RESTRICT)|(NO\s+ACTION)|(SET\s+NULL)|(SET\s+DEFAULT)))?`)
+var reFK = regexp.MustCompile(`(?is)FOREIGN\s+KEY\s*\((.+)\)\s*REFERENCES\s+(\S+)\s*\((.+)\)`)
 var reChk = regexp.MustCompile(`(?is)CHECK\s+\((.+)\)\s*$`)
 
+// reIdent matches the identifier that may be quoted and schema-qualified
+const reIdent = `(?:"(?:[^"]|"")+"|[^\s."]+)(?:\.(?:"(?:[^"]|"")+"|[^\s."]+))?`
+
+var reTrigger = regexp.MustCompile(`(?s)^CREATE (CONSTRAINT )?TRIGGER ` + reIdent + ` (BEFORE|AFTER|INSTEAD OF) (.+?) ON ` + reIdent +
+	`(?: FROM (` + reIdent + `))?(?: (NOT DEFERRABLE|DEFERRABLE))?(?: INITIALLY (IMMEDIATE|DEFERRED))?` +
+	`(?: REFERENCING(?: OLD TABLE AS (` + reIdent + `))?(?: NEW TABLE AS (` + reIdent + `))?)?` +
+	` FOR EACH (ROW|STATEMENT)(?: WHEN \((.*)\))? EXECUTE (?:FUNCTION|PROCEDURE) (.+)$`)
+
 // Postgres struct
 type Postgres struct {
 	db *sql.DB
@@ -58,7 +67,287 @@ func (p *Postgres) Analyze(s *schema.Schema) error {
 	}
 	s.SearchPaths = strings.Split(searchPaths, ", ")
 
-	fullTableNames := []string{}
+	// namespaces
+	nsRows, err := p.db.Query(qNamespaces)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer nsRows.Close()
+
+	namespaces := []*schema.Namespace{}
+	for nsRows.Next() {
+		var (
+			nsOid     uint32
+			nsName    string
+			nsComment sql.NullString
+			nsOwner   string
+		)
+		err := nsRows.Scan(&nsOid, &nsName, &nsComment, &nsOwner)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		nsGrants, err := p.queryGrants(qNamespaceGrants, nsOid)
+		if err != nil {
+			return err
+		}
+		namespaces = append(namespaces, &schema.Namespace{
+			Name:    nsName,
+			Comment: nsComment.String,
+			Owner:   nsOwner,
+			Grants:  nsGrants,
+		})
+	}
+	s.Namespaces = namespaces
+
+	// roles are not migrated, the roles of target schema are checked
+	roleRows, err := p.db.Query(qRoles)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer roleRows.Close()
+
+	roles := []string{}
+	for roleRows.Next() {
+		var roleName string
+		if err := roleRows.Scan(&roleName); err != nil {
+			return errors.WithStack(err)
+		}
+		roles = append(roles, roleName)
+	}
+	s.Roles = roles
+
+	// extensions, the objects of extensions are not inspected
+	extRows, err := p.db.Query(qExtensions)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer extRows.Close()
+
+	extensions := []*schema.Extension{}
+	for extRows.Next() {
+		var extName, extVersion, extSchema string
+		if err := extRows.Scan(&extName, &extVersion, &extSchema); err != nil {
+			return errors.WithStack(err)
+		}
+		extensions = append(extensions, &schema.Extension{
+			Name:      extName,
+			Version:   extVersion,
+			Namespace: extSchema,
+		})
+	}
+	s.Extensions = extensions
+
+	// enums
+	enumRows, err := p.db.Query(qEnums)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer enumRows.Close()
+
+	enums := []*schema.Enum{}
+	for enumRows.Next() {
+		var (
+			enumName    string
+			enumSchema  string
+			enumValues  NullStringArray
+			enumComment sql.NullString
+		)
+		err := enumRows.Scan(&enumName, &enumSchema, &enumValues, &enumComment)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		enums = append(enums, &schema.Enum{
+			Namespace: enumSchema,
+			Name:      enumName,
+			Values:    arrayRemoveNull(enumValues),
+			Comment:   enumComment.String,
+		})
+	}
+	s.Enums = enums
+
+	// domains
+	domainRows, err := p.db.Query(qDomains)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer domainRows.Close()
+
+	domains := []*schema.Domain{}
+	for domainRows.Next() {
+		var (
+			domainName     string
+			domainSchema   string
+			domainBaseType string
+			domainNotNull  bool
+			domainDefault  sql.NullString
+			checkNames     NullStringArray
+			checkDefs      NullStringArray
+			domainComment  sql.NullString
+		)
+		err := domainRows.Scan(&domainName, &domainSchema, &domainBaseType, &domainNotNull, &domainDefault,
+			&checkNames, &checkDefs, &domainComment)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		domain := &schema.Domain{
+			Namespace: domainSchema,
+			Name:      domainName,
+			BaseType:  domainBaseType,
+			NotNull:   domainNotNull,
+			Default:   domainDefault,
+			Comment:   domainComment.String,
+		}
+		names, defs := arrayRemoveNull(checkNames), arrayRemoveNull(checkDefs)
+		for i := 0; i < len(names) && i < len(defs); i++ {
+			expr := defs[i]
+			if ss := reChk.FindStringSubmatch(expr); len(ss) > 1 {
+				expr = ss[1]
+			}
+			domain.Checks = append(domain.Checks, &schema.DomainCheck{Name: names[i], Expr: expr})
+		}
+		domains = append(domains, domain)
+	}
+	s.Domains = domains
+
+	// composite types
+	compositeRows, err := p.db.Query(qComposites)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer compositeRows.Close()
+
+	composites := []*schema.CompositeType{}
+	for compositeRows.Next() {
+		var (
+			typeName       string
+			typeSchema     string
+			attributeNames NullStringArray
+			attributeTypes NullStringArray
+			typeComment    sql.NullString
+		)
+		err := compositeRows.Scan(&typeName, &typeSchema, &attributeNames, &attributeTypes, &typeComment)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		composite := &schema.CompositeType{
+			Namespace: typeSchema,
+			Name:      typeName,
+			Comment:   typeComment.String,
+		}
+		names, types := arrayRemoveNull(attributeNames), arrayRemoveNull(attributeTypes)
+		for i := 0; i < len(names) && i < len(types); i++ {
+			composite.Attributes = append(composite.Attributes, &schema.Attribute{Name: names[i], Type: types[i]})
+		}
+		composites = append(composites, composite)
+	}
+	s.Composites = composites
+
+	// sequences
+	seqRows, err := p.db.Query(qSequences)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer seqRows.Close()
+
+	sequences := []*schema.Sequence{}
+	for seqRows.Next() {
+		var (
+			seqOid       uint32
+			seqName      string
+			seqSchema    string
+			seqDataType  string
+			seqStart     int64
+			seqIncrement int64
+			seqMin       int64
+			seqMax       int64
+			seqCycle     bool
+			seqCache     int64
+			ownerTable   sql.NullString
+			ownerColumn  sql.NullString
+			seqComment   sql.NullString
+			seqOwner     string
+		)
+		err := seqRows.Scan(&seqOid, &seqName, &seqSchema, &seqDataType,
+			&seqStart, &seqIncrement, &seqMin, &seqMax, &seqCycle, &seqCache,
+			&ownerTable, &ownerColumn, &seqComment, &seqOwner)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		seqGrants, err := p.queryGrants(qRelationGrants, seqOid)
+		if err != nil {
+			return err
+		}
+		sequence := &schema.Sequence{
+			Namespace: seqSchema,
+			Name:      seqName,
+			DataType:  seqDataType,
+			Start:     sql.NullInt64{Int64: seqStart, Valid: true},
+			Increment: seqIncrement,
+			MinValue:  sql.NullInt64{Int64: seqMin, Valid: true},
+			MaxValue:  sql.NullInt64{Int64: seqMax, Valid: true},
+			Cycle:     seqCycle,
+			Cache:     seqCache,
+			Comment:   seqComment.String,
+			Owner:     seqOwner,
+			Grants:    seqGrants,
+		}
+		// owner table is always in the sequence namespace
+		if ownerTable.Valid && ownerColumn.Valid {
+			sequence.OwnedBy = fmt.Sprintf("%s.%s", ownerTable.String, ownerColumn.String)
+		}
+		sequences = append(sequences, sequence)
+	}
+	s.Sequences = sequences
+
+	// functions
+	funcRows, err := p.db.Query(qFunctions)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer funcRows.Close()
+
+	functions := []*schema.Function{}
+	for funcRows.Next() {
+		var (
+			funcName            string
+			funcSchema          string
+			funcArguments       string
+			funcResult          string
+			funcLanguage        string
+			funcVolatility      string
+			funcSecurityDefiner bool
+			funcStrict          bool
+			funcLeakproof       bool
+			funcParallel        string
+			funcCost            float64
+			funcConfig          NullStringArray
+			funcBody            string
+			funcComment         sql.NullString
+		)
+		err := funcRows.Scan(&funcName, &funcSchema, &funcArguments, &funcResult, &funcLanguage,
+			&funcVolatility, &funcSecurityDefiner, &funcStrict, &funcLeakproof, &funcParallel, &funcCost,
+			&funcConfig, &funcBody, &funcComment)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		functions = append(functions, &schema.Function{
+			Namespace:       funcSchema,
+			Name:            funcName,
+			Arguments:       funcArguments,
+			Returns:         funcResult,
+			Language:        funcLanguage,
+			Volatility:      convertVolatility(funcVolatility),
+			SecurityDefiner: funcSecurityDefiner,
+			Strict:          funcStrict,
+			Leakproof:       funcLeakproof,
+			Parallel:        convertParallel(funcParallel),
+			Cost:            convertCost(funcCost),
+			Config:          arrayRemoveNull(funcConfig),
+			Body:            funcBody,
+			Comment:         funcComment.String,
+		})
+	}
+	s.Functions = functions
 
 	// tables
 	tableRows, err := p.db.Query(qTables)
@@ -77,23 +366,50 @@ func (p *Postgres) Analyze(s *schema.Schema) error {
 			tableType    string
 			tableSchema  string
 			tableComment sql.NullString
+			partitionKey sql.NullString
+			rowSecurity  bool
+			forceRLS     bool
+			tableOwner   string
 		)
-		err := tableRows.Scan(&tableOid, &tableName, &tableType, &tableSchema, &tableComment)
+		err := tableRows.Scan(&tableOid, &tableName, &tableType, &tableSchema, &tableComment, &partitionKey,
+			&rowSecurity, &forceRLS, &tableOwner)
 		if err != nil {
 			return errors.WithStack(err)
 		}
-
-		name := tableName
-		if tableSchema != currentSchema {
-			name = fmt.Sprintf("%s.%s", tableSchema, tableName)
+		tableGrants, err := p.queryGrants(qRelationGrants, tableOid)
+		if err != nil {
+			return err
 		}
 
-		fullTableNames = append(fullTableNames, fmt.Sprintf("%s.%s", tableSchema, tableName))
-
 		table := &schema.Table{
-			Name:    name,
-			Type:    tableType,
-			Comment: tableComment.String,
+			Namespace:        tableSchema,
+			Name:             tableName,
+			Type:             tableType,
+			Comment:          tableComment.String,
+			RowSecurity:      rowSecurity,
+			ForceRowSecurity: forceRLS,
+			Owner:            tableOwner,
+			Grants:           tableGrants,
+		}
+
+		// partitions are not inspected as tables, their indexes and constraints are inherited from the table
+		if partitionKey.Valid {
+			table.PartitionBy = partitionKey.String
+			partRows, err := p.db.Query(qPartitions, tableOid)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			defer partRows.Close()
+			for partRows.Next() {
+				var partName, partBound string
+				if err := partRows.Scan(&partName, &partBound); err != nil {
+					return errors.WithStack(err)
+				}
+				table.Partitions = append(table.Partitions, &schema.Partition{
+					Name:  partName,
+					Bound: partBound,
+				})
+			}
 		}
 
 		// (materialized) view definition
@@ -112,6 +428,39 @@ func (p *Postgres) Analyze(s *schema.Schema) error {
 				//  fmt.Sprintf("CREATE %s %s AS (\n%s\n)", tableType, tableName, strings.TrimRight(tableDef.String, ";"))
 				table.Def = strings.TrimRight(tableDef.String, ";")
 			}
+
+			depRows, err := p.db.Query(qViewDependencies, tableOid)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			defer depRows.Close()
+			for depRows.Next() {
+				var depName, depSchema string
+				if err := depRows.Scan(&depName, &depSchema); err != nil {
+					return errors.WithStack(err)
+				}
+				table.DependsOn = append(table.DependsOn, schema.QualifiedName(depSchema, depName))
+			}
+
+			colDepRows, err := p.db.Query(qViewColumnDependencies, tableOid)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			defer colDepRows.Close()
+			for colDepRows.Next() {
+				var (
+					depName, depSchema, colName string
+					colNum                      int
+				)
+				if err := colDepRows.Scan(&depName, &depSchema, &colName, &colNum); err != nil {
+					return errors.WithStack(err)
+				}
+				if table.DependsOnColumns == nil {
+					table.DependsOnColumns = map[string][]string{}
+				}
+				dep := schema.QualifiedName(depSchema, depName)
+				table.DependsOnColumns[dep] = append(table.DependsOnColumns[dep], colName)
+			}
 		}
 
 		// constraints
@@ -129,15 +478,29 @@ func (p *Postgres) Analyze(s *schema.Schema) error {
 				constraintDef                  string
 				constraintType                 string
 				constraintReferenceTable       sql.NullString
+				constraintReferenceSchema      sql.NullString
 				constraintColumnNames          NullStringArray
 				constraintReferenceColumnNames NullStringArray
 				constraintComment              sql.NullString
+				constraintOnDelete             string
+				constraintOnUpdate             string
+				constraintMatch                string
+				constraintDeferrable           bool
+				constraintDeferred             bool
+				constraintNotValid             bool
 			)
 			err = constraintRows.Scan(&constraintName, &constraintDef, &constraintType,
 				&constraintReferenceTable,
+				&constraintReferenceSchema,
 				&constraintColumnNames,
 				&constraintReferenceColumnNames,
-				&constraintComment)
+				&constraintComment,
+				&constraintOnDelete,
+				&constraintOnUpdate,
+				&constraintMatch,
+				&constraintDeferrable,
+				&constraintDeferred,
+				&constraintNotValid)
 			if err != nil {
 				return errors.WithStack(err)
 			}
@@ -163,15 +526,22 @@ func (p *Postgres) Analyze(s *schema.Schema) error {
 			}
 
 			if constraintType == "f" {
-				ss = reFK.FindStringSubmatch(constraintDef)
-				if len(ss) > 0 {
-					constraint.OnDelete = strings.TrimSpace(ss[5])
-				}
+				constraint.OnDelete = convertFKAction(constraintOnDelete)
 				relation := &schema.Relation{
-					Name:     constraintName,
-					Table:    table,
-					OnDelete: constraint.OnDelete,
-					Def:      constraintDef,
+					Name:  constraintName,
+					Table: table,
+					ParentTable: &schema.Table{
+						Namespace: constraintReferenceSchema.String,
+						Name:      constraintReferenceTable.String,
+					},
+					OnDelete:          constraint.OnDelete,
+					OnUpdate:          convertFKAction(constraintOnUpdate),
+					Match:             convertFKMatch(constraintMatch),
+					Deferrable:        constraintDeferrable,
+					InitiallyDeferred: constraintDeferred,
+					NotValid:          constraintNotValid,
+					Def:               constraintDef,
+					Comment:           constraint.Comment,
 				}
 				relations = append(relations, relation)
 			} else {
@@ -195,8 +565,18 @@ func (p *Postgres) Analyze(s *schema.Schema) error {
 				isNullable    bool
 				dataType      string
 				columnComment sql.NullString
+				identity      string
+				generated     string
+				seqStart      sql.NullInt64
+				seqIncrement  sql.NullInt64
+				seqMin        sql.NullInt64
+				seqMax        sql.NullInt64
+				seqCycle      sql.NullBool
+				seqCache      sql.NullInt64
 			)
-			err = columnRows.Scan(&columnName, &columnDefault, &isNullable, &dataType, &columnComment)
+			err = columnRows.Scan(&columnName, &columnDefault, &isNullable, &dataType, &columnComment,
+				&identity, &generated,
+				&seqStart, &seqIncrement, &seqMin, &seqMax, &seqCycle, &seqCache)
 			if err != nil {
 				return errors.WithStack(err)
 			}
@@ -207,6 +587,30 @@ func (p *Postgres) Analyze(s *schema.Schema) error {
 				Default:  columnDefault,
 				Comment:  columnComment.String,
 			}
+			if generated == "s" {
+				column.Generated = columnDefault.String
+				column.Default = sql.NullString{}
+			}
+			if identity == "a" || identity == "d" {
+				column.Identity = &schema.Identity{
+					Generation: schema.IdentityAlways,
+				}
+				if identity == "d" {
+					column.Identity.Generation = schema.IdentityByDefault
+				}
+				column.Identity.Sequence = &schema.Sequence{
+					Start:     seqStart,
+					Increment: seqIncrement.Int64,
+					MinValue:  seqMin,
+					MaxValue:  seqMax,
+					Cycle:     seqCycle.Bool,
+					Cache:     seqCache.Int64,
+				}
+				// omit default options
+				if reflect.DeepEqual(column.IdentitySequence(), (&schema.Column{Type: dataType}).IdentitySequence()) {
+					column.Identity.Sequence = nil
+				}
+			}
 			// find in pk's
 			for _, cstr := range constraints {
 				if cstr.Type != schema.TypePK {
@@ -267,7 +671,7 @@ func (p *Postgres) Analyze(s *schema.Schema) error {
 				Columns:      arrayRemoveNull(indexColumnNames),
 				Comment:      indexComment.String,
 			}
-			if strings.ReplaceAll(idxprs.ColDef, " ", "") == "("+strings.Join(index.Columns, ",")+")" {
+			if idxprs.ColDef == "("+strings.Join(quoteIdents(index.Columns), ", ")+")" {
 				index.ColDef = ""
 			}
 			if index.MethodName == "btree" &&
@@ -289,6 +693,65 @@ func (p *Postgres) Analyze(s *schema.Schema) error {
 		}
 		table.Indexes = indexes
 
+		// triggers
+		triggerRows, err := p.db.Query(qTriggers, tableOid)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer triggerRows.Close()
+
+		for triggerRows.Next() {
+			var (
+				triggerName    string
+				triggerDef     string
+				triggerComment sql.NullString
+			)
+			if err := triggerRows.Scan(&triggerName, &triggerDef, &triggerComment); err != nil {
+				return errors.WithStack(err)
+			}
+			trigger := parseTriggerDef(triggerDef)
+			trigger.Name = triggerName
+			trigger.Comment = triggerComment.String
+			table.Triggers = append(table.Triggers, trigger)
+		}
+
+		// row level security policies
+		policyRows, err := p.db.Query(qPolicies, tableOid)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer policyRows.Close()
+
+		for policyRows.Next() {
+			var (
+				policyName        string
+				policyCommand     string
+				policyRestrictive bool
+				policyRoles       NullStringArray
+				policyUsing       sql.NullString
+				policyWithCheck   sql.NullString
+				policyComment     sql.NullString
+			)
+			err := policyRows.Scan(&policyName, &policyCommand, &policyRestrictive, &policyRoles,
+				&policyUsing, &policyWithCheck, &policyComment)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+			roles := arrayRemoveNull(policyRoles)
+			if len(roles) == 1 && roles[0] == "public" {
+				roles = nil
+			}
+			table.Policies = append(table.Policies, &schema.Policy{
+				Name:        policyName,
+				Command:     convertPolicyCommand(policyCommand),
+				Roles:       roles,
+				Using:       policyUsing.String,
+				WithCheck:   policyWithCheck.String,
+				Restrictive: policyRestrictive,
+				Comment:     policyComment.String,
+			})
+		}
+
 		tables = append(tables, table)
 	}
 
@@ -300,15 +763,8 @@ func (p *Postgres) Analyze(s *schema.Schema) error {
 		if len(result) == 0 {
 			continue
 		}
-		strColumns := []string{}
-		for _, c := range strings.Split(result[1], ", ") {
-			strColumns = append(strColumns, strings.ReplaceAll(c, `"`, ""))
-		}
-		strParentTable := strings.ReplaceAll(result[2], `"`, "")
-		strParentColumns := []string{}
-		for _, c := range strings.Split(result[3], ", ") {
-			strParentColumns = append(strParentColumns, strings.ReplaceAll(c, `"`, ""))
-		}
+		strColumns := splitIdents(result[1])
+		strParentColumns := splitIdents(result[3])
 		for _, c := range strColumns {
 			column, err := r.Table.FindColumnByName(c)
 			if err != nil {
@@ -318,12 +774,7 @@ func (p *Postgres) Analyze(s *schema.Schema) error {
 			column.ParentRelations = append(column.ParentRelations, r)
 		}
 
-		dn, err := detectFullTableName(strParentTable, s.SearchPaths, fullTableNames)
-		if err != nil {
-			return err
-		}
-		strParentTable = dn
-		parentTable, err := s.FindTableByName(strParentTable)
+		parentTable, err := s.FindTable(r.ParentTable.Namespace, r.ParentTable.Name)
 		if err != nil {
 			return err
 		}
@@ -343,6 +794,39 @@ func (p *Postgres) Analyze(s *schema.Schema) error {
 	return nil
 }
 
+// splitIdents splits the list of identifiers, that are quoted
+// by PostgreSQL when needed, and returns them unquoted with exact case
+func splitIdents(s string) []string {
+	ret := []string{}
+	sb := &strings.Builder{}
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == '"' && quoted && i+1 < len(s) && s[i+1] == '"':
+			sb.WriteByte('"')
+			i++
+		case ch == '"':
+			quoted = !quoted
+		case ch == ',' && !quoted:
+			ret = append(ret, sb.String())
+			sb.Reset()
+		case ch == ' ' && !quoted:
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	return append(ret, sb.String())
+}
+
+func quoteIdents(names []string) []string {
+	ret := make([]string, len(names))
+	for i, n := range names {
+		ret[i] = schema.QuoteIdent(n)
+	}
+	return ret
+}
+
 // arrayRemoveNull
 func arrayRemoveNull(in []NullString) []string {
 	out := []string{}
@@ -354,27 +838,6 @@ func arrayRemoveNull(in []NullString) []string {
 	return out
 }
 
-func detectFullTableName(name string, searchPaths, fullTableNames []string) (string, error) {
-	if strings.Contains(name, ".") {
-		return name, nil
-	}
-	fns := []string{}
-	for _, n := range fullTableNames {
-		if strings.HasSuffix(n, name) {
-			for _, p := range searchPaths {
-				// TODO: Support $user
-				if n == fmt.Sprintf("%s.%s", p, name) {
-					fns = append(fns, n)
-				}
-			}
-		}
-	}
-	if len(fns) != 1 {
-		return "", errors.Errorf("can not detect table name: %s", name)
-	}
-	return fns[0], nil
-}
-
 func convertConstraintType(t string) string {
 	switch t {
 	case "p":
@@ -391,3 +854,160 @@ func convertConstraintType(t string) string {
 		return t
 	}
 }
+
+// convertVolatility returns the volatility of pg_proc provolatile, the default VOLATILE is empty
+func convertVolatility(t string) string {
+	switch t {
+	case "i":
+		return "IMMUTABLE"
+	case "s":
+		return "STABLE"
+	default:
+		return ""
+	}
+}
+
+// convertParallel returns the parallel mode of pg_proc proparallel, the default UNSAFE is empty
+func convertParallel(t string) string {
+	switch t {
+	case "s":
+		return "SAFE"
+	case "r":
+		return "RESTRICTED"
+	default:
+		return ""
+	}
+}
+
+// convertCost returns the cost of function, the default cost of SQL and procedural languages is zero
+func convertCost(cost float64) float64 {
+	if cost == schema.DefaultFunctionCost {
+		return 0
+	}
+	return cost
+}
+
+// queryGrants returns the grants of object by roles from the rows of grantee, privilege and column,
+// the column privileges are grouped, e.g. UPDATE (name, email)
+func (p *Postgres) queryGrants(query string, oid uint32) ([]*schema.Grant, error) {
+	rows, err := p.db.Query(query, oid)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+
+	grants := []*schema.Grant{}
+	var (
+		grant   *schema.Grant
+		columns []string
+		colPriv string
+	)
+	flush := func() {
+		if len(columns) > 0 {
+			grant.Privileges = append(grant.Privileges, fmt.Sprintf("%s (%s)", colPriv, strings.Join(columns, ", ")))
+			columns = nil
+		}
+	}
+	for rows.Next() {
+		var (
+			grantee   string
+			privilege string
+			column    sql.NullString
+		)
+		if err := rows.Scan(&grantee, &privilege, &column); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if grant == nil || grant.Role != grantee {
+			if grant != nil {
+				flush()
+			}
+			grant = &schema.Grant{Role: grantee}
+			grants = append(grants, grant)
+		}
+		if !column.Valid {
+			grant.Privileges = append(grant.Privileges, privilege)
+			continue
+		}
+		if privilege != colPriv {
+			flush()
+			colPriv = privilege
+		}
+		columns = append(columns, schema.QuoteIdent(column.String))
+	}
+	if grant != nil {
+		flush()
+	}
+	return grants, nil
+}
+
+// convertPolicyCommand returns the command of pg_policy polcmd
+func convertPolicyCommand(t string) string {
+	switch t {
+	case "r":
+		return "SELECT"
+	case "a":
+		return "INSERT"
+	case "w":
+		return "UPDATE"
+	case "d":
+		return "DELETE"
+	default:
+		return "ALL"
+	}
+}
+
+// parseTriggerDef returns the trigger of pg_get_triggerdef definition without name and comment,
+// the definition that is not recognized is kept as it is
+func parseTriggerDef(def string) *schema.Trigger {
+	result := reTrigger.FindStringSubmatch(def)
+	if len(result) == 0 {
+		return &schema.Trigger{Def: def}
+	}
+	tr := &schema.Trigger{
+		Constraint:        result[1] != "",
+		Timing:            result[2],
+		Events:            strings.Split(result[3], " OR "),
+		From:              result[4],
+		Deferrable:        result[5] == "DEFERRABLE",
+		InitiallyDeferred: result[6] == "DEFERRED",
+		ForEach:           result[9],
+		When:              result[10],
+		Function:          result[11],
+	}
+	if result[7] != "" {
+		tr.OldTable = splitIdents(result[7])[0]
+	}
+	if result[8] != "" {
+		tr.NewTable = splitIdents(result[8])[0]
+	}
+	return tr
+}
+
+// convertFKAction returns the referential action of pg_constraint confdeltype or confupdtype,
+// the default NO ACTION is empty
+func convertFKAction(t string) string {
+	switch t {
+	case "r":
+		return "RESTRICT"
+	case "c":
+		return "CASCADE"
+	case "n":
+		return "SET NULL"
+	case "d":
+		return "SET DEFAULT"
+	default:
+		return ""
+	}
+}
+
+// convertFKMatch returns the match type of pg_constraint confmatchtype, the default SIMPLE is empty
+func convertFKMatch(t string) string {
+	switch t {
+	case "f":
+		return "FULL"
+	case "p":
+		return "PARTIAL"
+	default:
+		return ""
+	}
+}
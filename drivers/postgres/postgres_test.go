@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/covrom/goerd/schema"
+)
+
+func TestParseTriggerDef(t *testing.T) {
+	tests := []struct {
+		name string
+		def  string
+		want *schema.Trigger
+	}{
+		{
+			name: "row",
+			def:  `CREATE TRIGGER orders_updated BEFORE UPDATE OF status, total OR INSERT ON public.orders FOR EACH ROW WHEN ((new.status <> 'closed'::text)) EXECUTE FUNCTION set_updated_at()`,
+			want: &schema.Trigger{
+				Timing:   "BEFORE",
+				Events:   []string{"UPDATE OF status, total", "INSERT"},
+				ForEach:  "ROW",
+				When:     "(new.status <> 'closed'::text)",
+				Function: "set_updated_at()",
+			},
+		},
+		{
+			name: "constraint",
+			def:  `CREATE CONSTRAINT TRIGGER check_balance AFTER INSERT ON "my schema"."order items" FROM public.accounts DEFERRABLE INITIALLY DEFERRED FOR EACH ROW EXECUTE FUNCTION check_balance('strict')`,
+			want: &schema.Trigger{
+				Constraint:        true,
+				Timing:            "AFTER",
+				Events:            []string{"INSERT"},
+				From:              "public.accounts",
+				Deferrable:        true,
+				InitiallyDeferred: true,
+				ForEach:           "ROW",
+				Function:          "check_balance('strict')",
+			},
+		},
+		{
+			name: "not deferrable constraint",
+			def:  `CREATE CONSTRAINT TRIGGER audit AFTER DELETE ON orders NOT DEFERRABLE INITIALLY IMMEDIATE FOR EACH ROW EXECUTE FUNCTION audit()`,
+			want: &schema.Trigger{
+				Constraint: true,
+				Timing:     "AFTER",
+				Events:     []string{"DELETE"},
+				ForEach:    "ROW",
+				Function:   "audit()",
+			},
+		},
+		{
+			name: "transition tables",
+			def:  `CREATE TRIGGER orders_audit AFTER UPDATE ON public.orders REFERENCING OLD TABLE AS old_rows NEW TABLE AS "new rows" FOR EACH STATEMENT EXECUTE FUNCTION audit.log_changes()`,
+			want: &schema.Trigger{
+				Timing:   "AFTER",
+				Events:   []string{"UPDATE"},
+				OldTable: "old_rows",
+				NewTable: "new rows",
+				ForEach:  "STATEMENT",
+				Function: "audit.log_changes()",
+			},
+		},
+		{
+			name: "not recognized",
+			def:  `CREATE TRIGGER odd AFTER INSERT ON orders FOR EACH ROW EXECUTE something else`,
+			want: &schema.Trigger{
+				Def: `CREATE TRIGGER odd AFTER INSERT ON orders FOR EACH ROW EXECUTE something else`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseTriggerDef(tt.def); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%#v", got)
+			}
+		})
+	}
+}
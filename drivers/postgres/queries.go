@@ -1,6 +1,165 @@
 package postgres
 
 const (
+	qEnums = `
+SELECT
+	tp.typname AS enum_name,
+	ns.nspname AS enum_schema,
+	array_to_json(ARRAY_AGG(enm.enumlabel ORDER BY enm.enumsortorder)) AS enum_values,
+	descr.description AS enum_comment
+FROM pg_type AS tp
+INNER JOIN pg_namespace AS ns ON tp.typnamespace = ns.oid
+INNER JOIN pg_enum AS enm ON enm.enumtypid = tp.oid
+LEFT JOIN pg_description AS descr ON tp.oid = descr.objoid AND descr.objsubid = 0
+WHERE ns.nspname NOT IN ('pg_catalog', 'information_schema')
+AND NOT EXISTS (
+	SELECT 1 FROM pg_depend AS edep
+	WHERE edep.objid = tp.oid
+	AND edep.classid = 'pg_type'::regclass
+	AND edep.deptype = 'e'
+)
+GROUP BY tp.oid, tp.typname, ns.nspname, descr.description
+ORDER BY tp.oid`
+
+	qDomains = `
+SELECT
+	tp.typname AS domain_name,
+	ns.nspname AS domain_schema,
+	format_type(tp.typbasetype, tp.typtypmod) AS domain_base_type,
+	tp.typnotnull AS domain_not_null,
+	tp.typdefault AS domain_default,
+	array_to_json(ARRAY_AGG(con.conname ORDER BY con.conname) FILTER (WHERE con.oid IS NOT NULL)) AS check_names,
+	array_to_json(ARRAY_AGG(pg_get_constraintdef(con.oid) ORDER BY con.conname) FILTER (WHERE con.oid IS NOT NULL)) AS check_defs,
+	descr.description AS domain_comment
+FROM pg_type AS tp
+INNER JOIN pg_namespace AS ns ON tp.typnamespace = ns.oid
+LEFT JOIN pg_constraint AS con ON con.contypid = tp.oid AND con.contype = 'c'
+LEFT JOIN pg_description AS descr ON tp.oid = descr.objoid AND descr.classoid = 'pg_type'::regclass
+WHERE ns.nspname NOT IN ('pg_catalog', 'information_schema')
+AND tp.typtype = 'd'
+AND NOT EXISTS (
+	SELECT 1 FROM pg_depend AS edep
+	WHERE edep.objid = tp.oid
+	AND edep.classid = 'pg_type'::regclass
+	AND edep.deptype = 'e'
+)
+GROUP BY tp.oid, tp.typname, ns.nspname, tp.typbasetype, tp.typtypmod, tp.typnotnull, tp.typdefault, descr.description
+ORDER BY tp.oid`
+
+	qComposites = `
+SELECT
+	tp.typname AS type_name,
+	ns.nspname AS type_schema,
+	array_to_json(ARRAY_AGG(attr.attname ORDER BY attr.attnum)) AS attribute_names,
+	array_to_json(ARRAY_AGG(format_type(attr.atttypid, attr.atttypmod) ORDER BY attr.attnum)) AS attribute_types,
+	descr.description AS type_comment
+FROM pg_type AS tp
+INNER JOIN pg_namespace AS ns ON tp.typnamespace = ns.oid
+INNER JOIN pg_class AS cls ON tp.typrelid = cls.oid AND cls.relkind = 'c'
+INNER JOIN pg_attribute AS attr ON attr.attrelid = cls.oid AND attr.attnum > 0 AND NOT attr.attisdropped
+LEFT JOIN pg_description AS descr ON tp.oid = descr.objoid AND descr.classoid = 'pg_type'::regclass
+WHERE ns.nspname NOT IN ('pg_catalog', 'information_schema')
+AND tp.typtype = 'c'
+AND NOT EXISTS (
+	SELECT 1 FROM pg_depend AS edep
+	WHERE edep.objid = tp.oid
+	AND edep.classid = 'pg_type'::regclass
+	AND edep.deptype = 'e'
+)
+GROUP BY tp.oid, tp.typname, ns.nspname, descr.description
+ORDER BY tp.oid`
+
+	qSequences = `
+SELECT
+	cls.oid AS oid,
+	cls.relname AS sequence_name,
+	ns.nspname AS sequence_schema,
+	format_type(seq.seqtypid, NULL) AS data_type,
+	seq.seqstart,
+	seq.seqincrement,
+	seq.seqmin,
+	seq.seqmax,
+	seq.seqcycle,
+	seq.seqcache,
+	ocls.relname AS owner_table,
+	oattr.attname AS owner_column,
+	descr.description AS sequence_comment,
+	pg_get_userbyid(cls.relowner) AS sequence_owner
+FROM pg_sequence AS seq
+INNER JOIN pg_class AS cls ON seq.seqrelid = cls.oid
+INNER JOIN pg_namespace AS ns ON cls.relnamespace = ns.oid
+LEFT JOIN pg_depend AS dep ON dep.objid = seq.seqrelid
+	AND dep.classid = 'pg_class'::regclass
+	AND dep.refclassid = 'pg_class'::regclass
+	AND dep.deptype = 'a'
+LEFT JOIN pg_class AS ocls ON dep.refobjid = ocls.oid
+LEFT JOIN pg_attribute AS oattr ON oattr.attrelid = dep.refobjid AND oattr.attnum = dep.refobjsubid
+LEFT JOIN pg_description AS descr ON cls.oid = descr.objoid AND descr.objsubid = 0
+WHERE ns.nspname NOT IN ('pg_catalog', 'information_schema')
+AND NOT EXISTS (
+	SELECT 1 FROM pg_depend AS idep
+	WHERE idep.objid = seq.seqrelid
+	AND idep.classid = 'pg_class'::regclass
+	AND idep.deptype IN ('i', 'e')
+)
+ORDER BY cls.oid`
+
+	qFunctions = `
+SELECT
+	prc.proname AS function_name,
+	ns.nspname AS function_schema,
+	pg_get_function_identity_arguments(prc.oid) AS function_arguments,
+	pg_get_function_result(prc.oid) AS function_result,
+	lng.lanname AS function_language,
+	prc.provolatile::text AS function_volatility,
+	prc.prosecdef AS function_security_definer,
+	prc.proisstrict AS function_strict,
+	prc.proleakproof AS function_leakproof,
+	prc.proparallel::text AS function_parallel,
+	prc.procost AS function_cost,
+	prc.proconfig AS function_config,
+	prc.prosrc AS function_body,
+	descr.description AS function_comment
+FROM pg_proc AS prc
+INNER JOIN pg_namespace AS ns ON prc.pronamespace = ns.oid
+INNER JOIN pg_language AS lng ON prc.prolang = lng.oid
+LEFT JOIN pg_description AS descr ON prc.oid = descr.objoid AND descr.classoid = 'pg_proc'::regclass
+WHERE ns.nspname NOT IN ('pg_catalog', 'information_schema')
+AND prc.prokind = 'f'
+AND lng.lanname NOT IN ('c', 'internal')
+AND NOT EXISTS (
+	SELECT 1 FROM pg_depend AS edep
+	WHERE edep.objid = prc.oid
+	AND edep.classid = 'pg_proc'::regclass
+	AND edep.deptype = 'e'
+)
+ORDER BY prc.oid`
+
+	qTriggers = `
+SELECT
+	trg.tgname AS trigger_name,
+	pg_get_triggerdef(trg.oid) AS trigger_def,
+	descr.description AS trigger_comment
+FROM pg_trigger AS trg
+LEFT JOIN pg_description AS descr ON trg.oid = descr.objoid AND descr.classoid = 'pg_trigger'::regclass
+WHERE trg.tgrelid = $1::oid
+AND NOT trg.tgisinternal
+AND trg.tgconstraint = 0
+ORDER BY trg.tgname`
+
+	qNamespaces = `
+SELECT
+	ns.oid AS oid,
+	ns.nspname AS namespace_name,
+	descr.description AS namespace_comment,
+	pg_get_userbyid(ns.nspowner) AS namespace_owner
+FROM pg_namespace AS ns
+LEFT JOIN pg_description AS descr ON ns.oid = descr.objoid AND descr.classoid = 'pg_namespace'::regclass
+WHERE ns.nspname NOT IN ('pg_catalog', 'information_schema')
+AND ns.nspname NOT LIKE 'pg_toast%'
+AND ns.nspname NOT LIKE 'pg_temp_%'
+ORDER BY ns.nspname`
+
 	qTables = `
 SELECT
 	cls.oid AS oid,
@@ -12,14 +171,135 @@ SELECT
 		WHEN cls.relkind = 'f' THEN 'FOREIGN TABLE'
 	END AS table_type,
 	ns.nspname AS table_schema,
-	descr.description AS table_comment
+	descr.description AS table_comment,
+	pg_get_partkeydef(cls.oid) AS partition_key,
+	cls.relrowsecurity AS row_security,
+	cls.relforcerowsecurity AS force_row_security,
+	pg_get_userbyid(cls.relowner) AS table_owner
 FROM pg_class AS cls
 INNER JOIN pg_namespace AS ns ON cls.relnamespace = ns.oid
 LEFT JOIN pg_description AS descr ON cls.oid = descr.objoid AND descr.objsubid = 0
 WHERE ns.nspname NOT IN ('pg_catalog', 'information_schema')
 AND cls.relkind IN ('r', 'p', 'v', 'f', 'm')
+AND NOT cls.relispartition
+AND NOT EXISTS (
+	SELECT 1 FROM pg_depend AS edep
+	WHERE edep.objid = cls.oid
+	AND edep.classid = 'pg_class'::regclass
+	AND edep.deptype = 'e'
+)
 ORDER BY oid`
 
+	qRoles = `
+SELECT rolname FROM pg_roles ORDER BY rolname`
+
+	qRelationGrants = `
+SELECT grantee_name, privilege_type, column_name FROM (
+	SELECT
+		CASE WHEN acl.grantee = 0 THEN 'public' ELSE pg_get_userbyid(acl.grantee) END AS grantee_name,
+		acl.privilege_type,
+		NULL::name AS column_name
+	FROM pg_class AS cls
+	CROSS JOIN LATERAL aclexplode(cls.relacl) AS acl
+	WHERE cls.oid = $1::oid
+	AND acl.grantee <> cls.relowner
+	UNION ALL
+	SELECT
+		CASE WHEN acl.grantee = 0 THEN 'public' ELSE pg_get_userbyid(acl.grantee) END AS grantee_name,
+		acl.privilege_type,
+		attr.attname AS column_name
+	FROM pg_attribute AS attr
+	INNER JOIN pg_class AS cls ON attr.attrelid = cls.oid
+	CROSS JOIN LATERAL aclexplode(attr.attacl) AS acl
+	WHERE attr.attrelid = $1::oid
+	AND attr.attnum > 0
+	AND NOT attr.attisdropped
+	AND acl.grantee <> cls.relowner
+) AS grants
+ORDER BY grantee_name, privilege_type, column_name NULLS FIRST`
+
+	qNamespaceGrants = `
+SELECT
+	CASE WHEN acl.grantee = 0 THEN 'public' ELSE pg_get_userbyid(acl.grantee) END AS grantee_name,
+	acl.privilege_type,
+	NULL::name AS column_name
+FROM pg_namespace AS ns
+CROSS JOIN LATERAL aclexplode(ns.nspacl) AS acl
+WHERE ns.oid = $1::oid
+AND acl.grantee <> ns.nspowner
+ORDER BY grantee_name, acl.privilege_type`
+
+	qPolicies = `
+SELECT
+	pol.polname AS policy_name,
+	pol.polcmd::text AS policy_command,
+	NOT pol.polpermissive AS policy_restrictive,
+	array_to_json(ARRAY(
+		SELECT CASE WHEN rol.oid = 0 THEN 'public' ELSE pg_get_userbyid(rol.oid) END
+		FROM unnest(pol.polroles) AS rol(oid)
+		ORDER BY 1
+	)) AS policy_roles,
+	pg_get_expr(pol.polqual, pol.polrelid) AS policy_using,
+	pg_get_expr(pol.polwithcheck, pol.polrelid) AS policy_with_check,
+	descr.description AS policy_comment
+FROM pg_policy AS pol
+LEFT JOIN pg_description AS descr ON pol.oid = descr.objoid AND descr.classoid = 'pg_policy'::regclass
+WHERE pol.polrelid = $1::oid
+ORDER BY pol.polname`
+
+	qPartitions = `
+SELECT
+	cls.relname AS partition_name,
+	pg_get_expr(cls.relpartbound, cls.oid) AS partition_bound
+FROM pg_inherits AS inh
+INNER JOIN pg_class AS cls ON inh.inhrelid = cls.oid
+WHERE inh.inhparent = $1::oid
+AND cls.relispartition
+ORDER BY cls.relname`
+
+	qExtensions = `
+SELECT
+	ext.extname AS extension_name,
+	ext.extversion AS extension_version,
+	ns.nspname AS extension_schema
+FROM pg_extension AS ext
+INNER JOIN pg_namespace AS ns ON ext.extnamespace = ns.oid
+WHERE ext.extname <> 'plpgsql'
+ORDER BY ext.extname`
+
+	qViewDependencies = `
+SELECT DISTINCT
+	dcls.relname AS dependency_name,
+	dns.nspname AS dependency_schema
+FROM pg_rewrite AS rw
+INNER JOIN pg_depend AS dep ON dep.objid = rw.oid
+	AND dep.classid = 'pg_rewrite'::regclass
+	AND dep.refclassid = 'pg_class'::regclass
+INNER JOIN pg_class AS dcls ON dep.refobjid = dcls.oid
+INNER JOIN pg_namespace AS dns ON dcls.relnamespace = dns.oid
+WHERE rw.ev_class = $1::oid
+AND dep.refobjid <> $1::oid
+ORDER BY dns.nspname, dcls.relname`
+
+	qViewColumnDependencies = `
+SELECT DISTINCT
+	dcls.relname AS dependency_name,
+	dns.nspname AS dependency_schema,
+	att.attname AS column_name,
+	att.attnum
+FROM pg_rewrite AS rw
+INNER JOIN pg_depend AS dep ON dep.objid = rw.oid
+	AND dep.classid = 'pg_rewrite'::regclass
+	AND dep.refclassid = 'pg_class'::regclass
+	AND dep.refobjsubid > 0
+INNER JOIN pg_class AS dcls ON dep.refobjid = dcls.oid
+INNER JOIN pg_namespace AS dns ON dcls.relnamespace = dns.oid
+INNER JOIN pg_attribute AS att ON att.attrelid = dep.refobjid
+	AND att.attnum = dep.refobjsubid
+WHERE rw.ev_class = $1::oid
+AND dep.refobjid <> $1::oid
+ORDER BY dns.nspname, dcls.relname, att.attnum`
+
 	qContstraints = `
 SELECT
   cons.conname AS name,
@@ -28,20 +308,30 @@ SELECT
   END AS def,
   cons.contype AS type,
   fcls.relname,
+  fns.nspname,
   array_to_json(ARRAY_AGG(attr.attname)) as attnm,
   array_to_json(ARRAY_AGG(fattr.attname)) as fattnm,
-  descr.description AS comment
+  descr.description AS comment,
+  cons.confdeltype::text,
+  cons.confupdtype::text,
+  cons.confmatchtype::text,
+  cons.condeferrable,
+  cons.condeferred,
+  NOT cons.convalidated AS notvalid
 FROM pg_constraint AS cons
 LEFT JOIN pg_trigger AS trig ON trig.tgconstraint = cons.oid AND NOT trig.tgisinternal
 LEFT JOIN pg_class AS fcls ON cons.confrelid = fcls.oid
+LEFT JOIN pg_namespace AS fns ON fcls.relnamespace = fns.oid
 LEFT JOIN pg_attribute AS attr ON attr.attrelid = cons.conrelid
 LEFT JOIN pg_attribute AS fattr ON fattr.attrelid = cons.confrelid
 LEFT JOIN pg_description AS descr ON cons.oid = descr.objoid
 WHERE
 	cons.conrelid = $1::oid
+AND cons.conparentid = 0
 AND (cons.conkey IS NULL OR attr.attnum = ANY(cons.conkey))
 AND (cons.confkey IS NULL OR fattr.attnum = ANY(cons.confkey))
-GROUP BY cons.conindid, cons.conname, cons.contype, cons.oid, trig.oid, fcls.relname, descr.description
+GROUP BY cons.conindid, cons.conname, cons.contype, cons.oid, trig.oid, fcls.relname, fns.nspname, descr.description,
+  cons.confdeltype, cons.confupdtype, cons.confmatchtype, cons.condeferrable, cons.condeferred, cons.convalidated
 ORDER BY cons.conindid, cons.conname`
 
 	qColumns = `
@@ -56,11 +346,25 @@ SELECT
 			REPLACE(format_type(attr.atttypid, attr.atttypmod), 'timestamp with time zone', 'timestamptz')
 		ELSE format_type(attr.atttypid, attr.atttypmod)
 	END AS data_type,
-	descr.description AS comment
+	descr.description AS comment,
+	attr.attidentity::text AS identity,
+	attr.attgenerated::text AS generated,
+	seq.seqstart,
+	seq.seqincrement,
+	seq.seqmin,
+	seq.seqmax,
+	seq.seqcycle,
+	seq.seqcache
 FROM pg_attribute AS attr
 INNER JOIN pg_type AS tp ON attr.atttypid = tp.oid
 LEFT JOIN pg_attrdef AS def ON attr.attrelid = def.adrelid AND attr.attnum = def.adnum
 LEFT JOIN pg_description AS descr ON attr.attrelid = descr.objoid AND attr.attnum = descr.objsubid
+LEFT JOIN pg_depend AS idep ON idep.refobjid = attr.attrelid
+	AND idep.refobjsubid = attr.attnum
+	AND idep.classid = 'pg_class'::regclass
+	AND idep.refclassid = 'pg_class'::regclass
+	AND idep.deptype = 'i'
+LEFT JOIN pg_sequence AS seq ON idep.objid = seq.seqrelid
 WHERE
 	attr.attnum > 0
 AND NOT attr.attisdropped
@@ -23,14 +23,54 @@ func SchemaFromPostgresDB(db *sql.DB) (*schema.Schema, error) {
 	return s, err
 }
 
-// GenerateMigrationSQL generates an array of SQL DDL queries
+// MigrationOption sets up the migration patch
+type MigrationOption func(*schema.PatchSchema)
+
+// WithTargetNamespace moves all objects of the target schema into the namespace ns,
+// only this namespace of the source schema is compared
+func WithTargetNamespace(ns string) MigrationOption {
+	return func(ptch *schema.PatchSchema) {
+		ptch.TargetNamespace = ns
+	}
+}
+
+// WithRenameDetection warns about dropped and added tables or columns
+// that are probably renamed without renamedFrom hint
+func WithRenameDetection() MigrationOption {
+	return func(ptch *schema.PatchSchema) {
+		ptch.DetectRenames = true
+	}
+}
+
+// WithMatViewNoData creates materialized views WITH NO DATA
+// and refreshes them at the end of migration
+func WithMatViewNoData() MigrationOption {
+	return func(ptch *schema.PatchSchema) {
+		ptch.MatViewNoData = true
+	}
+}
+
+// GenerateMigrationPlan generates a plan of typed DDL operations
 // for postgres that modify database tables, columns, indexes, etc.
-func GenerateMigrationSQL(sfrom, sto *schema.Schema) ([]string, error) {
+func GenerateMigrationPlan(sfrom, sto *schema.Schema, opts ...MigrationOption) (*schema.Plan, error) {
 	ptch := &schema.PatchSchema{CurrentSchema: sfrom.CurrentSchema}
+	for _, opt := range opts {
+		opt(ptch)
+	}
 	if err := ptch.Build(sfrom, sto); err != nil {
 		return nil, err
 	}
-	return ptch.GenerateSQL(), nil
+	return ptch.Plan(), nil
+}
+
+// GenerateMigrationSQL generates an array of SQL DDL queries
+// for postgres that modify database tables, columns, indexes, etc.
+func GenerateMigrationSQL(sfrom, sto *schema.Schema, opts ...MigrationOption) ([]string, error) {
+	plan, err := GenerateMigrationPlan(sfrom, sto, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return plan.SQL(), nil
 }
 
 // SchemaToYAML saves the schema to a yaml file
@@ -47,8 +87,22 @@ func SchemaFromYAML(r io.Reader) (*schema.Schema, error) {
 	return s, nil
 }
 
+// PlantUMLOption sets up the plantuml output
+type PlantUMLOption func(*schema.PlantUML)
+
+// WithoutComments hides the comments of tables, columns and indexes in plantuml
+func WithoutComments() PlantUMLOption {
+	return func(p *schema.PlantUML) {
+		p.ShowComment = false
+	}
+}
+
 // SchemaToPlantUML saves the schema to a puml file,
 // distance - maximum path length for relationships between plantuml objects
-func SchemaToPlantUML(s *schema.Schema, w io.Writer, distance int) error {
-	return s.SavePlantUml(w, distance)
+func SchemaToPlantUML(s *schema.Schema, w io.Writer, distance int, opts ...PlantUMLOption) error {
+	p := schema.NewPlantUML(distance)
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p.OutputSchema(w, s)
 }
@@ -5,7 +5,6 @@ import (
 	_ "embed"
 	"fmt"
 	"os"
-	"strings"
 	"testing"
 	"time"
 
@@ -100,7 +99,7 @@ func Migrate(d *sqlx.DB, migsch *schema.Schema) error {
 	if err != nil {
 		return fmt.Errorf("cannot migrate database: %w", err)
 	}
-	qs, err := goerd.GenerateMigrationSQL(dbsch, migsch)
+	plan, err := goerd.GenerateMigrationPlan(dbsch, migsch)
 	if err != nil {
 		return err
 	}
@@ -108,20 +107,16 @@ func Migrate(d *sqlx.DB, migsch *schema.Schema) error {
 	if err != nil {
 		return fmt.Errorf("cannot migrate database: %w", err)
 	}
-	for i, q := range qs {
-		// skip all dropping DDL queries
-		if strings.HasPrefix(strings.ToUpper(q), "DROP") {
-			fmt.Println(i+1, "skip: ", q)
-			continue
-		}
-		if strings.Contains(strings.ToUpper(q), "DROP COLUMN") {
-			fmt.Println(i+1, "skip: ", q)
+	for i, op := range plan.Operations {
+		// skip all destructive DDL queries
+		if op.Destructive {
+			fmt.Println(i+1, "skip: ", op.SQL)
 			continue
 		}
 
-		fmt.Println(i+1, q)
+		fmt.Println(i+1, op.SQL)
 
-		_, err = tx.Exec(q)
+		_, err = tx.Exec(op.SQL)
 
 		if err != nil {
 			_ = tx.Rollback()
@@ -131,7 +126,7 @@ func Migrate(d *sqlx.DB, migsch *schema.Schema) error {
 			fmt.Println("target schema:")
 			migsch.SaveYaml(os.Stdout)
 
-			return fmt.Errorf("cannot migrate database %q: %w", q, err)
+			return fmt.Errorf("cannot migrate database %q: %w", op.SQL, err)
 		}
 	}
 	if err = tx.Commit(); err != nil {
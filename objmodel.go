@@ -3,7 +3,6 @@ package goerd
 import (
 	"fmt"
 	"os"
-	"strings"
 
 	"github.com/covrom/goerd/schema"
 	"github.com/jmoiron/sqlx"
@@ -153,32 +152,51 @@ func (md *ModelSet) Migrate(d *sqlx.DB, dbSchema string) error {
 		return fmt.Errorf("cannot migrate database: %w", err)
 	}
 
-	qs, err := GenerateMigrationSQL(dbsch, migsch)
+	var opts []MigrationOption
+	if dbSchema != "" {
+		opts = append(opts, WithTargetNamespace(dbSchema))
+	}
+	plan, err := GenerateMigrationPlan(dbsch, migsch, opts...)
 	if err != nil {
 		return fmt.Errorf("cannot migrate database: %w", err)
 	}
+	for _, w := range plan.Warnings {
+		fmt.Println("warning:", w)
+	}
 	tx, err := d.Begin()
 	if err != nil {
 		return fmt.Errorf("cannot migrate database: %w", err)
 	}
-	for i, q := range qs {
-		// skip all dropping DDL queries
-		if strings.HasPrefix(strings.ToUpper(q), "DROP") {
-			fmt.Println(i+1, "skip: ", q)
-			continue
-		}
-		if strings.Contains(strings.ToUpper(q), "DROP COLUMN") {
-			fmt.Println(i+1, "skip: ", q)
+	for i, op := range plan.Operations {
+		// skip all destructive DDL queries
+		if op.Destructive {
+			fmt.Println(i+1, "skip: ", op.SQL)
 			continue
 		}
 
-		fmt.Println(i+1, q)
-
-		_, err = tx.Exec(q)
+		fmt.Println(i+1, op.SQL)
+
+		if op.OutsideTx {
+			// commit previous queries, that the operation may depend on
+			if err = tx.Commit(); err != nil {
+				return fmt.Errorf("cannot migrate database: %w", err)
+			}
+			_, err = d.Exec(op.SQL)
+			if err == nil {
+				tx, err = d.Begin()
+				if err != nil {
+					return fmt.Errorf("cannot migrate database: %w", err)
+				}
+				continue
+			}
+		} else {
+			_, err = tx.Exec(op.SQL)
+			if err != nil {
+				_ = tx.Rollback()
+			}
+		}
 
 		if err != nil {
-			_ = tx.Rollback()
-
 			fmt.Println("db schema:")
 			dbsch.SaveYaml(os.Stdout)
 			fmt.Println("target schema:")
@@ -0,0 +1,197 @@
+package schema
+
+// Clone returns the deep copy of schema, the relations of copy are linked
+// to the copied tables and columns
+func (s *Schema) Clone() *Schema {
+	ret := *s
+	ret.SearchPaths = cloneStrings(s.SearchPaths)
+	ret.Roles = cloneStrings(s.Roles)
+	ret.Namespaces = make([]*Namespace, len(s.Namespaces))
+	for i, n := range s.Namespaces {
+		cn := *n
+		cn.Grants = cloneGrants(n.Grants)
+		ret.Namespaces[i] = &cn
+	}
+	ret.Extensions = make([]*Extension, len(s.Extensions))
+	for i, e := range s.Extensions {
+		ce := *e
+		ret.Extensions[i] = &ce
+	}
+	ret.Enums = make([]*Enum, len(s.Enums))
+	for i, e := range s.Enums {
+		ce := *e
+		ce.Values = cloneStrings(e.Values)
+		if e.RenamedValues != nil {
+			ce.RenamedValues = make(map[string]string, len(e.RenamedValues))
+			for v, old := range e.RenamedValues {
+				ce.RenamedValues[v] = old
+			}
+		}
+		ret.Enums[i] = &ce
+	}
+	ret.Domains = make([]*Domain, len(s.Domains))
+	for i, d := range s.Domains {
+		cd := *d
+		cd.Checks = make([]*DomainCheck, len(d.Checks))
+		for j, c := range d.Checks {
+			cc := *c
+			cd.Checks[j] = &cc
+		}
+		ret.Domains[i] = &cd
+	}
+	ret.Composites = make([]*CompositeType, len(s.Composites))
+	for i, ct := range s.Composites {
+		cct := *ct
+		cct.Attributes = make([]*Attribute, len(ct.Attributes))
+		for j, a := range ct.Attributes {
+			ca := *a
+			cct.Attributes[j] = &ca
+		}
+		ret.Composites[i] = &cct
+	}
+	ret.Sequences = make([]*Sequence, len(s.Sequences))
+	for i, sq := range s.Sequences {
+		ret.Sequences[i] = cloneSequence(sq)
+	}
+	ret.Functions = make([]*Function, len(s.Functions))
+	for i, f := range s.Functions {
+		cf := *f
+		cf.Config = cloneStrings(f.Config)
+		ret.Functions[i] = &cf
+	}
+	tables := make(map[*Table]*Table, len(s.Tables))
+	columns := map[*Column]*Column{}
+	ret.Tables = make([]*Table, len(s.Tables))
+	for i, t := range s.Tables {
+		ct := t.clone(columns)
+		tables[t] = ct
+		ret.Tables[i] = ct
+	}
+	ret.Relations = make([]*Relation, len(s.Relations))
+	for i, r := range s.Relations {
+		cr := *r
+		if t, ok := tables[r.Table]; ok {
+			cr.Table = t
+		}
+		if t, ok := tables[r.ParentTable]; ok {
+			cr.ParentTable = t
+		}
+		cr.Columns = make([]*Column, len(r.Columns))
+		for j, c := range r.Columns {
+			if cc, ok := columns[c]; ok {
+				c = cc
+				c.ParentRelations = append(c.ParentRelations, &cr)
+			}
+			cr.Columns[j] = c
+		}
+		cr.ParentColumns = make([]*Column, len(r.ParentColumns))
+		for j, c := range r.ParentColumns {
+			if cc, ok := columns[c]; ok {
+				c = cc
+				c.ChildRelations = append(c.ChildRelations, &cr)
+			}
+			cr.ParentColumns[j] = c
+		}
+		ret.Relations[i] = &cr
+	}
+	return &ret
+}
+
+// clone returns the deep copy of table without relations of columns,
+// the copied columns are added to the map by source columns
+func (t *Table) clone(columns map[*Column]*Column) *Table {
+	ret := *t
+	ret.Columns = make([]*Column, len(t.Columns))
+	for i, c := range t.Columns {
+		cc := *c
+		if c.Identity != nil {
+			idn := *c.Identity
+			if idn.Sequence != nil {
+				idn.Sequence = cloneSequence(idn.Sequence)
+			}
+			cc.Identity = &idn
+		}
+		cc.ParentRelations, cc.ChildRelations = nil, nil
+		columns[c] = &cc
+		ret.Columns[i] = &cc
+	}
+	ret.Indexes = make([]*Index, len(t.Indexes))
+	for i, idx := range t.Indexes {
+		ci := *idx
+		ci.Table = cloneStringPtr(idx.Table)
+		ci.Columns = cloneStrings(idx.Columns)
+		ret.Indexes[i] = &ci
+	}
+	ret.Constraints = make([]*Constraint, len(t.Constraints))
+	for i, c := range t.Constraints {
+		cc := *c
+		cc.Table = cloneStringPtr(c.Table)
+		cc.ReferenceTable = cloneStringPtr(c.ReferenceTable)
+		cc.Columns = cloneStrings(c.Columns)
+		cc.ReferenceColumns = cloneStrings(c.ReferenceColumns)
+		ret.Constraints[i] = &cc
+	}
+	ret.Triggers = make([]*Trigger, len(t.Triggers))
+	for i, tr := range t.Triggers {
+		ctr := *tr
+		ctr.Events = cloneStrings(tr.Events)
+		ret.Triggers[i] = &ctr
+	}
+	ret.DependsOn = cloneStrings(t.DependsOn)
+	if t.DependsOnColumns != nil {
+		ret.DependsOnColumns = make(map[string][]string, len(t.DependsOnColumns))
+		for d, cols := range t.DependsOnColumns {
+			ret.DependsOnColumns[d] = cloneStrings(cols)
+		}
+	}
+	ret.Partitions = make([]*Partition, len(t.Partitions))
+	for i, p := range t.Partitions {
+		cp := *p
+		ret.Partitions[i] = &cp
+	}
+	ret.PartitionRules = make([]*PartitionRule, len(t.PartitionRules))
+	for i, pr := range t.PartitionRules {
+		cpr := *pr
+		ret.PartitionRules[i] = &cpr
+	}
+	ret.Policies = make([]*Policy, len(t.Policies))
+	for i, p := range t.Policies {
+		cp := *p
+		cp.Roles = cloneStrings(p.Roles)
+		ret.Policies[i] = &cp
+	}
+	ret.Grants = cloneGrants(t.Grants)
+	return &ret
+}
+
+func cloneSequence(sq *Sequence) *Sequence {
+	ret := *sq
+	ret.Grants = cloneGrants(sq.Grants)
+	return &ret
+}
+
+func cloneGrants(grants []*Grant) []*Grant {
+	if grants == nil {
+		return nil
+	}
+	ret := make([]*Grant, len(grants))
+	for i, g := range grants {
+		ret[i] = &Grant{Role: g.Role, Privileges: cloneStrings(g.Privileges)}
+	}
+	return ret
+}
+
+func cloneStrings(ss []string) []string {
+	if ss == nil {
+		return nil
+	}
+	return append([]string{}, ss...)
+}
+
+func cloneStringPtr(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	v := *s
+	return &v
+}
@@ -2,6 +2,7 @@ package schema
 
 import (
 	"fmt"
+	"regexp"
 	"strings"
 )
 
@@ -12,9 +13,20 @@ type PatchTable struct {
 	columns     []*PatchColumn
 	indexes     []*PatchIndex
 	constraints []*PatchConstraint
+	triggers    []*PatchTrigger
+	policies    []*PatchPolicy
+	partitions  []*PatchPartition
+	primaryKey  *PatchPrimaryKey
+	recreate    bool // changed view is dropped and created again
+	noData      bool // materialized view is created WITH NO DATA
 }
 
-func (t *PatchTable) GenerateSQL() []string {
+// Operations returns the operations of table patch,
+// the recreated view is dropped separately before the tables are altered
+func (t *PatchTable) Operations() []*Operation {
+	if t.recreate {
+		return t.recreated().create()
+	}
 	if t.from != nil && t.to != nil {
 		return t.alter()
 	}
@@ -24,26 +36,49 @@ func (t *PatchTable) GenerateSQL() []string {
 	return t.drop()
 }
 
-func (t *PatchTable) create() []string {
+func (t *PatchTable) create() []*Operation {
 	if t.to.Type == "" {
 		t.to.Type = "TABLE"
 	}
+	name := quotedName(t.to.Namespace, t.to.Name)
 	if t.to.Type != "TABLE" {
-		return []string{
-			fmt.Sprintf("CREATE %s %s AS (\n%s\n)", t.to.Type, t.to.Name, strings.TrimRight(t.to.Def, ";")),
+		noData := ""
+		if t.noData && strings.EqualFold(t.to.Type, TypeMaterialized) {
+			noData = " WITH NO DATA"
+		}
+		ret := []*Operation{{
+			Kind:   OpCreate,
+			Object: t.to.Type,
+			Target: name,
+			SQL:    fmt.Sprintf("CREATE %s %s AS (\n%s\n)%s", t.to.Type, name, strings.TrimRight(t.to.Def, ";"), noData),
+		}}
+		ret = append(ret, t.comment()...)
+		for _, c := range t.columns {
+			ret = append(ret, c.comment()...)
+		}
+		// materialized view indexes
+		for _, idx := range t.indexes {
+			ret = append(ret, idx.create()...)
 		}
+		for _, tr := range t.triggers {
+			ret = append(ret, tr.create()...)
+		}
+		return append(ret, t.privileges()...)
 	}
 
 	sb := &strings.Builder{}
-	fmt.Fprint(sb, "CREATE TABLE ", t.to.Name, " (\n")
+	fmt.Fprint(sb, "CREATE TABLE ", name, " (\n")
 	crlf := false
+	comments := t.comment()
 	for _, c := range t.columns {
 		if crlf {
 			sb.WriteString(",\n")
 		} else {
 			crlf = true
 		}
-		sb.WriteString(c.create()[0])
+		cq := c.create()
+		sb.WriteString(cq[0].SQL)
+		comments = append(comments, cq[1:]...)
 	}
 	for _, cs := range t.constraints {
 		if crlf {
@@ -51,22 +86,58 @@ func (t *PatchTable) create() []string {
 		} else {
 			crlf = true
 		}
-		sb.WriteString(cs.create()[0])
+		cq := cs.create()
+		sb.WriteString(cq[0].SQL)
+		comments = append(comments, cq[1:]...)
 	}
 	fmt.Fprint(sb, ")")
+	if t.to.PartitionBy != "" {
+		fmt.Fprint(sb, " PARTITION BY ", t.to.PartitionBy)
+	}
 
-	ret := []string{sb.String()}
+	ret := append([]*Operation{{
+		Kind:   OpCreate,
+		Object: "TABLE",
+		Target: name,
+		SQL:    sb.String(),
+	}}, comments...)
 
 	for _, idx := range t.indexes {
-		idx.to.Table = &t.to.Name
 		ret = append(ret, idx.create()...)
 	}
+	for _, tr := range t.triggers {
+		ret = append(ret, tr.create()...)
+	}
+	for _, pp := range t.policies {
+		ret = append(ret, pp.create()...)
+	}
+	ret = append(ret, t.rowSecurity()...)
+	ret = append(ret, t.privileges()...)
+	for _, pp := range t.partitions {
+		ret = append(ret, pp.create()...)
+	}
 
 	return ret
 }
 
-func (t *PatchTable) alter() []string {
-	ret := []string{}
+func (t *PatchTable) alter() []*Operation {
+	ret := []*Operation{}
+	if t.from.Name != t.to.Name {
+		tp := t.to.Type
+		if tp == "" {
+			tp = "TABLE"
+		}
+		ret = append(ret, renameOp(tp, fmt.Sprintf("ALTER %s %s RENAME", tp, quotedName(t.from.Namespace, t.from.Name)),
+			quotedName(t.to.Namespace, t.to.Name), t.to.Name, LockAccessExclusive))
+	}
+	ret = append(ret, t.comment()...)
+	if isView(t.to) {
+		ret = append(ret, t.alterView()...)
+		return append(ret, t.privileges()...)
+	}
+	if t.primaryKey != nil {
+		ret = append(ret, t.primaryKey.drop()...)
+	}
 	for _, c := range t.columns {
 		if c.from == nil {
 			ret = append(ret, c.create()...)
@@ -94,25 +165,66 @@ func (t *PatchTable) alter() []string {
 			ret = append(ret, ctr.alter()...)
 		}
 	}
+	if t.primaryKey != nil {
+		ret = append(ret, t.primaryKey.create()...)
+	}
+	for _, tr := range t.triggers {
+		ret = append(ret, tr.Operations()...)
+	}
+	for _, pp := range t.policies {
+		ret = append(ret, pp.Operations()...)
+	}
+	ret = append(ret, t.rowSecurity()...)
+	ret = append(ret, t.privileges()...)
+	for _, pp := range t.partitions {
+		ret = append(ret, pp.Operations()...)
+	}
 	return ret
 }
 
-func (t *PatchTable) drop() []string {
-	if PatchDropDisable {
+func (t *PatchTable) comment() []*Operation {
+	if t.from != nil && t.from.Comment == t.to.Comment {
+		return nil
+	}
+	if t.from == nil && t.to.Comment == "" {
+		return nil
+	}
+	tp := t.to.Type
+	if tp == "" {
+		tp = "TABLE"
+	}
+	return []*Operation{commentOp(tp, quotedName(t.to.Namespace, t.to.Name), t.to.Comment)}
+}
+
+// drop drops the removed table or the recreated view,
+// the view is recreated regardless of the drop policy
+func (t *PatchTable) drop() []*Operation {
+	if t.to == nil && PatchDropDisable {
 		return nil
 	}
-	return []string{
-		fmt.Sprintf("DROP TABLE IF EXISTS %s", t.from.Name),
+	name := quotedName(t.from.Namespace, t.from.Name)
+	tp := t.from.Type
+	if tp == "" {
+		tp = "TABLE"
 	}
+	return []*Operation{{
+		Kind:        OpDrop,
+		Object:      tp,
+		Target:      name,
+		SQL:         fmt.Sprintf("DROP %s IF EXISTS %s", tp, name),
+		Destructive: t.to == nil || !isView(t.from),
+		Lock:        LockAccessExclusive,
+	}}
 }
 
 type PatchColumn struct {
-	from, to  *Column
-	tableName string
-	newTable  bool
+	from, to   *Column
+	tableName  string
+	newTable   bool
+	primaryKey bool // single column primary key of created table
 }
 
-func (c *PatchColumn) GenerateSQL() []string {
+func (c *PatchColumn) Operations() []*Operation {
 	if c.from != nil && c.to != nil {
 		return c.alter()
 	}
@@ -122,75 +234,216 @@ func (c *PatchColumn) GenerateSQL() []string {
 	return c.drop()
 }
 
-func (c *PatchColumn) create() []string {
+func (c *PatchColumn) create() []*Operation {
 	sb := &strings.Builder{}
 	if !c.newTable {
 		fmt.Fprintf(sb, "ALTER TABLE %s ADD COLUMN ", c.tableName)
 	}
-	fmt.Fprint(sb, c.to.Name, " ", c.to.Type)
+	fmt.Fprint(sb, QuoteIdent(c.to.Name), " ", c.to.Type)
 	if !c.to.Nullable {
 		fmt.Fprint(sb, " NOT NULL")
 	}
 	if c.to.Default.Valid {
 		fmt.Fprint(sb, " DEFAULT ", c.to.Default.String)
 	}
-	if c.to.PrimaryKey {
+	if c.to.Generated != "" {
+		fmt.Fprint(sb, " GENERATED ALWAYS AS (", c.to.Generated, ") STORED")
+	}
+	if c.to.Identity != nil {
+		fmt.Fprint(sb, " ", identityDDL(c.to))
+	}
+	if c.primaryKey {
 		fmt.Fprint(sb, " PRIMARY KEY")
 	}
-	return []string{sb.String()}
+	return append([]*Operation{{
+		Kind:   OpCreate,
+		Object: "COLUMN",
+		Target: c.tableName + "." + QuoteIdent(c.to.Name),
+		SQL:    sb.String(),
+		Lock:   LockAccessExclusive,
+	}}, c.comment()...)
 }
 
-func (c *PatchColumn) alter() []string {
-	ret := []string{}
-	if c.from.Default.String != c.to.Default.String && (c.from.Default.Valid || c.to.Default.Valid) {
+func (c *PatchColumn) comment() []*Operation {
+	if c.from != nil && c.from.Comment == c.to.Comment {
+		return nil
+	}
+	if c.from == nil && c.to.Comment == "" {
+		return nil
+	}
+	return []*Operation{commentOp("COLUMN", c.tableName+"."+QuoteIdent(c.to.Name), c.to.Comment)}
+}
+
+func (c *PatchColumn) rename() []*Operation {
+	if c.from.Name == c.to.Name {
+		return nil
+	}
+	return []*Operation{renameOp("COLUMN", fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s", c.tableName, QuoteIdent(c.from.Name)),
+		c.tableName+"."+QuoteIdent(c.to.Name), c.to.Name, LockAccessExclusive)}
+}
+
+// identityDDL returns identity column clause with non-default sequence options
+func identityDDL(c *Column) string {
+	ret := fmt.Sprintf("GENERATED %s AS IDENTITY", c.Identity.Generation)
+	sq := c.IdentitySequence()
+	opts := sequenceOptions((&Sequence{DataType: sq.DataType}).WithDefaults(), sq)
+	if sq.Name != "" {
+		opts = append([]string{"SEQUENCE NAME " + quoteRef(sq.Name)}, opts...)
+	}
+	if len(opts) > 0 {
+		ret += " (" + strings.Join(opts, " ") + ")"
+	}
+	return ret
+}
+
+func (c *PatchColumn) alter() []*Operation {
+	ret := []*Operation{}
+	target := c.tableName + "." + QuoteIdent(c.to.Name)
+	alter := func(action string) {
+		ret = append(ret, &Operation{
+			Kind:   OpAlter,
+			Object: "COLUMN",
+			Target: target,
+			SQL:    fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s", c.tableName, QuoteIdent(c.to.Name), action),
+			Lock:   LockAccessExclusive,
+		})
+	}
+	ret = append(ret, c.rename()...)
+	// columns are compared in canonical form, the declared form is used in DDL
+	from, to := normalizeColumn(c.from), normalizeColumn(c.to)
+	if c.recreated() {
+		// generated expression can not be changed, values are recomputed by re-adding column,
+		// the indexes, constraints and foreign keys that use the column are recreated by their patches.
+		// The column is added again only with its drop, so all of them are destructive.
+		recreated := []*Operation{{
+			Kind:   OpDrop,
+			Object: "COLUMN",
+			Target: target,
+			SQL:    fmt.Sprintf("ALTER TABLE %s DROP COLUMN IF EXISTS %s", c.tableName, QuoteIdent(c.to.Name)),
+			Lock:   LockAccessExclusive,
+		}, c.create()[0]}
+		if c.to.Comment != "" {
+			recreated = append(recreated, commentOp("COLUMN", target, c.to.Comment))
+		}
+		return append(ret, destructive(recreated)...)
+	}
+	if from.Generated != to.Generated && c.to.Generated == "" {
+		alter("DROP EXPRESSION")
+	}
+	if c.from.Identity != nil && c.to.Identity == nil {
+		alter("DROP IDENTITY IF EXISTS")
+	}
+	if !sameDefault(from, to) && to.Default.String != serialDefault {
 		if c.to.Default.Valid {
-			ret = append(ret, fmt.Sprintf(
-				"ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s",
-				c.tableName, c.to.Name, c.to.Default.String,
-			))
+			alter("SET DEFAULT " + c.to.Default.String)
 		} else {
-			ret = append(ret, fmt.Sprintf(
-				"ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT",
-				c.tableName, c.to.Name,
-			))
+			alter("DROP DEFAULT")
 		}
 	}
 	if c.from.Nullable != c.to.Nullable {
 		if c.to.Nullable {
-			ret = append(ret, fmt.Sprintf(
-				"ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL",
-				c.tableName, c.to.Name,
-			))
+			alter("DROP NOT NULL")
 		} else {
-			ret = append(ret, fmt.Sprintf(
-				"ALTER TABLE %s ALTER COLUMN %s SET NOT NULL",
-				c.tableName, c.to.Name,
-			))
+			alter("SET NOT NULL")
+		}
+	}
+	if from.Type != to.Type {
+		action := "TYPE " + c.to.Type
+		if _, ok := serialTypes[strings.ToLower(c.to.Type)]; ok {
+			action = "TYPE " + to.Type
+		}
+		if c.to.Using != "" {
+			action += " USING " + c.to.Using
 		}
+		alter(action)
+		ret[len(ret)-1].TypeChange = ClassifyTypeChange(from.Type, to.Type, c.to.Using)
 	}
-	if c.from.Type != c.to.Type {
-		ret = append(ret, fmt.Sprintf(
-			"ALTER TABLE %s ALTER COLUMN %s TYPE %s",
-			c.tableName, c.to.Name, c.to.Type,
-		))
+	if c.to.Identity != nil {
+		if c.from.Identity == nil {
+			alter("ADD " + identityDDL(c.to))
+		} else {
+			opts := sequenceOptions(c.from.IdentitySequence(), c.to.IdentitySequence())
+			if c.from.Identity.Generation != c.to.Identity.Generation {
+				opts = append([]string{"GENERATED " + c.to.Identity.Generation}, opts...)
+			}
+			if len(opts) > 0 {
+				alter("SET " + strings.Join(opts, " SET "))
+			}
+		}
 	}
-	return ret
+	return append(ret, c.comment()...)
 }
 
-func (c *PatchColumn) drop() []string {
+// impliedSequence reports whether the sequence is owned by the column
+// with sequence default in schema, e.g. the sequence of serial column,
+// such sequence is not declared separately
+func impliedSequence(s *Schema, sq *Sequence) bool {
+	if sq.OwnedBy == "" {
+		return false
+	}
+	t, err := s.FindTable(sq.Namespace, sq.OwnerTable())
+	if err != nil {
+		return false
+	}
+	c, err := t.FindColumnByName(sq.OwnerColumn())
+	if err != nil {
+		return false
+	}
+	nc := normalizeColumn(c)
+	return nc.Default.Valid && strings.HasPrefix(nc.Default.String, "nextval(")
+}
+
+// destructive marks the operations that run only together with a destructive operation
+func destructive(ops []*Operation) []*Operation {
+	for _, op := range ops {
+		op.Destructive = true
+	}
+	return ops
+}
+
+// recreated reports whether the generated expression of column is changed,
+// such column is dropped and added again unless drops are disabled
+func (c *PatchColumn) recreated() bool {
+	if c.from == nil || c.to == nil || c.to.Generated == "" || PatchDropDisable {
+		return false
+	}
+	return NormalizeExpr(c.from.Generated) != NormalizeExpr(c.to.Generated)
+}
+
+// generatedWarning describes the changed generated expression that is not applied
+// because the column can not be dropped
+func (c *PatchColumn) generatedWarning() string {
+	if c.from == nil || c.to == nil || c.to.Generated == "" || !PatchDropDisable ||
+		NormalizeExpr(c.from.Generated) == NormalizeExpr(c.to.Generated) {
+		return ""
+	}
+	return fmt.Sprintf("column %s.%s: generated expression change requires dropping the column, drops are disabled",
+		c.tableName, QuoteIdent(c.to.Name))
+}
+
+func (c *PatchColumn) drop() []*Operation {
 	if PatchDropDisable {
 		return nil
 	}
-	return []string{
-		fmt.Sprintf("ALTER TABLE %s DROP COLUMN IF EXISTS %s", c.tableName, c.from.Name),
-	}
+	return []*Operation{{
+		Kind:        OpDrop,
+		Object:      "COLUMN",
+		Target:      c.tableName + "." + QuoteIdent(c.from.Name),
+		SQL:         fmt.Sprintf("ALTER TABLE %s DROP COLUMN IF EXISTS %s", c.tableName, QuoteIdent(c.from.Name)),
+		Destructive: true,
+		Lock:        LockAccessExclusive,
+	}}
 }
 
 type PatchIndex struct {
-	from, to *Index
+	from, to       *Index
+	namespace      string
+	tableName      string
+	renamedColumns map[string]string // old to new names of renamed table columns
+	recreate       bool              // index uses the recreated generated column
 }
 
-func (i *PatchIndex) GenerateSQL() []string {
+func (i *PatchIndex) Operations() []*Operation {
 	if i.from != nil && i.to != nil {
 		return i.alter()
 	}
@@ -200,7 +453,7 @@ func (i *PatchIndex) GenerateSQL() []string {
 	return i.drop()
 }
 
-func createIndexDDL(idx *Index) string {
+func createIndexDDL(idx *Index, tableName string) string {
 	sb := &strings.Builder{}
 	fmt.Fprint(sb, "CREATE")
 	if idx.IsUnique {
@@ -211,7 +464,7 @@ func createIndexDDL(idx *Index) string {
 		fmt.Fprint(sb, " CONCURRENTLY")
 	}
 	fmt.Fprintf(sb, " %s ON %s",
-		idx.Name, *idx.Table)
+		QuoteIdent(idx.Name), tableName)
 	if len(idx.MethodName) > 0 {
 		fmt.Fprint(sb, " USING ", idx.MethodName)
 	}
@@ -219,7 +472,7 @@ func createIndexDDL(idx *Index) string {
 	if len(idx.ColDef) > 0 {
 		sb.WriteString(idx.ColDef)
 	} else {
-		fmt.Fprint(sb, strings.Join(idx.Columns, ", "))
+		fmt.Fprint(sb, strings.Join(quoteIdents(idx.Columns), ", "))
 	}
 	sb.WriteByte(')')
 	if len(idx.With) > 0 {
@@ -234,103 +487,247 @@ func createIndexDDL(idx *Index) string {
 	return sb.String()
 }
 
-func (i *PatchIndex) create() []string {
-	return []string{createIndexDDL(i.to)}
+func (i *PatchIndex) create() []*Operation {
+	op := &Operation{
+		Kind:   OpCreate,
+		Object: "INDEX",
+		Target: quotedName(i.namespace, i.to.Name),
+		SQL:    createIndexDDL(i.to, i.tableName),
+		Lock:   LockShare,
+	}
+	if i.to.Concurrently {
+		op.OutsideTx = true
+		op.Lock = LockShareUpdateExclusive
+	}
+	ret := []*Operation{op}
+	if i.to.Comment != "" {
+		ret = append(ret, commentOp("INDEX", quotedName(i.namespace, i.to.Name), i.to.Comment))
+	}
+	return ret
 }
 
-func (i *PatchIndex) alter() []string {
+func (i *PatchIndex) alter() []*Operation {
 	if i.from.MethodName == "" {
 		i.from.MethodName = "btree"
 	}
 	if i.to.MethodName == "" {
 		i.to.MethodName = "btree"
 	}
-	if strings.EqualFold(createIndexDDL(i.from), createIndexDDL(i.to)) {
-		return nil
+	// renamed index is compared with the new names of index and columns
+	from := *i.from
+	from.Name = i.to.Name
+	from.Columns = renameColumns(from.Columns, i.renamedColumns)
+	if !i.recreate && strings.EqualFold(createIndexDDL(&from, i.tableName), createIndexDDL(i.to, i.tableName)) {
+		ret := []*Operation{}
+		if i.from.Name != i.to.Name {
+			ret = append(ret, renameOp("INDEX", "ALTER INDEX "+quotedName(i.namespace, i.from.Name)+" RENAME",
+				quotedName(i.namespace, i.to.Name), i.to.Name, LockShareUpdateExclusive))
+		}
+		if i.from.Comment != i.to.Comment {
+			ret = append(ret, commentOp("INDEX", quotedName(i.namespace, i.to.Name), i.to.Comment))
+		}
+		return ret
+	}
+	if i.recreate {
+		return destructive(append(i.drop(), i.create()...))
 	}
 	return append(i.drop(), i.create()...)
 }
 
-func (i *PatchIndex) drop() []string {
+func (i *PatchIndex) drop() []*Operation {
 	// always drop unused indexes
-	return []string{
-		fmt.Sprintf("DROP INDEX IF EXISTS %s", i.from.Name),
-	}
+	name := quotedName(i.namespace, i.from.Name)
+	return []*Operation{{
+		Kind:        OpDrop,
+		Object:      "INDEX",
+		Target:      name,
+		SQL:         fmt.Sprintf("DROP INDEX IF EXISTS %s", name),
+		Destructive: i.to == nil,
+		Lock:        LockAccessExclusive,
+	}}
 }
 
 type PatchConstraint struct {
-	from, to  *Constraint
-	tableName string
-	newTable  bool
+	from, to       *Constraint
+	tableName      string
+	newTable       bool
+	renamedColumns map[string]string // old to new names of renamed table columns
+	recreate       bool              // constraint uses the recreated generated column
 }
 
-func (c *PatchConstraint) GenerateSQL() []string {
+func (c *PatchConstraint) Operations() []*Operation {
 	if c.from != nil && c.to != nil {
-		c.from.Table = &c.tableName
-		c.to.Table = &c.tableName
 		return c.alter()
 	}
 	if c.from == nil {
-		c.to.Table = &c.tableName
 		return c.create()
 	}
-	c.from.Table = &c.tableName
 	return c.drop()
 }
 
-func createConstraintDDL(ctr *Constraint, newTable bool) string {
+func createConstraintDDL(ctr *Constraint, tableName string, newTable bool) string {
 	sb := &strings.Builder{}
 	if !newTable {
-		fmt.Fprintf(sb, "ALTER TABLE %s ADD ", *ctr.Table)
+		fmt.Fprintf(sb, "ALTER TABLE %s ADD ", tableName)
 	}
-	fmt.Fprint(sb, "CONSTRAINT ", ctr.Name)
+	fmt.Fprint(sb, "CONSTRAINT ", QuoteIdent(ctr.Name))
 	if len(ctr.Check) > 0 {
 		fmt.Fprint(sb, " CHECK (", ctr.Check, ")")
 	}
+	// foreign keys are relations, see Schema.NormalizeForeignKeys
 	switch ctr.Type {
-	case TypeFK:
-		if ctr.ReferenceTable == nil {
-			fmt.Fprint(sb, " FOREIGN KEY (", strings.Join(ctr.Columns, ", "), ")")
-			fmt.Fprintf(sb, " REFERENCES %s (%s)", *ctr.ReferenceTable, strings.Join(ctr.ReferenceColumns, ", "))
-			if len(ctr.OnDelete) > 0 {
-				fmt.Fprint(sb, " ON DELETE ", ctr.OnDelete)
-			}
-		}
 	case TypePK:
-		fmt.Fprint(sb, " PRIMARY KEY (", strings.Join(ctr.Columns, ", "), ")")
+		fmt.Fprint(sb, " PRIMARY KEY (", strings.Join(quoteIdents(ctr.Columns), ", "), ")")
 	case TypeUQ:
-		fmt.Fprint(sb, " UNIQUE (", strings.Join(ctr.Columns, ", "), ")")
+		fmt.Fprint(sb, " UNIQUE (", strings.Join(quoteIdents(ctr.Columns), ", "), ")")
 	}
 	return sb.String()
 }
 
-func (c *PatchConstraint) create() []string {
-	return []string{createConstraintDDL(c.to, c.newTable)}
+func (c *PatchConstraint) create() []*Operation {
+	target := QuoteIdent(c.to.Name) + " ON " + c.tableName
+	ret := []*Operation{{
+		Kind:   OpCreate,
+		Object: "CONSTRAINT",
+		Target: target,
+		SQL:    createConstraintDDL(c.to, c.tableName, c.newTable),
+		Lock:   LockAccessExclusive,
+	}}
+	if c.to.Comment != "" {
+		ret = append(ret, commentOp("CONSTRAINT", target, c.to.Comment))
+	}
+	return ret
+}
+
+func (c *PatchConstraint) alter() []*Operation {
+	// renamed constraint is compared with the new names of constraint and columns
+	from := *c.from
+	from.Name = c.to.Name
+	from.Columns = renameColumns(from.Columns, c.renamedColumns)
+	if !c.recreate && strings.EqualFold(createConstraintDDL(&from, c.tableName, c.newTable),
+		createConstraintDDL(c.to, c.tableName, c.newTable)) {
+		ret := []*Operation{}
+		target := QuoteIdent(c.to.Name) + " ON " + c.tableName
+		if c.from.Name != c.to.Name {
+			ret = append(ret, renameOp("CONSTRAINT", fmt.Sprintf("ALTER TABLE %s RENAME CONSTRAINT %s", c.tableName, QuoteIdent(c.from.Name)),
+				target, c.to.Name, LockAccessExclusive))
+		}
+		if c.from.Comment != c.to.Comment {
+			ret = append(ret, commentOp("CONSTRAINT", target, c.to.Comment))
+		}
+		return ret
+	}
+	if c.recreate {
+		return destructive(append(c.drop(), c.create()...))
+	}
+	return append(c.drop(), c.create()...)
 }
 
-func (c *PatchConstraint) alter() []string {
-	if strings.EqualFold(createConstraintDDL(c.from, c.newTable),
-		createConstraintDDL(c.to, c.newTable)) {
+func (c *PatchConstraint) drop() []*Operation {
+	return []*Operation{{
+		Kind:        OpDrop,
+		Object:      "CONSTRAINT",
+		Target:      QuoteIdent(c.from.Name) + " ON " + c.tableName,
+		SQL:         fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s", c.tableName, QuoteIdent(c.from.Name)),
+		Destructive: c.to == nil,
+		Lock:        LockAccessExclusive,
+	}}
+}
+
+// PatchPrimaryKey replaces the primary key of altered table,
+// foreign keys that reference the old primary key are recreated by relation patches
+type PatchPrimaryKey struct {
+	from, to  *Constraint
+	tableName string
+}
+
+// newPatchPrimaryKey returns the patch when primary key columns of table are changed,
+// the renamed columns are compared by their new names
+func newPatchPrimaryKey(from, to *Table, renamed map[string]string) *PatchPrimaryKey {
+	pk := &PatchPrimaryKey{
+		from:      from.PrimaryKey(),
+		to:        to.PrimaryKey(),
+		tableName: quotedName(to.Namespace, to.Name),
+	}
+	if pk.from == nil && pk.to == nil ||
+		pk.from != nil && pk.to != nil && equalNames(renameColumns(pk.from.Columns, renamed), pk.to.Columns) {
 		return nil
 	}
-	return append(c.drop(), c.create()...)
+	return pk
+}
+
+func (p *PatchPrimaryKey) Operations() []*Operation {
+	return append(p.drop(), p.create()...)
+}
+
+// drop drops the primary key by its name in the source schema,
+// the replaced primary key is dropped regardless of the drop policy
+func (p *PatchPrimaryKey) drop() []*Operation {
+	if p.from == nil || p.to == nil && PatchDropDisable {
+		return nil
+	}
+	return []*Operation{{
+		Kind:        OpDrop,
+		Object:      "CONSTRAINT",
+		Target:      QuoteIdent(p.from.Name) + " ON " + p.tableName,
+		SQL:         fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s", p.tableName, QuoteIdent(p.from.Name)),
+		Destructive: true,
+		Lock:        LockAccessExclusive,
+	}}
 }
 
-func (c *PatchConstraint) drop() []string {
-	if c.to == nil && c.from.Type == TypePK {
-		// pk not drop
+func (p *PatchPrimaryKey) create() []*Operation {
+	if p.to == nil {
 		return nil
 	}
-	return []string{
-		fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s", c.tableName, c.from.Name),
+	return []*Operation{{
+		Kind:        OpCreate,
+		Object:      "CONSTRAINT",
+		Target:      QuoteIdent(p.to.Name) + " ON " + p.tableName,
+		SQL:         createConstraintDDL(p.to, p.tableName, false),
+		Destructive: p.from != nil,
+		Lock:        LockAccessExclusive,
+	}}
+}
+
+// equalNames reports whether both lists contain the same names in the same order
+func equalNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sameNames reports whether both lists contain the same names in any order
+func sameNames(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[string]int, len(a))
+	for _, n := range a {
+		seen[n]++
+	}
+	for _, n := range b {
+		if seen[n] == 0 {
+			return false
+		}
+		seen[n]--
 	}
+	return true
 }
 
 type PatchRelation struct {
 	from, to *Relation
+	current  *Relation // source relation with the names of renamed objects, nil without renames
+	recreate bool      // referenced primary key is replaced or the column of foreign key is recreated
 }
 
-func (r *PatchRelation) GenerateSQL() []string {
+func (r *PatchRelation) Operations() []*Operation {
 	if r.from != nil && r.to != nil {
 		return r.alter()
 	}
@@ -342,124 +739,882 @@ func (r *PatchRelation) GenerateSQL() []string {
 
 func createRelationDDL(r *Relation) string {
 	sb := &strings.Builder{}
-	fmt.Fprintf(sb, "ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (", r.Table.Name, r.Name)
+	fmt.Fprintf(sb, "ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (", quotedName(r.Table.Namespace, r.Table.Name), QuoteIdent(r.Name))
 	for i, c := range r.Columns {
 		if i > 0 {
 			sb.WriteString(", ")
 		}
-		sb.WriteString(c.Name)
+		sb.WriteString(QuoteIdent(c.Name))
 	}
-	fmt.Fprintf(sb, ") REFERENCES %s (", r.ParentTable.Name)
+	fmt.Fprintf(sb, ") REFERENCES %s (", quotedName(r.ParentTable.Namespace, r.ParentTable.Name))
 	for i, c := range r.ParentColumns {
 		if i > 0 {
 			sb.WriteString(", ")
 		}
-		sb.WriteString(c.Name)
+		sb.WriteString(QuoteIdent(c.Name))
 	}
 	sb.WriteByte(')')
+	sb.WriteString(relationOptionsDDL(r))
+	if r.NotValid {
+		sb.WriteString(" NOT VALID")
+	}
 	return sb.String()
 }
 
-func (r *PatchRelation) create() []string {
-	return []string{createRelationDDL(r.to)}
+// relationOptionsDDL returns match type and referential actions of foreign key
+func relationOptionsDDL(r *Relation) string {
+	sb := &strings.Builder{}
+	if match := fkMatch(r.Match); match != "" {
+		fmt.Fprint(sb, " MATCH ", match)
+	}
+	if action := fkAction(r.OnDelete); action != "" {
+		fmt.Fprint(sb, " ON DELETE ", action)
+	}
+	if action := fkAction(r.OnUpdate); action != "" {
+		fmt.Fprint(sb, " ON UPDATE ", action)
+	}
+	sb.WriteString(deferrableDDL(r))
+	return sb.String()
+}
+
+// deferrableDDL returns the deferrable clause of foreign key, INITIALLY DEFERRED implies DEFERRABLE
+func deferrableDDL(r *Relation) string {
+	switch {
+	case r.InitiallyDeferred:
+		return " DEFERRABLE INITIALLY DEFERRED"
+	case r.Deferrable:
+		return " DEFERRABLE"
+	}
+	return ""
+}
+
+// fkAction returns normalized referential action, the default NO ACTION is empty
+func fkAction(action string) string {
+	action = strings.ToUpper(strings.Join(strings.Fields(action), " "))
+	if action == "NO ACTION" {
+		return ""
+	}
+	return action
+}
+
+// fkMatch returns normalized match type, the default SIMPLE is empty
+func fkMatch(match string) string {
+	match = strings.ToUpper(strings.TrimSpace(match))
+	if match == "SIMPLE" {
+		return ""
+	}
+	return match
+}
+
+// changed reports whether the foreign key must be recreated,
+// deferrability and validation are altered in place
+func (r *PatchRelation) changed() bool {
+	if r.from == nil || r.to == nil {
+		return false
+	}
+	if r.recreate {
+		return true
+	}
+	from, to := *r.from, *r.to
+	if r.current != nil {
+		from = *r.current
+	}
+	from.NotValid, to.NotValid = false, false
+	from.Deferrable, to.Deferrable = false, false
+	from.InitiallyDeferred, to.InitiallyDeferred = false, false
+	return !strings.EqualFold(createRelationDDL(&from), createRelationDDL(&to))
+}
+
+func (r *PatchRelation) create() []*Operation {
+	target := QuoteIdent(r.to.Name) + " ON " + quotedName(r.to.Table.Namespace, r.to.Table.Name)
+	ret := []*Operation{{
+		Kind:        OpCreate,
+		Object:      "CONSTRAINT",
+		Target:      target,
+		SQL:         createRelationDDL(r.to),
+		Destructive: r.recreate,
+		Lock:        LockShareRowExclusive,
+	}}
+	if r.to.Comment != "" {
+		ret = append(ret, commentOp("CONSTRAINT", target, r.to.Comment))
+	}
+	return ret
+}
+
+func (r *PatchRelation) alter() []*Operation {
+	if r.changed() {
+		return append(r.drop(), r.create()...)
+	}
+	return r.modify()
 }
 
-func (r *PatchRelation) alter() []string {
-	if strings.EqualFold(createRelationDDL(r.from),
-		createRelationDDL(r.to)) {
+// modify renames and alters deferrability, validates the foreign key and changes the comment
+func (r *PatchRelation) modify() []*Operation {
+	ret := []*Operation{}
+	table := quotedName(r.to.Table.Namespace, r.to.Table.Name)
+	target := QuoteIdent(r.to.Name) + " ON " + table
+	if r.from.Name != r.to.Name {
+		ret = append(ret, renameOp("CONSTRAINT", fmt.Sprintf("ALTER TABLE %s RENAME CONSTRAINT %s", table, QuoteIdent(r.from.Name)),
+			target, r.to.Name, LockAccessExclusive))
+	}
+	if deferrableDDL(r.from) != deferrableDDL(r.to) {
+		clause := strings.TrimSpace(deferrableDDL(r.to))
+		if clause == "" {
+			clause = "NOT DEFERRABLE"
+		} else if !r.to.InitiallyDeferred {
+			clause += " INITIALLY IMMEDIATE"
+		}
+		ret = append(ret, &Operation{
+			Kind:   OpAlter,
+			Object: "CONSTRAINT",
+			Target: target,
+			SQL:    fmt.Sprintf("ALTER TABLE %s ALTER CONSTRAINT %s %s", table, QuoteIdent(r.to.Name), clause),
+			Lock:   LockAccessExclusive,
+		})
+	}
+	if r.from.NotValid && !r.to.NotValid {
+		ret = append(ret, &Operation{
+			Kind:   OpAlter,
+			Object: "CONSTRAINT",
+			Target: target,
+			SQL:    fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s", table, QuoteIdent(r.to.Name)),
+			Lock:   LockShareUpdateExclusive,
+		})
+	}
+	if r.from.Comment != r.to.Comment {
+		ret = append(ret, commentOp("CONSTRAINT", target, r.to.Comment))
+	}
+	return ret
+}
+
+// drop drops the foreign key by its name in the source schema,
+// changed foreign key is dropped regardless of the drop policy, because it is recreated
+func (r *PatchRelation) drop() []*Operation {
+	if r.to == nil && PatchDropDisable {
 		return nil
 	}
-	return append(r.drop(), r.create()...)
-}
-
-func (r *PatchRelation) drop() []string {
-	// TODO:
-	// declare r record;
-	// begin
-	//   for r in (
-	// 	select constraint_name
-	// 	from information_schema.table_constraints
-	// 	where table_name='relationships'
-	// 	and constraint_name like 'fk_%'
-	//   ) loop
-	//   execute CONCAT('ALTER TABLE "relationships" DROP CONSTRAINT '||r.constraint_name);
-	//   end loop;
-	// end;
-	return nil
+	table := quotedName(r.from.Table.Namespace, r.from.Table.Name)
+	return []*Operation{{
+		Kind:        OpDrop,
+		Object:      "CONSTRAINT",
+		Target:      QuoteIdent(r.from.Name) + " ON " + table,
+		SQL:         fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s", table, QuoteIdent(r.from.Name)),
+		Destructive: r.to == nil || r.recreate,
+		Lock:        LockAccessExclusive,
+	}}
+}
+
+type PatchEnum struct {
+	from, to *Enum
+}
+
+func (e *PatchEnum) Operations() []*Operation {
+	if e.from != nil && e.to != nil {
+		return e.alter()
+	}
+	if e.from == nil {
+		return e.create()
+	}
+	return e.drop()
+}
+
+func (e *PatchEnum) create() []*Operation {
+	name := quotedName(e.to.Namespace, e.to.Name)
+	vals := make([]string, len(e.to.Values))
+	for i, v := range e.to.Values {
+		vals[i] = quoteLiteral(v)
+	}
+	ret := []*Operation{{
+		Kind:   OpCreate,
+		Object: "TYPE",
+		Target: name,
+		SQL:    fmt.Sprintf("CREATE TYPE %s AS ENUM (%s)", name, strings.Join(vals, ", ")),
+	}}
+	if e.to.Comment != "" {
+		ret = append(ret, commentOp("TYPE", name, e.to.Comment))
+	}
+	return ret
+}
+
+// alter adds new values at their positions; a new value that takes the place
+// of a removed value between the same neighbours is treated as renamed.
+// PostgreSQL can not drop enum values, so removed values are kept.
+// Added values can not be used in the same transaction, so they are added outside of it.
+func (e *PatchEnum) alter() []*Operation {
+	name := quotedName(e.to.Namespace, e.to.Name)
+	ret := []*Operation{}
+	if e.from.Comment != e.to.Comment {
+		ret = append(ret, commentOp("TYPE", name, e.to.Comment))
+	}
+	alter := func(outsideTx bool, format string, args ...interface{}) {
+		ret = append(ret, &Operation{
+			Kind:      OpAlter,
+			Object:    "TYPE",
+			Target:    name,
+			SQL:       fmt.Sprintf("ALTER TYPE %s ", name) + fmt.Sprintf(format, args...),
+			OutsideTx: outsideTx,
+			Lock:      LockAccessExclusive,
+		})
+	}
+	cur := append([]string{}, e.from.Values...)
+	indexOf := func(vs []string, v string) int {
+		for i, vv := range vs {
+			if vv == v {
+				return i
+			}
+		}
+		return -1
+	}
+	for i, v := range e.to.Values {
+		if indexOf(cur, v) >= 0 {
+			continue
+		}
+		// only the value with rename hint is renamed, the rows keep the renamed value
+		if old, ok := e.to.RenamedValues[v]; ok && indexOf(e.to.Values, old) < 0 {
+			if j := indexOf(cur, old); j >= 0 {
+				alter(false, "RENAME VALUE %s TO %s", quoteLiteral(old), quoteLiteral(v))
+				cur[j] = v
+				continue
+			}
+		}
+		pos := -1
+		if i > 0 {
+			pos = indexOf(cur, e.to.Values[i-1])
+		}
+		switch {
+		case pos >= 0:
+			alter(true, "ADD VALUE %s AFTER %s", quoteLiteral(v), quoteLiteral(cur[pos]))
+		case len(cur) > 0:
+			alter(true, "ADD VALUE %s BEFORE %s", quoteLiteral(v), quoteLiteral(cur[0]))
+		default:
+			alter(true, "ADD VALUE %s", quoteLiteral(v))
+		}
+		cur = append(cur[:pos+1], append([]string{v}, cur[pos+1:]...)...)
+	}
+	return ret
+}
+
+// removedWarning describes the removed enum values, PostgreSQL can not drop enum values
+func (e *PatchEnum) removedWarning() string {
+	if e.from == nil || e.to == nil {
+		return ""
+	}
+	renamed := map[string]bool{}
+	for _, old := range e.to.RenamedValues {
+		renamed[old] = true
+	}
+	removed := []string{}
+	for _, v := range e.from.Values {
+		found := renamed[v]
+		for _, rv := range e.to.Values {
+			found = found || rv == v
+		}
+		if !found {
+			removed = append(removed, quoteLiteral(v))
+		}
+	}
+	if len(removed) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("enum %s: removed values %s can not be dropped, set renamedValues if they are renamed",
+		e.to.FullName(), strings.Join(removed, ", "))
+}
+
+func (e *PatchEnum) drop() []*Operation {
+	if PatchDropDisable {
+		return nil
+	}
+	name := quotedName(e.from.Namespace, e.from.Name)
+	return []*Operation{{
+		Kind:        OpDrop,
+		Object:      "TYPE",
+		Target:      name,
+		SQL:         fmt.Sprintf("DROP TYPE IF EXISTS %s", name),
+		Destructive: true,
+		Lock:        LockAccessExclusive,
+	}}
+}
+
+type PatchSequence struct {
+	from, to *Sequence
+}
+
+func (sq *PatchSequence) Operations() []*Operation {
+	if sq.from != nil && sq.to != nil {
+		return sq.alter()
+	}
+	if sq.from == nil {
+		return sq.create()
+	}
+	return sq.drop()
+}
+
+func (sq *PatchSequence) create() []*Operation {
+	to := sq.to.WithDefaults()
+	name := quotedName(to.Namespace, to.Name)
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "CREATE SEQUENCE %s AS %s INCREMENT BY %d MINVALUE %d MAXVALUE %d START WITH %d CACHE %d",
+		name, to.DataType, to.Increment, to.MinValue.Int64, to.MaxValue.Int64, to.Start.Int64, to.Cache)
+	if to.Cycle {
+		fmt.Fprint(sb, " CYCLE")
+	}
+	ret := []*Operation{{
+		Kind:   OpCreate,
+		Object: "SEQUENCE",
+		Target: name,
+		SQL:    sb.String(),
+	}}
+	if to.Comment != "" {
+		ret = append(ret, commentOp("SEQUENCE", name, to.Comment))
+	}
+	ret = append(ret, ownerOp("SEQUENCE", name, "", to.Owner)...)
+	return append(ret, grantOps("SEQUENCE", name, nil, to.Grants)...)
+}
+
+func (sq *PatchSequence) alter() []*Operation {
+	from, to := sq.from.WithDefaults(), sq.to.WithDefaults()
+	name := quotedName(to.Namespace, to.Name)
+	opts := sequenceOptions(from, to)
+	if from.DataType != to.DataType {
+		opts = append([]string{"AS " + to.DataType}, opts...)
+	}
+	ret := []*Operation{}
+	if len(opts) > 0 {
+		ret = append(ret, &Operation{
+			Kind:   OpAlter,
+			Object: "SEQUENCE",
+			Target: name,
+			SQL:    fmt.Sprintf("ALTER SEQUENCE %s %s", name, strings.Join(opts, " ")),
+			Lock:   LockShareRowExclusive,
+		})
+	}
+	if from.Comment != to.Comment {
+		ret = append(ret, commentOp("SEQUENCE", name, to.Comment))
+	}
+	ret = append(ret, ownerOp("SEQUENCE", name, from.Owner, to.Owner)...)
+	return append(ret, grantOps("SEQUENCE", name, from.Grants, to.Grants)...)
+}
+
+// sequenceOptions returns the sequence option clauses of 'to' that differ from 'from',
+// both sequences must be filled with defaults
+func sequenceOptions(from, to *Sequence) []string {
+	ret := []string{}
+	if from.Increment != to.Increment {
+		ret = append(ret, fmt.Sprint("INCREMENT BY ", to.Increment))
+	}
+	if from.MinValue != to.MinValue {
+		ret = append(ret, fmt.Sprint("MINVALUE ", to.MinValue.Int64))
+	}
+	if from.MaxValue != to.MaxValue {
+		ret = append(ret, fmt.Sprint("MAXVALUE ", to.MaxValue.Int64))
+	}
+	if from.Start != to.Start {
+		ret = append(ret, fmt.Sprint("START WITH ", to.Start.Int64))
+	}
+	if from.Cache != to.Cache {
+		ret = append(ret, fmt.Sprint("CACHE ", to.Cache))
+	}
+	if from.Cycle != to.Cycle {
+		if to.Cycle {
+			ret = append(ret, "CYCLE")
+		} else {
+			ret = append(ret, "NO CYCLE")
+		}
+	}
+	return ret
+}
+
+// owned sets the sequence owner column, it must be called when
+// the owner table already exists
+func (sq *PatchSequence) owned() []*Operation {
+	if sq.to == nil {
+		return nil
+	}
+	if sq.from != nil && sq.from.OwnedBy == sq.to.OwnedBy {
+		return nil
+	}
+	if sq.from == nil && sq.to.OwnedBy == "" {
+		return nil
+	}
+	owner := "NONE"
+	if sq.to.OwnedBy != "" {
+		owner = quotedName(sq.to.Namespace, sq.to.OwnerTable()) + "." + QuoteIdent(sq.to.OwnerColumn())
+	}
+	name := quotedName(sq.to.Namespace, sq.to.Name)
+	return []*Operation{{
+		Kind:   OpAlter,
+		Object: "SEQUENCE",
+		Target: name,
+		SQL:    fmt.Sprintf("ALTER SEQUENCE %s OWNED BY %s", name, owner),
+		Lock:   LockShareRowExclusive,
+	}}
+}
+
+func (sq *PatchSequence) drop() []*Operation {
+	if PatchDropDisable {
+		return nil
+	}
+	name := quotedName(sq.from.Namespace, sq.from.Name)
+	return []*Operation{{
+		Kind:        OpDrop,
+		Object:      "SEQUENCE",
+		Target:      name,
+		SQL:         fmt.Sprintf("DROP SEQUENCE IF EXISTS %s", name),
+		Destructive: true,
+		Lock:        LockAccessExclusive,
+	}}
+}
+
+// commentDDL returns COMMENT ON statement, empty comment is removed
+func commentDDL(objType, name, comment string) string {
+	if comment == "" {
+		return fmt.Sprintf("COMMENT ON %s %s IS NULL", objType, name)
+	}
+	return fmt.Sprintf("COMMENT ON %s %s IS %s", objType, name, quoteLiteral(comment))
+}
+
+// quoteLiteral returns s as a SQL string literal
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// PatchNamespace creates missing namespaces, namespaces are never dropped,
+// because they may contain objects that are not described by schema
+type PatchNamespace struct {
+	from, to *Namespace
+}
+
+func (n *PatchNamespace) Operations() []*Operation {
+	if n.to == nil {
+		return nil
+	}
+	if n.from == nil {
+		return n.create()
+	}
+	return n.alter()
+}
+
+func (n *PatchNamespace) create() []*Operation {
+	name := QuoteIdent(n.to.Name)
+	ret := []*Operation{{
+		Kind:   OpCreate,
+		Object: "SCHEMA",
+		Target: name,
+		SQL:    fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s", name),
+	}}
+	if n.to.Comment != "" {
+		ret = append(ret, commentOp("SCHEMA", name, n.to.Comment))
+	}
+	ret = append(ret, ownerOp("SCHEMA", name, "", n.to.Owner)...)
+	return append(ret, grantOps("SCHEMA", name, nil, n.to.Grants)...)
+}
+
+func (n *PatchNamespace) alter() []*Operation {
+	name := QuoteIdent(n.to.Name)
+	ret := []*Operation{}
+	if n.from.Comment != n.to.Comment {
+		ret = append(ret, commentOp("SCHEMA", name, n.to.Comment))
+	}
+	ret = append(ret, ownerOp("SCHEMA", name, n.from.Owner, n.to.Owner)...)
+	return append(ret, grantOps("SCHEMA", name, n.from.Grants, n.to.Grants)...)
 }
 
 type PatchSchema struct {
 	CurrentSchema string
+	// TargetNamespace retargets all objects of the target schema into this namespace,
+	// only objects of this namespace are compared with the source schema
+	TargetNamespace string
+	// DetectRenames warns about dropped and added objects that are probably renamed
+	DetectRenames bool
+	// MatViewNoData creates materialized views WITH NO DATA and refreshes them at the end of migration
+	MatViewNoData bool
+	namespaces    []*PatchNamespace
+	extensions    []*PatchExtension
+	enums         []*PatchEnum
+	domains       []*PatchDomain
+	composites    []*PatchCompositeType
+	sequences     []*PatchSequence
+	functions     []*PatchFunction
 	tables        []*PatchTable
 	relations     []*PatchRelation
+	warnings      []string
+}
+
+// Warnings returns the schema differences that can not be migrated
+func (s *PatchSchema) Warnings() []string {
+	return s.warnings
 }
 
-func (t *PatchSchema) GenerateSQL() (ret []string) {
-	// TODO: using CurrentSchema as schema prefix
+// GenerateSQL returns SQL queries of migration plan
+func (t *PatchSchema) GenerateSQL() []string {
+	return t.Plan().SQL()
+}
+
+// Plan returns ordered migration operations
+func (t *PatchSchema) Plan() *Plan {
+	ret := []*Operation{}
+	// namespaces are created before all objects
+	for _, pn := range t.namespaces {
+		ret = append(ret, pn.Operations()...)
+	}
+	// extensions are created before the objects that use their types, functions and operators
+	for _, pe := range t.extensions {
+		if pe.to != nil {
+			ret = append(ret, pe.Operations()...)
+		}
+	}
+	// types are created before the tables that use them
+	for _, pe := range t.enums {
+		if pe.to != nil {
+			ret = append(ret, pe.Operations()...)
+		}
+	}
+	// domains and composite types may use enums
+	for _, pd := range t.domains {
+		if pd.to != nil {
+			ret = append(ret, pd.Operations()...)
+		}
+	}
+	for _, pc := range t.composites {
+		if pc.to != nil {
+			ret = append(ret, pc.Operations()...)
+		}
+	}
+	// sequences are created before the tables that use them in defaults
+	for _, sq := range t.sequences {
+		if sq.to != nil {
+			ret = append(ret, sq.Operations()...)
+		}
+	}
+	// triggers that call the recreated functions are dropped before the functions
 	for _, st := range t.tables {
-		ret = append(ret, st.GenerateSQL()...)
+		for _, tr := range st.triggers {
+			if tr.recreate {
+				ret = append(ret, tr.drop()...)
+			}
+		}
 	}
+	// functions are created before the triggers that use them
+	for _, pf := range t.functions {
+		if pf.to != nil {
+			ret = append(ret, pf.Operations()...)
+		}
+	}
+	// removed and changed foreign keys are dropped before the tables they reference
 	for _, rt := range t.relations {
-		ret = append(ret, rt.GenerateSQL()...)
+		if rt.to == nil || rt.changed() {
+			ret = append(ret, rt.drop()...)
+		}
+	}
+	// tables are dropped, created and altered in dependency order
+	for _, st := range t.tables {
+		if st.to == nil {
+			ret = append(ret, st.Operations()...)
+		}
+	}
+	// recreated views are dropped in reverse dependency order before they are created again
+	for i := len(t.tables) - 1; i >= 0; i-- {
+		if t.tables[i].recreate {
+			ret = append(ret, t.tables[i].drop()...)
+		}
+	}
+	for _, st := range t.tables {
+		if st.to != nil {
+			ret = append(ret, st.Operations()...)
+		}
+	}
+	// and created after all tables
+	for _, rt := range t.relations {
+		switch {
+		case rt.to == nil:
+		case rt.from == nil || rt.changed():
+			ret = append(ret, rt.create()...)
+		default:
+			ret = append(ret, rt.modify()...)
+		}
+	}
+	// and owned by the table columns after the tables are created
+	for _, sq := range t.sequences {
+		ret = append(ret, sq.owned()...)
+	}
+	// and dropped after the tables and triggers that used them
+	for _, pf := range t.functions {
+		if pf.to == nil {
+			ret = append(ret, pf.Operations()...)
+		}
+	}
+	for _, sq := range t.sequences {
+		if sq.to == nil {
+			ret = append(ret, sq.Operations()...)
+		}
+	}
+	for _, pc := range t.composites {
+		if pc.to == nil {
+			ret = append(ret, pc.Operations()...)
+		}
+	}
+	for _, pd := range t.domains {
+		if pd.to == nil {
+			ret = append(ret, pd.Operations()...)
+		}
+	}
+	for _, pe := range t.enums {
+		if pe.to == nil {
+			ret = append(ret, pe.Operations()...)
+		}
+	}
+	for _, pe := range t.extensions {
+		if pe.to == nil {
+			ret = append(ret, pe.Operations()...)
+		}
+	}
+	// materialized views created WITH NO DATA are filled at the end
+	for _, st := range t.tables {
+		ret = append(ret, st.refresh()...)
+	}
+	return &Plan{
+		Operations: ret,
+		Warnings:   t.warnings,
 	}
-	return
 }
 
 func (s *PatchSchema) Build(from, to *Schema) error {
+	if s.TargetNamespace != "" {
+		// the target schema of caller is kept as it is
+		to = to.Clone()
+		to.Retarget(s.TargetNamespace)
+		from = from.InNamespace(s.TargetNamespace)
+	}
+	from.NormalizeNamespaces()
+	to.NormalizeNamespaces()
+	if err := from.NormalizeForeignKeys(); err != nil {
+		return fmt.Errorf("source schema foreign keys error: %w", err)
+	}
+	if err := to.NormalizeForeignKeys(); err != nil {
+		return fmt.Errorf("target schema foreign keys error: %w", err)
+	}
 	if err := from.Validate(); err != nil {
 		return fmt.Errorf("source schema validation error: %w", err)
 	}
 	if err := to.Validate(); err != nil {
 		return fmt.Errorf("target schema validation error: %w", err)
 	}
+	if err := validateRoles(from, to); err != nil {
+		return fmt.Errorf("target schema validation error: %w", err)
+	}
 	s.CurrentSchema = to.CurrentSchema
 	s.tables = make([]*PatchTable, 0, len(from.Tables)+len(to.Tables))
 	s.relations = make([]*PatchRelation, 0, len(from.Relations)+len(to.Relations))
+	s.enums = make([]*PatchEnum, 0, len(from.Enums)+len(to.Enums))
+	s.domains = make([]*PatchDomain, 0, len(from.Domains)+len(to.Domains))
+	s.composites = make([]*PatchCompositeType, 0, len(from.Composites)+len(to.Composites))
+	s.sequences = make([]*PatchSequence, 0, len(from.Sequences)+len(to.Sequences))
+	s.functions = make([]*PatchFunction, 0, len(from.Functions)+len(to.Functions))
+	s.extensions = make([]*PatchExtension, 0, len(from.Extensions)+len(to.Extensions))
+	s.namespaces = nil
+	s.warnings = nil
+
+	// create namespaces, the current schema of source always exists
+	fromNames := append(from.NamespaceNames(), from.CurrentSchema)
+	for _, name := range to.NamespaceNames() {
+		pn := &PatchNamespace{}
+		pn.to, _ = to.FindNamespaceByName(name)
+		exists := false
+		for _, fn := range fromNames {
+			if fn == name {
+				exists = true
+				break
+			}
+		}
+		if exists {
+			if pn.to == nil {
+				// not declared, nothing to alter
+				continue
+			}
+			pn.from, _ = from.FindNamespaceByName(name)
+			if pn.from == nil {
+				pn.from = &Namespace{Name: name}
+			}
+		} else if pn.to == nil {
+			pn.to = &Namespace{Name: name}
+		}
+		s.namespaces = append(s.namespaces, pn)
+	}
+
+	// drop or alter extensions
+	for _, e := range from.Extensions {
+		pe := &PatchExtension{
+			from: e,
+		}
+		re, err := to.FindExtension(e.Name)
+		if err == nil {
+			pe.to = re
+		}
+		s.extensions = append(s.extensions, pe)
+	}
+	// create extensions
+	for _, e := range to.Extensions {
+		if _, err := from.FindExtension(e.Name); err != nil {
+			s.extensions = append(s.extensions, &PatchExtension{to: e})
+		}
+	}
 
-	// drop or alter tables
+	// drop or alter enums
+	for _, e := range from.Enums {
+		pe := &PatchEnum{
+			from: e,
+		}
+		re, err := to.FindEnum(e.Namespace, e.Name)
+		if err == nil {
+			pe.to = re
+			if w := pe.removedWarning(); w != "" {
+				s.warnings = append(s.warnings, w)
+			}
+		}
+		s.enums = append(s.enums, pe)
+	}
+	// create enums
+	for _, e := range to.Enums {
+		if _, err := from.FindEnum(e.Namespace, e.Name); err != nil {
+			s.enums = append(s.enums, &PatchEnum{to: e})
+		}
+	}
+
+	// drop or alter domains
+	for _, d := range from.Domains {
+		pd := &PatchDomain{
+			from: d,
+		}
+		rd, err := to.FindDomain(d.Namespace, d.Name)
+		if err == nil {
+			pd.to = rd
+			if w := pd.baseTypeWarning(); w != "" {
+				s.warnings = append(s.warnings, w)
+			}
+		}
+		s.domains = append(s.domains, pd)
+	}
+	// create domains
+	for _, d := range to.Domains {
+		if _, err := from.FindDomain(d.Namespace, d.Name); err != nil {
+			s.domains = append(s.domains, &PatchDomain{to: d})
+		}
+	}
+
+	// drop or alter composite types
+	for _, ct := range from.Composites {
+		pc := &PatchCompositeType{
+			from: ct,
+		}
+		rct, err := to.FindComposite(ct.Namespace, ct.Name)
+		if err == nil {
+			pc.to = rct
+		}
+		s.composites = append(s.composites, pc)
+	}
+	// create composite types
+	for _, ct := range to.Composites {
+		if _, err := from.FindComposite(ct.Namespace, ct.Name); err != nil {
+			s.composites = append(s.composites, &PatchCompositeType{to: ct})
+		}
+	}
+
+	// drop or alter sequences
+	for _, sq := range from.Sequences {
+		psq := &PatchSequence{
+			from: sq,
+		}
+		rsq, err := to.FindSequence(sq.Namespace, sq.Name)
+		if err == nil {
+			psq.to = rsq
+		} else if impliedSequence(to, sq) {
+			continue
+		}
+		s.sequences = append(s.sequences, psq)
+	}
+	// create sequences
+	for _, sq := range to.Sequences {
+		if _, err := from.FindSequence(sq.Namespace, sq.Name); err != nil {
+			s.sequences = append(s.sequences, &PatchSequence{to: sq})
+		}
+	}
+
+	// drop or alter functions
+	for _, f := range from.Functions {
+		pf := &PatchFunction{
+			from: f,
+		}
+		rf, err := to.FindFunction(f.Namespace, f.Name, f.Arguments)
+		if err == nil {
+			pf.to = rf
+			if w := pf.returnsWarning(); w != "" {
+				s.warnings = append(s.warnings, w)
+			}
+		}
+		s.functions = append(s.functions, pf)
+	}
+	// create functions
+	for _, f := range to.Functions {
+		if _, err := from.FindFunction(f.Namespace, f.Name, f.Arguments); err != nil {
+			s.functions = append(s.functions, &PatchFunction{to: f})
+		}
+	}
+
+	// drop or alter tables, the renamed objects are paired by renamedFrom hints
 	for _, t := range from.Tables {
 		pt := &PatchTable{
 			from: t,
 		}
-		rt, err := to.FindTableByName(t.Name)
-		if err == nil {
+		tableName := quotedName(t.Namespace, t.Name)
+		rt := findRenamedTable(from, to, t)
+		if rt != nil {
 			pt.to = rt
+			tableName = quotedName(rt.Namespace, rt.Name)
 		}
 		s.tables = append(s.tables, pt)
+		// old names of renamed columns to the new names
+		renamed := map[string]string{}
 		for _, c := range t.Columns {
 			pc := &PatchColumn{
-				tableName: t.Name,
+				tableName: tableName,
 				from:      c,
 			}
 			if rt != nil {
-				rc, err := rt.FindColumnByName(c.Name)
-				if err == nil {
-					pc.to = rc
+				pc.to = findRenamedColumn(t, rt, c)
+				if pc.to != nil && pc.to.Name != c.Name {
+					renamed[c.Name] = pc.to.Name
 				}
 			}
 			pt.columns = append(pt.columns, pc)
 		}
 		if rt != nil {
+			pt.primaryKey = newPatchPrimaryKey(t, rt, renamed)
 			for _, c := range rt.Columns {
-				pc := &PatchColumn{
-					tableName: t.Name,
-					to:        c,
-				}
-				tc, err := t.FindColumnByName(c.Name)
 				fnd := false
-				if err == nil {
-					pc.from = tc
-
-					for _, v := range pt.columns {
-						if v.from == pc.from &&
-							v.to == pc.to {
-							fnd = true
-							break
-						}
+				for _, v := range pt.columns {
+					if v.to == c {
+						fnd = true
+						break
 					}
 				}
 				if !fnd {
-					pt.columns = append(pt.columns, pc)
+					pt.columns = append(pt.columns, &PatchColumn{
+						tableName: tableName,
+						to:        c,
+					})
+				}
+			}
+		}
+		if rt != nil {
+			if w := columnOrderDrift(t, rt); w != "" {
+				s.warnings = append(s.warnings, w)
+			}
+			for _, pc := range pt.columns {
+				if w := typeChangeWarning(pc); w != "" {
+					s.warnings = append(s.warnings, w)
+				}
+				if w := pc.generatedWarning(); w != "" {
+					s.warnings = append(s.warnings, w)
 				}
 			}
 		}
@@ -468,13 +1623,13 @@ func (s *PatchSchema) Build(from, to *Schema) error {
 				idx.Table = &t.Name
 			}
 			pi := &PatchIndex{
-				from: idx,
+				namespace:      t.Namespace,
+				tableName:      tableName,
+				from:           idx,
+				renamedColumns: renamed,
 			}
 			if rt != nil {
-				ri, err := rt.FindIndexByName(idx.Name)
-				if err == nil {
-					pi.to = ri
-				}
+				pi.to = findRenamedIndex(t, rt, idx)
 			}
 			pt.indexes = append(pt.indexes, pi)
 		}
@@ -484,39 +1639,38 @@ func (s *PatchSchema) Build(from, to *Schema) error {
 				if idx.Table == nil {
 					idx.Table = &rt.Name
 				}
-				pi := &PatchIndex{
-					to: idx,
-				}
-				ti, err := t.FindIndexByName(idx.Name)
 				fnd := false
-				if err == nil {
-					pi.from = ti
-
-					for _, v := range pt.indexes {
-						if v.from == pi.from &&
-							v.to == pi.to {
-							fnd = true
-							break
-						}
+				for _, v := range pt.indexes {
+					if v.to == idx {
+						fnd = true
+						break
 					}
 				}
 				if !fnd {
-					pt.indexes = append(pt.indexes, pi)
+					pt.indexes = append(pt.indexes, &PatchIndex{
+						namespace: rt.Namespace,
+						tableName: tableName,
+						to:        idx,
+					})
 				}
 			}
 		}
 
+		// primary key is patched separately
 		for _, c := range t.Constraints {
+			if c.Type == TypePK {
+				continue
+			}
 			if c.Table == nil {
 				c.Table = &t.Name
 			}
 			pc := &PatchConstraint{
-				tableName: t.Name,
-				from:      c,
+				tableName:      tableName,
+				from:           c,
+				renamedColumns: renamed,
 			}
 			if rt != nil {
-				rc, err := rt.FindConstraintByName(c.Name)
-				if err == nil {
+				if rc := findRenamedConstraint(t, rt, c); rc != nil && rc.Type != TypePK {
 					pc.to = rc
 				}
 			}
@@ -525,28 +1679,58 @@ func (s *PatchSchema) Build(from, to *Schema) error {
 
 		if rt != nil {
 			for _, c := range rt.Constraints {
+				if c.Type == TypePK {
+					continue
+				}
 				if c.Table == nil {
 					c.Table = &rt.Name
 				}
-				pc := &PatchConstraint{
-					tableName: rt.Name,
-					to:        c,
-				}
-				tc, err := t.FindConstraintByName(c.Name)
 				fnd := false
-				if err == nil {
-					pc.from = tc
-
-					for _, v := range pt.constraints {
-						if v.from == pc.from &&
-							v.to == pc.to {
-							fnd = true
-							break
-						}
+				for _, v := range pt.constraints {
+					if v.to == c {
+						fnd = true
+						break
 					}
 				}
 				if !fnd {
-					pt.constraints = append(pt.constraints, pc)
+					pt.constraints = append(pt.constraints, &PatchConstraint{
+						tableName: tableName,
+						to:        c,
+					})
+				}
+			}
+			for _, tr := range t.Triggers {
+				ptr := &PatchTrigger{
+					namespace: rt.Namespace,
+					tableName: tableName,
+					from:      tr,
+				}
+				ptr.to, _ = rt.FindTriggerByName(tr.Name)
+				pt.triggers = append(pt.triggers, ptr)
+			}
+			for _, tr := range rt.Triggers {
+				if _, err := t.FindTriggerByName(tr.Name); err != nil {
+					pt.triggers = append(pt.triggers, &PatchTrigger{
+						namespace: rt.Namespace,
+						tableName: tableName,
+						to:        tr,
+					})
+				}
+			}
+			for _, p := range t.Policies {
+				pp := &PatchPolicy{
+					tableName: tableName,
+					from:      p,
+				}
+				pp.to, _ = rt.FindPolicyByName(p.Name)
+				pt.policies = append(pt.policies, pp)
+			}
+			for _, p := range rt.Policies {
+				if _, err := t.FindPolicyByName(p.Name); err != nil {
+					pt.policies = append(pt.policies, &PatchPolicy{
+						tableName: tableName,
+						to:        p,
+					})
 				}
 			}
 		}
@@ -555,10 +1739,7 @@ func (s *PatchSchema) Build(from, to *Schema) error {
 	for _, rt := range to.Tables {
 		fnd := false
 		for _, t := range s.tables {
-			if t.to == nil {
-				continue
-			}
-			if t.to.Name == rt.Name {
+			if t.to == rt {
 				fnd = true
 				break
 			}
@@ -568,56 +1749,266 @@ func (s *PatchSchema) Build(from, to *Schema) error {
 		}
 		pt := &PatchTable{to: rt}
 		s.tables = append(s.tables, pt)
+		// single column primary key with the default name is declared inline
+		pk := rt.PrimaryKey()
+		inlinePK := pk != nil && len(pk.Columns) == 1 && pk.Name == rt.Name+"_pkey"
 		for _, c := range rt.Columns {
 			pc := &PatchColumn{
-				tableName: rt.Name,
-				to:        c,
-				newTable:  true,
+				tableName:  quotedName(rt.Namespace, rt.Name),
+				to:         c,
+				newTable:   true,
+				primaryKey: inlinePK && c.Name == pk.Columns[0],
 			}
 			pt.columns = append(pt.columns, pc)
 		}
+		if pk != nil && !inlinePK {
+			pt.constraints = append(pt.constraints, &PatchConstraint{
+				tableName: quotedName(rt.Namespace, rt.Name),
+				to:        pk,
+				newTable:  true,
+			})
+		}
 		for _, idx := range rt.Indexes {
 			if idx.Table == nil {
 				idx.Table = &rt.Name
 			}
 			pi := &PatchIndex{
-				to: idx,
+				namespace: rt.Namespace,
+				tableName: quotedName(rt.Namespace, rt.Name),
+				to:        idx,
 			}
 			pt.indexes = append(pt.indexes, pi)
 		}
 		for _, c := range rt.Constraints {
+			if c.Type == TypePK {
+				continue
+			}
 			if c.Table == nil {
 				c.Table = &rt.Name
 			}
 			pc := &PatchConstraint{
-				tableName: rt.Name,
+				tableName: quotedName(rt.Namespace, rt.Name),
 				to:        c,
 				newTable:  true,
 			}
 			pt.constraints = append(pt.constraints, pc)
 		}
+		for _, tr := range rt.Triggers {
+			pt.triggers = append(pt.triggers, &PatchTrigger{
+				namespace: rt.Namespace,
+				tableName: quotedName(rt.Namespace, rt.Name),
+				to:        tr,
+			})
+		}
+		for _, p := range rt.Policies {
+			pt.policies = append(pt.policies, &PatchPolicy{
+				tableName: quotedName(rt.Namespace, rt.Name),
+				to:        p,
+			})
+		}
+	}
+
+	// foreign keys that reference the replaced primary key are recreated
+	replacedPK := map[string]*Constraint{}
+	for _, pt := range s.tables {
+		if pt.primaryKey != nil && pt.primaryKey.from != nil {
+			replacedPK[pt.from.FullName()] = pt.primaryKey.from
+		}
+	}
+
+	// source tables and columns are matched with the target by their new names
+	tableTargets := map[*Table]*Table{}
+	columnTargets := map[*Column]*Column{}
+	for _, pt := range s.tables {
+		if pt.from == nil || pt.to == nil {
+			continue
+		}
+		tableTargets[pt.from] = pt.to
+		for _, pc := range pt.columns {
+			if pc.from != nil && pc.to != nil {
+				columnTargets[pc.from] = pc.to
+			}
+		}
+	}
+	current := func(r *Relation) *Relation {
+		ret := *r
+		if t, ok := tableTargets[r.Table]; ok {
+			ret.Table = t
+		}
+		if t, ok := tableTargets[r.ParentTable]; ok {
+			ret.ParentTable = t
+		}
+		ret.Columns = make([]*Column, len(r.Columns))
+		for i, c := range r.Columns {
+			if rc, ok := columnTargets[c]; ok {
+				c = rc
+			}
+			ret.Columns[i] = c
+		}
+		ret.ParentColumns = make([]*Column, len(r.ParentColumns))
+		for i, c := range r.ParentColumns {
+			if rc, ok := columnTargets[c]; ok {
+				c = rc
+			}
+			ret.ParentColumns[i] = c
+		}
+		return &ret
 	}
 
 	// drop or alter relations
+	matched := map[*Relation]bool{}
 	for _, r := range from.Relations {
 		pt := &PatchRelation{
-			from: r,
+			from:    r,
+			current: current(r),
 		}
-		rt, err := to.FindRelation(r.Table.Name, r.Columns, r.ParentColumns)
-		if err == nil {
+		rt, err := to.FindRelation(pt.current.Table, pt.current.Columns, pt.current.ParentColumns)
+		if err == nil && !matched[rt] {
 			pt.to = rt
+			matched[rt] = true
+			if rt.RenamedFrom == r.Name {
+				pt.current.Name = rt.Name
+			}
+		}
+		if pk, ok := replacedPK[r.ParentTable.FullName()]; ok {
+			cols := make([]string, 0, len(r.ParentColumns))
+			for _, c := range r.ParentColumns {
+				cols = append(cols, c.Name)
+			}
+			pt.recreate = sameNames(cols, pk.Columns)
 		}
 		s.relations = append(s.relations, pt)
 	}
 	// create relations
 	for _, r := range to.Relations {
-		pt := &PatchRelation{
-			to: r,
-		}
-		_, err := from.FindRelation(r.Table.Name, r.Columns, r.ParentColumns)
-		if err != nil {
-			s.relations = append(s.relations, pt)
+		if !matched[r] {
+			s.relations = append(s.relations, &PatchRelation{to: r})
 		}
 	}
+	s.recreateGeneratedDependents()
+	s.recreateFunctionTriggers()
+	for _, pt := range s.tables {
+		pt.noData = s.MatViewNoData
+	}
+	s.buildPartitions(from, to)
+	s.recreateViews(from, to)
+	s.sortTables(from, to)
+	if s.DetectRenames {
+		s.warnings = append(s.warnings, s.probableRenames()...)
+	}
 	return nil
 }
+
+// recreateGeneratedDependents marks the indexes, constraints, primary keys and foreign keys
+// that use the recreated generated columns, they are dropped together with the column
+// and must be created again
+func (s *PatchSchema) recreateGeneratedDependents() {
+	recreated := map[*Column]bool{}
+	for _, pt := range s.tables {
+		names := []string{}
+		for _, pc := range pt.columns {
+			if pc.recreated() {
+				recreated[pc.to] = true
+				names = append(names, pc.to.Name)
+			}
+		}
+		if len(names) == 0 {
+			continue
+		}
+		for _, pi := range pt.indexes {
+			if pi.from != nil && pi.to != nil && (usesColumns(pi.to.Columns, names) ||
+				exprUsesColumns(pi.to.ColDef, names) || exprUsesColumns(pi.to.Where, names)) {
+				pi.recreate = true
+			}
+		}
+		for _, pc := range pt.constraints {
+			if pc.from != nil && pc.to != nil && (usesColumns(pc.to.Columns, names) || exprUsesColumns(pc.to.Check, names)) {
+				pc.recreate = true
+			}
+		}
+		if pk := pt.to.PrimaryKey(); pt.primaryKey == nil && pk != nil && pt.from.PrimaryKey() != nil &&
+			usesColumns(pk.Columns, names) {
+			pt.primaryKey = &PatchPrimaryKey{
+				from:      pt.from.PrimaryKey(),
+				to:        pk,
+				tableName: quotedName(pt.to.Namespace, pt.to.Name),
+			}
+		}
+	}
+	if len(recreated) == 0 {
+		return
+	}
+	for _, pr := range s.relations {
+		if pr.from == nil || pr.to == nil {
+			continue
+		}
+		for _, cols := range [][]*Column{pr.current.Columns, pr.current.ParentColumns} {
+			for _, c := range cols {
+				if recreated[c] {
+					pr.recreate = true
+				}
+			}
+		}
+	}
+}
+
+// usesColumns reports whether the list contains any of column names
+func usesColumns(columns, names []string) bool {
+	for _, c := range columns {
+		for _, n := range names {
+			if c == n {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// exprUsesColumns reports whether the expression refers to any of column names
+func exprUsesColumns(expr string, names []string) bool {
+	if expr == "" {
+		return false
+	}
+	for _, n := range names {
+		re := regexp.MustCompile(`(^|\W)` + regexp.QuoteMeta(n) + `(\W|$)`)
+		if re.MatchString(expr) {
+			return true
+		}
+	}
+	return false
+}
+
+// typeChangeWarning describes the column type change that rewrites the table or likely fails
+func typeChangeWarning(c *PatchColumn) string {
+	if c.from == nil || c.to == nil {
+		return ""
+	}
+	switch tc := ClassifyTypeChange(normalizeColumn(c.from).Type, normalizeColumn(c.to).Type, c.to.Using); tc {
+	case TypeChangeRewrite, TypeChangeFailure:
+		return fmt.Sprintf("column %s.%s: type change %s -> %s: %s",
+			c.tableName, QuoteIdent(c.to.Name), c.from.Type, c.to.Type, tc)
+	}
+	return ""
+}
+
+// columnOrderDrift describes the different order of columns that exist in both tables,
+// PostgreSQL can not reorder columns without recreating the table
+func columnOrderDrift(from, to *Table) string {
+	fromNames := []string{}
+	for _, c := range from.Columns {
+		if _, err := to.FindColumnByName(c.Name); err == nil {
+			fromNames = append(fromNames, c.Name)
+		}
+	}
+	toNames := []string{}
+	for _, c := range to.Columns {
+		if _, err := from.FindColumnByName(c.Name); err == nil {
+			toNames = append(toNames, c.Name)
+		}
+	}
+	if strings.Join(fromNames, ",") == strings.Join(toNames, ",") {
+		return ""
+	}
+	return fmt.Sprintf("table %s: columns order (%s) differs from (%s)",
+		to.FullName(), strings.Join(fromNames, ", "), strings.Join(toNames, ", "))
+}
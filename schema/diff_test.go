@@ -1,6 +1,8 @@
 package schema
 
 import (
+	"database/sql"
+	"math"
 	"strings"
 	"testing"
 )
@@ -70,14 +72,14 @@ func TestPatchSchema_BuildDropAndNew(t *testing.T) {
 		}
 		qs := s.GenerateSQL()
 		qss := strings.Join(qs, "\n")
-		if qss != `DROP TABLE IF EXISTS table_old
-CREATE TABLE table1 (
+		if qss != `DROP TABLE IF EXISTS public.table_old
+CREATE TABLE public.table1 (
 column1 uuid NOT NULL PRIMARY KEY,
 column2 uuid NOT NULL,
 column3 uuid NOT NULL,
 CONSTRAINT table1_constraint_check CHECK (true))
-CREATE INDEX table1_col2 ON table1(column2)
-CREATE INDEX table1_col3 ON table1(column3)` {
+CREATE INDEX table1_col2 ON public.table1(column2)
+CREATE INDEX table1_col3 ON public.table1(column3)` {
 			t.Error(qss)
 		}
 	})
@@ -158,9 +160,9 @@ func TestPatchSchema_BuildAddColIdx(t *testing.T) {
 		}
 		qs := s.GenerateSQL()
 		qss := strings.Join(qs, "\n")
-		if qss != `ALTER TABLE table1 ADD COLUMN column3 uuid NOT NULL
-CREATE INDEX table1_col3 ON table1(column3)
-ALTER TABLE table1 ADD CONSTRAINT table1_constraint_check CHECK (true)` {
+		if qss != `ALTER TABLE public.table1 ADD COLUMN column3 uuid NOT NULL
+CREATE INDEX table1_col3 ON public.table1(column3)
+ALTER TABLE public.table1 ADD CONSTRAINT table1_constraint_check CHECK (true)` {
 			t.Error(qss)
 		}
 	})
@@ -294,7 +296,7 @@ func TestPatchSchema_BuildChangeCol(t *testing.T) {
 		}
 		qs := s.GenerateSQL()
 		qss := strings.Join(qs, "\n")
-		if qss != `ALTER TABLE table1 ALTER COLUMN column2 TYPE text` {
+		if qss != `ALTER TABLE public.table1 ALTER COLUMN column2 TYPE text` {
 			t.Error(qss)
 		}
 	})
@@ -351,7 +353,7 @@ func TestPatchSchema_BuildChangeIdx(t *testing.T) {
 					Indexes: []*Index{
 						{
 							Name:    "table1_col2",
-							Columns: []string{"column2,column3"},
+							Columns: []string{"column2", "column3"},
 						},
 					},
 				},
@@ -365,9 +367,9 @@ func TestPatchSchema_BuildChangeIdx(t *testing.T) {
 		}
 		qs := s.GenerateSQL()
 		qss := strings.Join(qs, "\n")
-		if qss != `ALTER TABLE table1 ADD COLUMN column3 uuid NOT NULL
-DROP INDEX IF EXISTS table1_col2
-CREATE INDEX table1_col2 ON table1 USING btree(column2,column3)` {
+		if qss != `ALTER TABLE public.table1 ADD COLUMN column3 uuid NOT NULL
+DROP INDEX IF EXISTS public.table1_col2
+CREATE INDEX table1_col2 ON public.table1 USING btree(column2, column3)` {
 			t.Error(qss)
 		}
 	})
@@ -444,3 +446,2113 @@ func TestPatchSchema_BuildChangeIdx2(t *testing.T) {
 		}
 	})
 }
+
+func TestPatchSchema_BuildEnum(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		from := &Schema{
+			CurrentSchema: "public",
+		}
+		to := &Schema{
+			CurrentSchema: "public",
+			Enums: []*Enum{
+				{
+					Name:   "order_status",
+					Values: []string{"new", "paid", "it's shipped"},
+				},
+			},
+			Tables: []*Table{
+				{
+					Name: "orders",
+					Columns: []*Column{
+						{
+							Name:       "id",
+							Type:       "uuid",
+							PrimaryKey: true,
+						},
+						{
+							Name: "status",
+							Type: "order_status",
+						},
+					},
+				},
+			},
+		}
+
+		s := &PatchSchema{}
+		if err := s.Build(from, to); err != nil {
+			t.Error(err)
+			return
+		}
+		qss := strings.Join(s.GenerateSQL(), "\n")
+		if qss != `CREATE TYPE public.order_status AS ENUM ('new', 'paid', 'it''s shipped')
+CREATE TABLE public.orders (
+id uuid NOT NULL PRIMARY KEY,
+status order_status NOT NULL)` {
+			t.Error(qss)
+		}
+	})
+
+	t.Run("alter", func(t *testing.T) {
+		from := &Schema{
+			CurrentSchema: "public",
+			Enums: []*Enum{
+				{
+					Name:   "order_status",
+					Values: []string{"new", "paid", "sent"},
+				},
+			},
+		}
+		to := &Schema{
+			CurrentSchema: "public",
+			Enums: []*Enum{
+				{
+					Name:          "order_status",
+					Values:        []string{"draft", "new", "paid", "shipped", "closed"},
+					RenamedValues: map[string]string{"shipped": "sent"},
+				},
+			},
+		}
+
+		s := &PatchSchema{}
+		if err := s.Build(from, to); err != nil {
+			t.Error(err)
+			return
+		}
+		qss := strings.Join(s.GenerateSQL(), "\n")
+		if qss != `ALTER TYPE public.order_status ADD VALUE 'draft' BEFORE 'new'
+ALTER TYPE public.order_status RENAME VALUE 'sent' TO 'shipped'
+ALTER TYPE public.order_status ADD VALUE 'closed' AFTER 'shipped'` {
+			t.Error(qss)
+		}
+		if ws := s.Warnings(); len(ws) != 0 {
+			t.Error(ws)
+		}
+
+		// the value without rename hint is added, the removed value is kept with a warning
+		to.Enums[0].RenamedValues = nil
+		s = &PatchSchema{}
+		if err := s.Build(from, to); err != nil {
+			t.Error(err)
+			return
+		}
+		qss = strings.Join(s.GenerateSQL(), "\n")
+		if qss != `ALTER TYPE public.order_status ADD VALUE 'draft' BEFORE 'new'
+ALTER TYPE public.order_status ADD VALUE 'shipped' AFTER 'paid'
+ALTER TYPE public.order_status ADD VALUE 'closed' AFTER 'shipped'` {
+			t.Error(qss)
+		}
+		if ws := s.Warnings(); len(ws) != 1 || !strings.Contains(ws[0], "removed values 'sent'") {
+			t.Error(ws)
+		}
+	})
+}
+
+func TestPatchSchema_BuildSequence(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		from := &Schema{
+			CurrentSchema: "public",
+		}
+		to := &Schema{
+			CurrentSchema: "public",
+			Sequences: []*Sequence{
+				{
+					Name:     "orders_id_seq",
+					DataType: "integer",
+					OwnedBy:  "orders.id",
+				},
+			},
+			Tables: []*Table{
+				{
+					Name: "orders",
+					Columns: []*Column{
+						{
+							Name:       "id",
+							Type:       "integer",
+							PrimaryKey: true,
+							Default:    sql.NullString{String: "nextval('orders_id_seq'::regclass)", Valid: true},
+						},
+					},
+				},
+			},
+		}
+
+		s := &PatchSchema{}
+		if err := s.Build(from, to); err != nil {
+			t.Error(err)
+			return
+		}
+		qss := strings.Join(s.GenerateSQL(), "\n")
+		if qss != `CREATE SEQUENCE public.orders_id_seq AS integer INCREMENT BY 1 MINVALUE 1 MAXVALUE 2147483647 START WITH 1 CACHE 1
+CREATE TABLE public.orders (
+id integer NOT NULL DEFAULT nextval('orders_id_seq'::regclass) PRIMARY KEY)
+ALTER SEQUENCE public.orders_id_seq OWNED BY public.orders.id` {
+			t.Error(qss)
+		}
+	})
+
+	t.Run("alter and drop", func(t *testing.T) {
+		from := &Schema{
+			CurrentSchema: "public",
+			Sequences: []*Sequence{
+				{
+					Name:      "counter_seq",
+					DataType:  "bigint",
+					Start:     sql.NullInt64{Int64: 1, Valid: true},
+					Increment: 1,
+					MinValue:  sql.NullInt64{Int64: 1, Valid: true},
+					MaxValue:  sql.NullInt64{Int64: math.MaxInt64, Valid: true},
+					Cache:     1,
+				},
+				{
+					Name: "old_seq",
+				},
+			},
+		}
+		to := &Schema{
+			CurrentSchema: "public",
+			Sequences: []*Sequence{
+				{
+					Name:      "counter_seq",
+					Increment: 10,
+					Cycle:     true,
+				},
+			},
+		}
+
+		s := &PatchSchema{}
+		if err := s.Build(from, to); err != nil {
+			t.Error(err)
+			return
+		}
+		qss := strings.Join(s.GenerateSQL(), "\n")
+		if qss != `ALTER SEQUENCE public.counter_seq INCREMENT BY 10 CYCLE
+DROP SEQUENCE IF EXISTS public.old_seq` {
+			t.Error(qss)
+		}
+	})
+
+	t.Run("serial round trip", func(t *testing.T) {
+		// the inspected sequence of serial column is implied by the declared serial column
+		from := &Schema{
+			CurrentSchema: "public",
+			Sequences: []*Sequence{
+				{
+					Name:      "orders_id_seq",
+					DataType:  "integer",
+					Start:     sql.NullInt64{Int64: 1, Valid: true},
+					Increment: 1,
+					MinValue:  sql.NullInt64{Int64: 1, Valid: true},
+					MaxValue:  sql.NullInt64{Int64: math.MaxInt32, Valid: true},
+					Cache:     1,
+					OwnedBy:   "orders.id",
+				},
+			},
+			Tables: []*Table{
+				{
+					Name: "orders",
+					Columns: []*Column{
+						{
+							Name:       "id",
+							Type:       "integer",
+							PrimaryKey: true,
+							Default:    sql.NullString{String: "nextval('orders_id_seq'::regclass)", Valid: true},
+						},
+					},
+				},
+			},
+		}
+		to := &Schema{
+			CurrentSchema: "public",
+			Tables: []*Table{
+				{
+					Name: "orders",
+					Columns: []*Column{
+						{
+							Name:       "id",
+							Type:       "serial",
+							PrimaryKey: true,
+						},
+					},
+				},
+			},
+		}
+
+		s := &PatchSchema{}
+		if err := s.Build(from, to); err != nil {
+			t.Error(err)
+			return
+		}
+		if qss := s.GenerateSQL(); len(qss) > 0 {
+			t.Error(strings.Join(qss, "\n"))
+		}
+	})
+}
+
+func TestPatchSchema_BuildIdentityGenerated(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		from := &Schema{
+			CurrentSchema: "public",
+		}
+		to := &Schema{
+			CurrentSchema: "public",
+			Tables: []*Table{
+				{
+					Name: "items",
+					Columns: []*Column{
+						{
+							Name:       "id",
+							Type:       "integer",
+							PrimaryKey: true,
+							Identity: &Identity{
+								Generation: IdentityAlways,
+								Sequence: &Sequence{
+									Start: sql.NullInt64{Int64: 100, Valid: true},
+								},
+							},
+						},
+						{
+							Name: "price",
+							Type: "numeric",
+						},
+						{
+							Name:      "price_vat",
+							Type:      "numeric",
+							Generated: "price * 1.2",
+						},
+					},
+				},
+			},
+		}
+
+		s := &PatchSchema{}
+		if err := s.Build(from, to); err != nil {
+			t.Error(err)
+			return
+		}
+		qss := strings.Join(s.GenerateSQL(), "\n")
+		if qss != `CREATE TABLE public.items (
+id integer NOT NULL GENERATED ALWAYS AS IDENTITY (START WITH 100) PRIMARY KEY,
+price numeric NOT NULL,
+price_vat numeric NOT NULL GENERATED ALWAYS AS (price * 1.2) STORED)` {
+			t.Error(qss)
+		}
+	})
+
+	t.Run("alter", func(t *testing.T) {
+		from := &Schema{
+			CurrentSchema: "public",
+			Tables: []*Table{
+				{
+					Name: "items",
+					Columns: []*Column{
+						{
+							Name:       "id",
+							Type:       "bigint",
+							PrimaryKey: true,
+							Identity: &Identity{
+								Generation: IdentityAlways,
+							},
+						},
+						{
+							Name:    "num",
+							Type:    "integer",
+							Default: sql.NullString{String: "nextval('items_num_seq'::regclass)", Valid: true},
+						},
+						{
+							Name:     "code",
+							Type:     "integer",
+							Identity: &Identity{Generation: IdentityByDefault},
+						},
+						{
+							Name:      "price_vat",
+							Type:      "numeric",
+							Generated: "price * 1.2",
+						},
+					},
+				},
+			},
+		}
+		to := &Schema{
+			CurrentSchema: "public",
+			Tables: []*Table{
+				{
+					Name: "items",
+					Columns: []*Column{
+						{
+							Name:       "id",
+							Type:       "bigint",
+							PrimaryKey: true,
+							Identity: &Identity{
+								Generation: IdentityByDefault,
+								Sequence:   &Sequence{Increment: 5},
+							},
+						},
+						{
+							Name:     "num",
+							Type:     "integer",
+							Identity: &Identity{Generation: IdentityByDefault},
+						},
+						{
+							Name: "code",
+							Type: "integer",
+						},
+						{
+							Name:      "price_vat",
+							Type:      "numeric",
+							Generated: "price * 1.25",
+						},
+					},
+				},
+			},
+		}
+
+		s := &PatchSchema{}
+		if err := s.Build(from, to); err != nil {
+			t.Error(err)
+			return
+		}
+		qss := strings.Join(s.GenerateSQL(), "\n")
+		if qss != `ALTER TABLE public.items ALTER COLUMN id SET GENERATED BY DEFAULT SET INCREMENT BY 5
+ALTER TABLE public.items ALTER COLUMN num DROP DEFAULT
+ALTER TABLE public.items ALTER COLUMN num ADD GENERATED BY DEFAULT AS IDENTITY
+ALTER TABLE public.items ALTER COLUMN code DROP IDENTITY IF EXISTS
+ALTER TABLE public.items DROP COLUMN IF EXISTS price_vat
+ALTER TABLE public.items ADD COLUMN price_vat numeric NOT NULL GENERATED ALWAYS AS (price * 1.25) STORED` {
+			t.Error(qss)
+		}
+	})
+
+	t.Run("recreate dependents", func(t *testing.T) {
+		table := func(column, renamedFrom, generated string) *Table {
+			return &Table{
+				Name: "items",
+				Columns: []*Column{
+					{Name: "id", Type: "integer", PrimaryKey: true},
+					{Name: "price", Type: "numeric"},
+					{Name: column, Type: "numeric", Generated: generated, RenamedFrom: renamedFrom},
+				},
+				Indexes: []*Index{
+					{Name: "items_total", Columns: []string{column}},
+					{Name: "items_price", Columns: []string{"price"}},
+				},
+				Constraints: []*Constraint{
+					{Name: "items_total_key", Type: TypeUQ, Columns: []string{column}},
+				},
+			}
+		}
+		from := &Schema{
+			CurrentSchema: "public",
+			Tables:        []*Table{table("price_vat", "", "price * 1.2")},
+		}
+		to := &Schema{
+			CurrentSchema: "public",
+			Tables:        []*Table{table("total", "price_vat", "price * 1.25")},
+		}
+
+		s := &PatchSchema{}
+		if err := s.Build(from, to); err != nil {
+			t.Error(err)
+			return
+		}
+		plan := s.Plan()
+		qss := strings.Join(plan.SQL(), "\n")
+		if qss != `ALTER TABLE public.items RENAME COLUMN price_vat TO total
+ALTER TABLE public.items DROP COLUMN IF EXISTS total
+ALTER TABLE public.items ADD COLUMN total numeric NOT NULL GENERATED ALWAYS AS (price * 1.25) STORED
+DROP INDEX IF EXISTS public.items_total
+CREATE INDEX items_total ON public.items USING btree(total)
+ALTER TABLE public.items DROP CONSTRAINT IF EXISTS items_total_key
+ALTER TABLE public.items ADD CONSTRAINT items_total_key UNIQUE (total)` {
+			t.Error(qss)
+		}
+		safe := []string{}
+		for _, op := range plan.Operations {
+			if !op.Destructive {
+				safe = append(safe, op.SQL)
+			}
+		}
+		// without destructive operations the column is only renamed, its index and constraint are kept
+		if qss := strings.Join(safe, "\n"); qss != `ALTER TABLE public.items RENAME COLUMN price_vat TO total` {
+			t.Error(qss)
+		}
+
+		PatchDropDisable = true
+		defer func() { PatchDropDisable = false }()
+		s = &PatchSchema{}
+		if err := s.Build(from, to); err != nil {
+			t.Error(err)
+			return
+		}
+		if qss := strings.Join(s.GenerateSQL(), "\n"); qss != `ALTER TABLE public.items RENAME COLUMN price_vat TO total` {
+			t.Error(qss)
+		}
+		if ws := s.Warnings(); len(ws) != 1 || !strings.Contains(ws[0], "generated expression change") {
+			t.Error(ws)
+		}
+	})
+}
+
+func TestPatchSchema_BuildComments(t *testing.T) {
+	t.Run("create", func(t *testing.T) {
+		from := &Schema{
+			CurrentSchema: "public",
+		}
+		to := &Schema{
+			CurrentSchema: "public",
+			Tables: []*Table{
+				{
+					Name:    "table1",
+					Comment: "customer's orders",
+					Columns: []*Column{
+						{
+							Name:       "column1",
+							Type:       "uuid",
+							PrimaryKey: true,
+							Comment:    "order id",
+						},
+						{
+							Name: "column2",
+							Type: "uuid",
+						},
+					},
+					Indexes: []*Index{
+						{
+							Name:    "table1_col2",
+							Columns: []string{"column2"},
+							Comment: "lookup",
+						},
+					},
+					Constraints: []*Constraint{
+						{
+							Name:    "table1_check",
+							Check:   "true",
+							Comment: "always",
+						},
+					},
+				},
+			},
+		}
+
+		s := &PatchSchema{}
+		if err := s.Build(from, to); err != nil {
+			t.Error(err)
+			return
+		}
+		qss := strings.Join(s.GenerateSQL(), "\n")
+		if qss != `CREATE TABLE public.table1 (
+column1 uuid NOT NULL PRIMARY KEY,
+column2 uuid NOT NULL,
+CONSTRAINT table1_check CHECK (true))
+COMMENT ON TABLE public.table1 IS 'customer''s orders'
+COMMENT ON COLUMN public.table1.column1 IS 'order id'
+COMMENT ON CONSTRAINT table1_check ON public.table1 IS 'always'
+CREATE INDEX table1_col2 ON public.table1(column2)
+COMMENT ON INDEX public.table1_col2 IS 'lookup'` {
+			t.Error(qss)
+		}
+	})
+
+	t.Run("alter", func(t *testing.T) {
+		from := &Schema{
+			CurrentSchema: "public",
+			Tables: []*Table{
+				{
+					Name:    "table1",
+					Comment: "old",
+					Columns: []*Column{
+						{
+							Name:    "column1",
+							Type:    "uuid",
+							Comment: "old",
+						},
+					},
+				},
+			},
+		}
+		to := &Schema{
+			CurrentSchema: "public",
+			Tables: []*Table{
+				{
+					Name:    "table1",
+					Comment: "new",
+					Columns: []*Column{
+						{
+							Name: "column1",
+							Type: "uuid",
+						},
+					},
+				},
+			},
+		}
+
+		s := &PatchSchema{}
+		if err := s.Build(from, to); err != nil {
+			t.Error(err)
+			return
+		}
+		qss := strings.Join(s.GenerateSQL(), "\n")
+		if qss != `COMMENT ON TABLE public.table1 IS 'new'
+COMMENT ON COLUMN public.table1.column1 IS NULL` {
+			t.Error(qss)
+		}
+	})
+}
+
+func TestPatchSchema_BuildNamespaces(t *testing.T) {
+	customers := &Table{
+		Name: "customers",
+		Columns: []*Column{
+			{
+				Name:       "id",
+				Type:       "uuid",
+				PrimaryKey: true,
+			},
+		},
+	}
+	invoices := &Table{
+		Namespace: "billing",
+		Name:      "invoices",
+		Columns: []*Column{
+			{
+				Name:       "id",
+				Type:       "uuid",
+				PrimaryKey: true,
+			},
+			{
+				Name: "customer_id",
+				Type: "uuid",
+			},
+		},
+		Indexes: []*Index{
+			{
+				Name:    "invoices_customer_id",
+				Columns: []string{"customer_id"},
+			},
+		},
+	}
+	from := &Schema{
+		CurrentSchema: "public",
+		Tables: []*Table{
+			{
+				Name: "customers",
+				Columns: []*Column{
+					{
+						Name:       "id",
+						Type:       "uuid",
+						PrimaryKey: true,
+					},
+				},
+			},
+		},
+	}
+	to := &Schema{
+		CurrentSchema: "public",
+		Namespaces: []*Namespace{
+			{
+				Name:    "audit",
+				Comment: "audit log",
+			},
+		},
+		Tables: []*Table{customers, invoices},
+		Relations: []*Relation{
+			{
+				Name:          "invoices_customer_fk",
+				Table:         invoices,
+				Columns:       []*Column{invoices.Columns[1]},
+				ParentTable:   customers,
+				ParentColumns: []*Column{customers.Columns[0]},
+			},
+		},
+	}
+
+	s := &PatchSchema{}
+	if err := s.Build(from, to); err != nil {
+		t.Error(err)
+		return
+	}
+	qss := strings.Join(s.GenerateSQL(), "\n")
+	if qss != `CREATE SCHEMA IF NOT EXISTS audit
+COMMENT ON SCHEMA audit IS 'audit log'
+CREATE SCHEMA IF NOT EXISTS billing
+CREATE TABLE billing.invoices (
+id uuid NOT NULL PRIMARY KEY,
+customer_id uuid NOT NULL)
+CREATE INDEX invoices_customer_id ON billing.invoices(customer_id)
+ALTER TABLE billing.invoices ADD CONSTRAINT invoices_customer_fk FOREIGN KEY (customer_id) REFERENCES public.customers (id)` {
+		t.Error(qss)
+	}
+}
+
+func TestPatchSchema_BuildTargetNamespace(t *testing.T) {
+	newTo := func() *Schema {
+		users := &Table{
+			Name: "users",
+			Columns: []*Column{
+				{
+					Name:       "id",
+					Type:       "bigint",
+					PrimaryKey: true,
+					Default:    sql.NullString{String: "nextval('users_id_seq'::regclass)", Valid: true},
+				},
+				{
+					Name: "status",
+					Type: "status[]",
+				},
+			},
+		}
+		orders := &Table{
+			Name: "orders",
+			Columns: []*Column{
+				{
+					Name: "user_id",
+					Type: "bigint",
+				},
+			},
+			Indexes: []*Index{
+				{
+					Name:    "orders_user_id",
+					Columns: []string{"user_id"},
+				},
+			},
+		}
+		return &Schema{
+			Enums: []*Enum{
+				{
+					Name:   "status",
+					Values: []string{"new", "done"},
+				},
+			},
+			Sequences: []*Sequence{
+				{
+					Name:    "users_id_seq",
+					OwnedBy: "users.id",
+				},
+			},
+			Tables: []*Table{users, orders},
+			Relations: []*Relation{
+				{
+					Name:          "orders_user_fk",
+					Table:         orders,
+					Columns:       []*Column{orders.Columns[0]},
+					ParentTable:   users,
+					ParentColumns: []*Column{users.Columns[0]},
+				},
+			},
+		}
+	}
+	from := &Schema{
+		CurrentSchema: "public",
+		Tables: []*Table{
+			{
+				Namespace: "public",
+				Name:      "users",
+				Columns: []*Column{
+					{
+						Name: "id",
+						Type: "bigint",
+					},
+				},
+			},
+		},
+	}
+
+	s := &PatchSchema{TargetNamespace: "tenant_42"}
+	if err := s.Build(from, newTo()); err != nil {
+		t.Error(err)
+		return
+	}
+	qss := strings.Join(s.GenerateSQL(), "\n")
+	if qss != `CREATE SCHEMA IF NOT EXISTS tenant_42
+CREATE TYPE tenant_42.status AS ENUM ('new', 'done')
+CREATE SEQUENCE tenant_42.users_id_seq AS bigint INCREMENT BY 1 MINVALUE 1 MAXVALUE 9223372036854775807 START WITH 1 CACHE 1
+CREATE TABLE tenant_42.users (
+id bigint NOT NULL DEFAULT nextval('tenant_42.users_id_seq'::regclass) PRIMARY KEY,
+status tenant_42.status[] NOT NULL)
+CREATE TABLE tenant_42.orders (
+user_id bigint NOT NULL)
+CREATE INDEX orders_user_id ON tenant_42.orders(user_id)
+ALTER TABLE tenant_42.orders ADD CONSTRAINT orders_user_fk FOREIGN KEY (user_id) REFERENCES tenant_42.users (id)
+ALTER SEQUENCE tenant_42.users_id_seq OWNED BY tenant_42.users.id` {
+		t.Error(qss)
+	}
+
+	// the schema of the tenant is up to date, other namespaces are ignored
+	s = &PatchSchema{TargetNamespace: "tenant_42"}
+	if err := s.Build(newTo(), newTo()); err != nil {
+		t.Error(err)
+		return
+	}
+	from = newTo()
+	from.Retarget("tenant_42")
+	from.Tables = append(from.Tables, &Table{
+		Namespace: "public",
+		Name:      "other",
+		Columns: []*Column{
+			{
+				Name: "id",
+				Type: "bigint",
+			},
+		},
+	})
+	from.CurrentSchema = "public"
+	s = &PatchSchema{TargetNamespace: "tenant_42"}
+	if err := s.Build(from, newTo()); err != nil {
+		t.Error(err)
+		return
+	}
+	if qs := s.GenerateSQL(); len(qs) != 0 {
+		t.Error(strings.Join(qs, "\n"))
+	}
+}
+
+func TestPatchSchema_BuildTargetNamespaceReferences(t *testing.T) {
+	to := &Schema{
+		CurrentSchema: "public",
+		Functions: []*Function{
+			{
+				Namespace: "public",
+				Name:      "touch",
+				Returns:   "trigger",
+				Language:  "plpgsql",
+				Body:      "BEGIN RETURN NEW; END",
+			},
+		},
+		Tables: []*Table{
+			{
+				Namespace: "public",
+				Name:      "users",
+				Columns: []*Column{
+					{Name: "id", Type: "bigint"},
+				},
+				Triggers: []*Trigger{
+					{Name: "users_touch", Timing: "BEFORE", Events: []string{"UPDATE"}, ForEach: "ROW", Function: "public.touch()"},
+				},
+			},
+			{
+				Namespace: "public",
+				Name:      "user_ids",
+				Type:      TypeView,
+				Def:       "SELECT id FROM public.users WHERE id > 0",
+				DependsOn: []string{"public.users"},
+				Columns: []*Column{
+					{Name: "id", Type: "bigint"},
+				},
+			},
+		},
+	}
+	var want string
+	for i := 0; i < 2; i++ {
+		s := &PatchSchema{TargetNamespace: "tenant_42"}
+		if err := s.Build(&Schema{CurrentSchema: "public"}, to); err != nil {
+			t.Error(err)
+			return
+		}
+		qss := strings.Join(s.GenerateSQL(), "\n")
+		if !strings.Contains(qss, "FROM tenant_42.users WHERE") || !strings.Contains(qss, "EXECUTE FUNCTION tenant_42.touch()") {
+			t.Error(qss)
+		}
+		if i > 0 && qss != want {
+			t.Errorf("second build differs:\n%s", qss)
+		}
+		want = qss
+	}
+	// the target schema of caller is not retargeted
+	if to.Tables[0].Namespace != "public" || to.Tables[1].Def != "SELECT id FROM public.users WHERE id > 0" ||
+		to.Tables[0].Triggers[0].Function != "public.touch()" || to.CurrentSchema != "public" {
+		t.Error("target schema is changed")
+	}
+}
+
+func TestQuoteIdent(t *testing.T) {
+	for name, want := range map[string]string{
+		"users":      "users",
+		"_id2$":      "_id2$",
+		"password":   "password",
+		"order":      `"order"`,
+		"user":       `"user"`,
+		"UserName":   `"UserName"`,
+		"first name": `"first name"`,
+		"a.b":        `"a.b"`,
+		"2fa":        `"2fa"`,
+		`say "hi"`:   `"say ""hi"""`,
+	} {
+		if got := QuoteIdent(name); got != want {
+			t.Errorf("QuoteIdent(%q) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestPatchSchema_BuildQuoted(t *testing.T) {
+	users := &Table{
+		Namespace: "Sales",
+		Name:      "user",
+		Columns: []*Column{
+			{
+				Name:       "ID",
+				Type:       "uuid",
+				PrimaryKey: true,
+			},
+			{
+				Name:    "first name",
+				Type:    "text",
+				Comment: "given name",
+			},
+			{
+				Name: "order",
+				Type: "integer",
+			},
+		},
+		Indexes: []*Index{
+			{
+				Name:    "user.first name",
+				Columns: []string{"first name", "order"},
+			},
+		},
+		Constraints: []*Constraint{
+			{
+				Name:    "User_Order_UQ",
+				Type:    TypeUQ,
+				Columns: []string{"order"},
+			},
+		},
+	}
+	orders := &Table{
+		Namespace: "Sales",
+		Name:      "Orders",
+		Columns: []*Column{
+			{
+				Name: "user",
+				Type: "uuid",
+			},
+		},
+	}
+	to := &Schema{
+		CurrentSchema: "public",
+		Tables:        []*Table{users, orders},
+		Relations: []*Relation{
+			{
+				Name:          "Orders_User_FK",
+				Table:         orders,
+				Columns:       []*Column{orders.Columns[0]},
+				ParentTable:   users,
+				ParentColumns: []*Column{users.Columns[0]},
+			},
+		},
+	}
+
+	s := &PatchSchema{}
+	if err := s.Build(&Schema{CurrentSchema: "public"}, to); err != nil {
+		t.Error(err)
+		return
+	}
+	qss := strings.Join(s.GenerateSQL(), "\n")
+	if qss != `CREATE SCHEMA IF NOT EXISTS "Sales"
+CREATE TABLE "Sales"."user" (
+"ID" uuid NOT NULL PRIMARY KEY,
+"first name" text NOT NULL,
+"order" integer NOT NULL,
+CONSTRAINT "User_Order_UQ" UNIQUE ("order"))
+COMMENT ON COLUMN "Sales"."user"."first name" IS 'given name'
+CREATE INDEX "user.first name" ON "Sales"."user"("first name", "order")
+CREATE TABLE "Sales"."Orders" (
+"user" uuid NOT NULL)
+ALTER TABLE "Sales"."Orders" ADD CONSTRAINT "Orders_User_FK" FOREIGN KEY ("user") REFERENCES "Sales"."user" ("ID")` {
+		t.Error(qss)
+	}
+}
+
+func TestPatchSchema_Plan(t *testing.T) {
+	from := &Schema{
+		Enums: []*Enum{
+			{
+				Name:   "status",
+				Values: []string{"new"},
+			},
+		},
+		Tables: []*Table{
+			{
+				Name: "table1",
+				Columns: []*Column{
+					{
+						Name: "column1",
+						Type: "uuid",
+					},
+					{
+						Name: "column2",
+						Type: "text",
+					},
+				},
+				Indexes: []*Index{
+					{
+						Name:    "table1_col1",
+						Columns: []string{"column1"},
+					},
+				},
+			},
+		},
+	}
+	to := &Schema{
+		Enums: []*Enum{
+			{
+				Name:   "status",
+				Values: []string{"new", "done"},
+			},
+		},
+		Tables: []*Table{
+			{
+				Name: "table1",
+				Columns: []*Column{
+					{
+						Name: "column1",
+						Type: "uuid",
+					},
+				},
+				Indexes: []*Index{
+					{
+						Name:         "table1_col1",
+						Columns:      []string{"column1"},
+						IsUnique:     true,
+						Concurrently: true,
+					},
+				},
+			},
+		},
+	}
+
+	s := &PatchSchema{}
+	if err := s.Build(from, to); err != nil {
+		t.Error(err)
+		return
+	}
+	plan := s.Plan()
+	want := []Operation{
+		{Kind: OpAlter, Object: "TYPE", Target: "public.status", OutsideTx: true, Lock: LockAccessExclusive,
+			SQL: "ALTER TYPE public.status ADD VALUE 'done' AFTER 'new'"},
+		{Kind: OpDrop, Object: "COLUMN", Target: "public.table1.column2", Destructive: true, Lock: LockAccessExclusive,
+			SQL: "ALTER TABLE public.table1 DROP COLUMN IF EXISTS column2"},
+		{Kind: OpDrop, Object: "INDEX", Target: "public.table1_col1", Lock: LockAccessExclusive,
+			SQL: "DROP INDEX IF EXISTS public.table1_col1"},
+		{Kind: OpCreate, Object: "INDEX", Target: "public.table1_col1", OutsideTx: true, Lock: LockShareUpdateExclusive,
+			SQL: "CREATE UNIQUE INDEX CONCURRENTLY table1_col1 ON public.table1 USING btree(column1)"},
+	}
+	if len(plan.Operations) != len(want) {
+		t.Error(strings.Join(plan.SQL(), "\n"))
+		return
+	}
+	for i, op := range plan.Operations {
+		if *op != want[i] {
+			t.Errorf("operation %d: %+v, want %+v", i, *op, want[i])
+		}
+	}
+	if !plan.HasDestructive() {
+		t.Error("plan has no destructive operations")
+	}
+}
+
+func TestPatchSchema_BuildDependencyOrder(t *testing.T) {
+	newColumns := func() []*Column {
+		return []*Column{
+			{
+				Name:       "id",
+				Type:       "uuid",
+				PrimaryKey: true,
+			},
+			{
+				Name:     "ref_id",
+				Type:     "uuid",
+				Nullable: true,
+			},
+		}
+	}
+	view := &Table{
+		Name:    "v_orders",
+		Type:    "VIEW",
+		Columns: []*Column{{Name: "id", Type: "uuid"}},
+		Def:     "SELECT o.id FROM Orders AS o JOIN public.customers AS c ON c.id = o.ref_id",
+	}
+	orders := &Table{Name: "orders", Columns: newColumns()}
+	customers := &Table{Name: "customers", Columns: newColumns()}
+	to := &Schema{
+		Tables: []*Table{view, orders, customers},
+		Relations: []*Relation{
+			{
+				Name:          "orders_customer_fk",
+				Table:         orders,
+				Columns:       []*Column{orders.Columns[1]},
+				ParentTable:   customers,
+				ParentColumns: []*Column{customers.Columns[0]},
+			},
+			{
+				Name:          "customers_last_order_fk",
+				Table:         customers,
+				Columns:       []*Column{customers.Columns[1]},
+				ParentTable:   orders,
+				ParentColumns: []*Column{orders.Columns[0]},
+			},
+		},
+	}
+
+	s := &PatchSchema{}
+	if err := s.Build(&Schema{}, to); err != nil {
+		t.Error(err)
+		return
+	}
+	qss := strings.Join(s.GenerateSQL(), "\n")
+	if qss != `CREATE TABLE public.customers (
+id uuid NOT NULL PRIMARY KEY,
+ref_id uuid)
+CREATE TABLE public.orders (
+id uuid NOT NULL PRIMARY KEY,
+ref_id uuid)
+CREATE VIEW public.v_orders AS (
+SELECT o.id FROM Orders AS o JOIN public.customers AS c ON c.id = o.ref_id
+)
+ALTER TABLE public.orders ADD CONSTRAINT orders_customer_fk FOREIGN KEY (ref_id) REFERENCES public.customers (id)
+ALTER TABLE public.customers ADD CONSTRAINT customers_last_order_fk FOREIGN KEY (ref_id) REFERENCES public.orders (id)` {
+		t.Error(qss)
+	}
+
+	parent := &Table{Name: "parent", Columns: newColumns()}
+	child := &Table{Name: "child", Columns: newColumns()}
+	from := &Schema{
+		Tables: []*Table{
+			parent,
+			child,
+			{
+				Name:      "v_child",
+				Type:      "VIEW",
+				Columns:   []*Column{{Name: "id", Type: "uuid"}},
+				Def:       "SELECT id FROM child",
+				DependsOn: []string{"public.child"},
+			},
+		},
+		Relations: []*Relation{
+			{
+				Name:          "child_parent_fk",
+				Table:         child,
+				Columns:       []*Column{child.Columns[1]},
+				ParentTable:   parent,
+				ParentColumns: []*Column{parent.Columns[0]},
+			},
+		},
+	}
+	s = &PatchSchema{}
+	if err := s.Build(from, &Schema{}); err != nil {
+		t.Error(err)
+		return
+	}
+	qss = strings.Join(s.GenerateSQL(), "\n")
+	if qss != `ALTER TABLE public.child DROP CONSTRAINT IF EXISTS child_parent_fk
+DROP VIEW IF EXISTS public.v_child
+DROP TABLE IF EXISTS public.child
+DROP TABLE IF EXISTS public.parent` {
+		t.Error(qss)
+	}
+}
+
+func TestPatchSchema_BuildRelations(t *testing.T) {
+	newSchema := func(rels ...*Relation) *Schema {
+		parent := &Table{Name: "parent", Columns: []*Column{{Name: "id", Type: "uuid", PrimaryKey: true}}}
+		child := &Table{Name: "child", Columns: []*Column{
+			{Name: "id", Type: "uuid", PrimaryKey: true},
+			{Name: "parent_id", Type: "uuid", Nullable: true},
+			{Name: "owner_id", Type: "uuid", Nullable: true},
+		}}
+		for _, r := range rels {
+			r.Table = child
+			r.ParentTable = parent
+			r.ParentColumns = parent.Columns[:1]
+			if r.Columns[0].Name == "owner_id" {
+				r.Columns = child.Columns[2:]
+			} else {
+				r.Columns = child.Columns[1:2]
+			}
+		}
+		return &Schema{Tables: []*Table{parent, child}, Relations: rels}
+	}
+	from := newSchema(
+		&Relation{Name: "child_parent_id_fkey", Columns: []*Column{{Name: "parent_id"}}},
+		&Relation{Name: "child_owner_id_fkey", Columns: []*Column{{Name: "owner_id"}}},
+	)
+	to := newSchema(
+		&Relation{Name: "child_parent_fk", Columns: []*Column{{Name: "parent_id"}}, OnDelete: "set null"},
+	)
+
+	s := &PatchSchema{}
+	if err := s.Build(from, to); err != nil {
+		t.Error(err)
+		return
+	}
+	plan := s.Plan()
+	want := []Operation{
+		{Kind: OpDrop, Object: "CONSTRAINT", Target: "child_parent_id_fkey ON public.child", Lock: LockAccessExclusive,
+			SQL: "ALTER TABLE public.child DROP CONSTRAINT IF EXISTS child_parent_id_fkey"},
+		{Kind: OpDrop, Object: "CONSTRAINT", Target: "child_owner_id_fkey ON public.child", Destructive: true, Lock: LockAccessExclusive,
+			SQL: "ALTER TABLE public.child DROP CONSTRAINT IF EXISTS child_owner_id_fkey"},
+		{Kind: OpCreate, Object: "CONSTRAINT", Target: "child_parent_fk ON public.child", Lock: LockShareRowExclusive,
+			SQL: "ALTER TABLE public.child ADD CONSTRAINT child_parent_fk FOREIGN KEY (parent_id) REFERENCES public.parent (id) ON DELETE SET NULL"},
+	}
+	if len(plan.Operations) != len(want) {
+		t.Error(strings.Join(plan.SQL(), "\n"))
+		return
+	}
+	for i, op := range plan.Operations {
+		if *op != want[i] {
+			t.Errorf("operation %d: %+v, want %+v", i, *op, want[i])
+		}
+	}
+
+	// unchanged relation is not recreated
+	s = &PatchSchema{}
+	if err := s.Build(to, newSchema(
+		&Relation{Name: "child_parent_fk", Columns: []*Column{{Name: "parent_id"}}, OnDelete: "SET NULL"},
+	)); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := s.GenerateSQL(); len(qss) != 0 {
+		t.Error(strings.Join(qss, "\n"))
+	}
+
+	// removed relation is kept when drops are disabled, changed relation is recreated
+	PatchDropDisable = true
+	defer func() { PatchDropDisable = false }()
+	s = &PatchSchema{}
+	if err := s.Build(from, to); err != nil {
+		t.Error(err)
+		return
+	}
+	qss := strings.Join(s.GenerateSQL(), "\n")
+	if qss != `ALTER TABLE public.child DROP CONSTRAINT IF EXISTS child_parent_id_fkey
+ALTER TABLE public.child ADD CONSTRAINT child_parent_fk FOREIGN KEY (parent_id) REFERENCES public.parent (id) ON DELETE SET NULL` {
+		t.Error(qss)
+	}
+}
+
+func TestPatchSchema_BuildRelationOptions(t *testing.T) {
+	newSchema := func(r *Relation) *Schema {
+		parent := &Table{Name: "parent", Columns: []*Column{{Name: "id", Type: "uuid", PrimaryKey: true}}}
+		child := &Table{Name: "child", Columns: []*Column{
+			{Name: "id", Type: "uuid", PrimaryKey: true},
+			{Name: "parent_id", Type: "uuid", Nullable: true},
+		}}
+		r.Name = "child_parent_fk"
+		r.Table, r.Columns = child, child.Columns[1:]
+		r.ParentTable, r.ParentColumns = parent, parent.Columns
+		return &Schema{Tables: []*Table{parent, child}, Relations: []*Relation{r}}
+	}
+	tests := []struct {
+		name     string
+		from, to *Relation
+		want     string
+	}{
+		{
+			name: "create",
+			to:   &Relation{OnUpdate: "cascade", Match: "full", InitiallyDeferred: true, NotValid: true},
+			want: "ALTER TABLE public.child ADD CONSTRAINT child_parent_fk FOREIGN KEY (parent_id) REFERENCES public.parent (id) MATCH FULL ON UPDATE CASCADE DEFERRABLE INITIALLY DEFERRED NOT VALID",
+		},
+		{
+			name: "recreate on action change",
+			from: &Relation{OnUpdate: "CASCADE"},
+			to:   &Relation{OnUpdate: "RESTRICT", Deferrable: true},
+			want: `ALTER TABLE public.child DROP CONSTRAINT IF EXISTS child_parent_fk
+ALTER TABLE public.child ADD CONSTRAINT child_parent_fk FOREIGN KEY (parent_id) REFERENCES public.parent (id) ON UPDATE RESTRICT DEFERRABLE`,
+		},
+		{
+			name: "alter deferrable and validate",
+			from: &Relation{Match: "SIMPLE", InitiallyDeferred: true, NotValid: true},
+			to:   &Relation{Deferrable: true},
+			want: `ALTER TABLE public.child ALTER CONSTRAINT child_parent_fk DEFERRABLE INITIALLY IMMEDIATE
+ALTER TABLE public.child VALIDATE CONSTRAINT child_parent_fk`,
+		},
+		{
+			name: "not deferrable",
+			from: &Relation{Deferrable: true},
+			to:   &Relation{NotValid: true},
+			want: "ALTER TABLE public.child ALTER CONSTRAINT child_parent_fk NOT DEFERRABLE",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			from := newSchema(&Relation{})
+			from.Relations = nil
+			if tt.from != nil {
+				from = newSchema(tt.from)
+			}
+			s := &PatchSchema{}
+			if err := s.Build(from, newSchema(tt.to)); err != nil {
+				t.Error(err)
+				return
+			}
+			if qss := strings.Join(s.GenerateSQL(), "\n"); qss != tt.want {
+				t.Error(qss)
+			}
+		})
+	}
+}
+
+func TestPatchSchema_BuildConstraintRelationOptions(t *testing.T) {
+	// the foreign key declared as table constraint is equal to the same relation
+	parent := "parent"
+	opts := &Relation{OnDelete: "CASCADE", OnUpdate: "CASCADE", Match: "FULL", Deferrable: true, InitiallyDeferred: true, NotValid: true}
+	newTables := func() []*Table {
+		return []*Table{
+			{Name: "parent", Columns: []*Column{{Name: "id", Type: "uuid", PrimaryKey: true}}},
+			{Name: "child", Columns: []*Column{
+				{Name: "id", Type: "uuid", PrimaryKey: true},
+				{Name: "parent_id", Type: "uuid", Nullable: true},
+			}},
+		}
+	}
+	tables := newTables()
+	r := *opts
+	r.Name = "child_parent_fk"
+	r.Table, r.Columns = tables[1], tables[1].Columns[1:]
+	r.ParentTable, r.ParentColumns = tables[0], tables[0].Columns
+	from := &Schema{Tables: tables, Relations: []*Relation{&r}}
+
+	tables = newTables()
+	tables[1].Constraints = []*Constraint{{
+		Name:              "child_parent_fk",
+		Type:              TypeFK,
+		ReferenceTable:    &parent,
+		Columns:           []string{"parent_id"},
+		ReferenceColumns:  []string{"id"},
+		OnDelete:          opts.OnDelete,
+		OnUpdate:          opts.OnUpdate,
+		Match:             opts.Match,
+		Deferrable:        opts.Deferrable,
+		InitiallyDeferred: opts.InitiallyDeferred,
+		NotValid:          opts.NotValid,
+	}}
+	to := &Schema{Tables: tables}
+
+	s := &PatchSchema{}
+	if err := s.Build(from, to); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := s.GenerateSQL(); len(qss) > 0 {
+		t.Error(strings.Join(qss, "\n"))
+	}
+}
+
+func TestPatchSchema_BuildCircularConstraints(t *testing.T) {
+	newTable := func(name, ref string) *Table {
+		return &Table{
+			Name: name,
+			Columns: []*Column{
+				{Name: "id", Type: "uuid", PrimaryKey: true},
+				{Name: "ref_id", Type: "uuid", Nullable: true},
+			},
+			Constraints: []*Constraint{
+				{Name: name + "_ref_fk", Type: TypeFK, ReferenceTable: &ref, Columns: []string{"ref_id"}, ReferenceColumns: []string{"id"}},
+				{Name: name + "_ref_uq", Type: TypeUQ, Columns: []string{"ref_id"}},
+			},
+		}
+	}
+	s := &PatchSchema{}
+	if err := s.Build(&Schema{}, &Schema{Tables: []*Table{newTable("a", "b"), newTable("b", "public.a")}}); err != nil {
+		t.Error(err)
+		return
+	}
+	qss := strings.Join(s.GenerateSQL(), "\n")
+	if qss != `CREATE TABLE public.b (
+id uuid NOT NULL PRIMARY KEY,
+ref_id uuid,
+CONSTRAINT b_ref_uq UNIQUE (ref_id))
+CREATE TABLE public.a (
+id uuid NOT NULL PRIMARY KEY,
+ref_id uuid,
+CONSTRAINT a_ref_uq UNIQUE (ref_id))
+ALTER TABLE public.a ADD CONSTRAINT a_ref_fk FOREIGN KEY (ref_id) REFERENCES public.b (id)
+ALTER TABLE public.b ADD CONSTRAINT b_ref_fk FOREIGN KEY (ref_id) REFERENCES public.a (id)` {
+		t.Error(qss)
+	}
+
+	// foreign key to unknown table
+	s = &PatchSchema{}
+	if err := s.Build(&Schema{}, &Schema{Tables: []*Table{newTable("a", "c")}}); err == nil {
+		t.Error("error expected")
+	}
+}
+
+func TestPatchSchema_BuildPrimaryKey(t *testing.T) {
+	newSchema := func(tenantPK bool) *Schema {
+		orders := &Table{Name: "orders", Columns: []*Column{
+			{Name: "tenant_id", Type: "uuid", PrimaryKey: tenantPK},
+			{Name: "id", Type: "uuid", PrimaryKey: true},
+		}}
+		items := &Table{Name: "items", Columns: []*Column{
+			{Name: "id", Type: "uuid", PrimaryKey: true},
+			{Name: "order_id", Type: "uuid"},
+		}}
+		return &Schema{
+			Tables: []*Table{orders, items},
+			Relations: []*Relation{{
+				Name:          "items_order_fk",
+				Table:         items,
+				Columns:       items.Columns[1:],
+				ParentTable:   orders,
+				ParentColumns: orders.Columns[1:],
+			}},
+		}
+	}
+	from := newSchema(false)
+	// primary key of database has its own name
+	from.Tables[0].Constraints = []*Constraint{{Name: "orders_pk", Type: TypePK, Columns: []string{"id"}}}
+
+	s := &PatchSchema{}
+	if err := s.Build(from, newSchema(true)); err != nil {
+		t.Error(err)
+		return
+	}
+	plan := s.Plan()
+	want := `ALTER TABLE public.items DROP CONSTRAINT IF EXISTS items_order_fk
+ALTER TABLE public.orders DROP CONSTRAINT IF EXISTS orders_pk
+ALTER TABLE public.orders ADD CONSTRAINT orders_pkey PRIMARY KEY (tenant_id, id)
+ALTER TABLE public.items ADD CONSTRAINT items_order_fk FOREIGN KEY (order_id) REFERENCES public.orders (id)`
+	if qss := strings.Join(plan.SQL(), "\n"); qss != want {
+		t.Error(qss)
+	}
+	for _, op := range plan.Operations {
+		if !op.Destructive {
+			t.Errorf("operation is not destructive: %s", op.SQL)
+		}
+	}
+
+	// the same primary key declared by columns and by constraint is not changed
+	s = &PatchSchema{}
+	if err := s.Build(from, newSchema(false)); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := s.GenerateSQL(); len(qss) != 0 {
+		t.Error(strings.Join(qss, "\n"))
+	}
+
+	// composite primary key of created table
+	s = &PatchSchema{}
+	if err := s.Build(&Schema{}, &Schema{Tables: newSchema(true).Tables[:1]}); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := strings.Join(s.GenerateSQL(), "\n"); qss != `CREATE TABLE public.orders (
+tenant_id uuid NOT NULL,
+id uuid NOT NULL,
+CONSTRAINT orders_pkey PRIMARY KEY (tenant_id, id))` {
+		t.Error(qss)
+	}
+
+	// the order of key columns is significant, it is kept by sort and yaml
+	reordered := newSchema(true)
+	reordered.Tables[0].Constraints = []*Constraint{{Name: "orders_pkey", Type: TypePK, Columns: []string{"id", "tenant_id"}}}
+	reordered.Sort()
+	b, err := reordered.MarshalYAML()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	loaded := &Schema{}
+	if err := loaded.UnmarshalYAML(b); err != nil {
+		t.Error(err)
+		return
+	}
+	orders, err := loaded.FindTableByName("orders")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if pk := orders.PrimaryKey(); strings.Join(pk.Columns, ",") != "id,tenant_id" {
+		t.Error(pk.Columns)
+	}
+	s = &PatchSchema{}
+	if err := s.Build(newSchema(true), loaded); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := strings.Join(s.GenerateSQL(), "\n"); qss != `ALTER TABLE public.orders DROP CONSTRAINT IF EXISTS orders_pkey
+ALTER TABLE public.orders ADD CONSTRAINT orders_pkey PRIMARY KEY (id, tenant_id)` {
+		t.Error(qss)
+	}
+}
+
+func TestPatchSchema_BuildRenames(t *testing.T) {
+	newSchema := func(renamed bool) *Schema {
+		users := &Table{Name: "users", Columns: []*Column{
+			{Name: "id", Type: "uuid", PrimaryKey: true},
+			{Name: "name", Type: "text"},
+		},
+			Indexes:     []*Index{{Name: "users_name_idx", Columns: []string{"name"}}},
+			Constraints: []*Constraint{{Name: "users_name_key", Type: TypeUQ, Columns: []string{"name"}}},
+		}
+		orders := &Table{Name: "orders", Columns: []*Column{
+			{Name: "id", Type: "uuid", PrimaryKey: true},
+			{Name: "user_id", Type: "uuid"},
+		}}
+		rel := &Relation{
+			Name:          "orders_user_fk",
+			Table:         orders,
+			Columns:       orders.Columns[1:],
+			ParentTable:   users,
+			ParentColumns: users.Columns[:1],
+		}
+		if renamed {
+			users.RenamedFrom, users.Name = "users", "accounts"
+			users.Columns[1].RenamedFrom, users.Columns[1].Name = "name", "full_name"
+			users.Indexes[0].RenamedFrom, users.Indexes[0].Name = "users_name_idx", "accounts_full_name_idx"
+			users.Indexes[0].Columns = []string{"full_name"}
+			users.Constraints[0].RenamedFrom, users.Constraints[0].Name = "users_name_key", "accounts_full_name_key"
+			users.Constraints[0].Columns = []string{"full_name"}
+			orders.Columns[1].RenamedFrom, orders.Columns[1].Name = "user_id", "account_id"
+			rel.RenamedFrom, rel.Name = "orders_user_fk", "orders_account_fk"
+		}
+		return &Schema{
+			Tables:    []*Table{users, orders},
+			Relations: []*Relation{rel},
+		}
+	}
+
+	s := &PatchSchema{}
+	if err := s.Build(newSchema(false), newSchema(true)); err != nil {
+		t.Error(err)
+		return
+	}
+	plan := s.Plan()
+	want := `ALTER TABLE public.users RENAME TO accounts
+ALTER TABLE public.accounts RENAME COLUMN name TO full_name
+ALTER INDEX public.users_name_idx RENAME TO accounts_full_name_idx
+ALTER TABLE public.accounts RENAME CONSTRAINT users_name_key TO accounts_full_name_key
+ALTER TABLE public.orders RENAME COLUMN user_id TO account_id
+ALTER TABLE public.orders RENAME CONSTRAINT orders_user_fk TO orders_account_fk`
+	if qss := strings.Join(plan.SQL(), "\n"); qss != want {
+		t.Error(qss)
+	}
+	for _, op := range plan.Operations {
+		if op.Destructive {
+			t.Errorf("operation is destructive: %s", op.SQL)
+		}
+	}
+
+	// hints of applied renames are ignored
+	s = &PatchSchema{}
+	if err := s.Build(newSchema(true), newSchema(true)); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := s.GenerateSQL(); len(qss) != 0 {
+		t.Error(strings.Join(qss, "\n"))
+	}
+
+	// probable renames without hints are warnings
+	from, to := newSchema(false), newSchema(false)
+	to.Tables[0].Name = "accounts"
+	from.Tables[1].Columns = append(from.Tables[1].Columns, &Column{Name: "note", Type: "text"})
+	to.Tables[1].Columns = append(to.Tables[1].Columns, &Column{Name: "comment", Type: "text"})
+	s = &PatchSchema{DetectRenames: true}
+	if err := s.Build(from, to); err != nil {
+		t.Error(err)
+		return
+	}
+	wantWarnings := []string{
+		"table public.orders: column note is dropped and column comment of the same type is added, set renamedFrom: note if it is renamed",
+		"table public.users is dropped and table public.accounts with the same columns is created, set renamedFrom: users if it is renamed",
+	}
+	if ws := s.Warnings(); strings.Join(ws, "\n") != strings.Join(wantWarnings, "\n") {
+		t.Error(strings.Join(ws, "\n"))
+	}
+}
+
+func TestPatchSchema_BuildViews(t *testing.T) {
+	newSchema := func(def string, columns ...string) *Schema {
+		users := &Table{Name: "users", Columns: []*Column{
+			{Name: "id", Type: "uuid", PrimaryKey: true},
+			{Name: "name", Type: "text"},
+			{Name: "active", Type: "bool"},
+		}}
+		active := &Table{Name: "v_active", Type: TypeView, Def: def}
+		for _, name := range columns {
+			c, _ := users.FindColumnByName(name)
+			active.Columns = append(active.Columns, &Column{Name: c.Name, Type: c.Type})
+		}
+		names := &Table{
+			Name:    "v_names",
+			Type:    TypeView,
+			Columns: []*Column{{Name: "name", Type: "text"}},
+			Def:     "SELECT name FROM v_active",
+		}
+		stats := &Table{
+			Name:    "mv_stats",
+			Type:    TypeMaterialized,
+			Columns: []*Column{{Name: "active", Type: "bool"}, {Name: "cnt", Type: "int8"}},
+			Def:     "SELECT active, count(*) AS cnt FROM users GROUP BY active",
+			Indexes: []*Index{{Name: "mv_stats_active_idx", Columns: []string{"active"}}},
+		}
+		return &Schema{Tables: []*Table{users, active, names, stats}}
+	}
+	from := newSchema("SELECT id, name FROM users WHERE active", "id", "name")
+
+	// definitions are compared in canonical form
+	s := &PatchSchema{}
+	if err := s.Build(from, newSchema("select id,name\n  from users\n where active;", "id", "name")); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := s.GenerateSQL(); len(qss) != 0 {
+		t.Error(strings.Join(qss, "\n"))
+	}
+
+	// compatible view is replaced
+	s = &PatchSchema{}
+	if err := s.Build(from, newSchema("SELECT id, name, active FROM users", "id", "name", "active")); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := strings.Join(s.GenerateSQL(), "\n"); qss != `CREATE OR REPLACE VIEW public.v_active AS (
+SELECT id, name, active FROM users
+)` {
+		t.Error(qss)
+	}
+
+	// incompatible view is recreated with the dependent views
+	s = &PatchSchema{}
+	if err := s.Build(from, newSchema("SELECT name FROM users WHERE active", "name")); err != nil {
+		t.Error(err)
+		return
+	}
+	plan := s.Plan()
+	if qss := strings.Join(plan.SQL(), "\n"); qss != `DROP VIEW IF EXISTS public.v_names
+DROP VIEW IF EXISTS public.v_active
+CREATE VIEW public.v_active AS (
+SELECT name FROM users WHERE active
+)
+CREATE VIEW public.v_names AS (
+SELECT name FROM v_active
+)` {
+		t.Error(qss)
+	}
+	for _, op := range plan.Operations {
+		if op.Destructive {
+			t.Errorf("operation is destructive: %s", op.SQL)
+		}
+	}
+
+	// materialized view is recreated with indexes and refreshed at the end
+	to := newSchema("SELECT id, name FROM users WHERE active", "id", "name")
+	to.Tables[3].Def = "SELECT active, count(id) AS cnt FROM users GROUP BY active"
+	s = &PatchSchema{MatViewNoData: true}
+	if err := s.Build(from, to); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := strings.Join(s.GenerateSQL(), "\n"); qss != `DROP MATERIALIZED VIEW IF EXISTS public.mv_stats
+CREATE MATERIALIZED VIEW public.mv_stats AS (
+SELECT active, count(id) AS cnt FROM users GROUP BY active
+) WITH NO DATA
+CREATE INDEX mv_stats_active_idx ON public.mv_stats(active)
+REFRESH MATERIALIZED VIEW public.mv_stats` {
+		t.Error(qss)
+	}
+}
+
+func TestPatchSchema_BuildViewDependencies(t *testing.T) {
+	newSchema := func(nameType string) *Schema {
+		users := &Table{Name: "users", Columns: []*Column{
+			{Name: "id", Type: "uuid", PrimaryKey: true},
+			{Name: "name", Type: nameType},
+		}}
+		return &Schema{Tables: []*Table{
+			users,
+			{
+				Name:             "v_users",
+				Type:             TypeView,
+				Columns:          []*Column{{Name: "id", Type: "uuid"}, {Name: "name", Type: nameType}},
+				Def:              "SELECT id, name FROM users",
+				DependsOn:        []string{"public.users"},
+				DependsOnColumns: map[string][]string{"public.users": {"id", "name"}},
+			},
+			{
+				Name:             "v_ids",
+				Type:             TypeView,
+				Columns:          []*Column{{Name: "id", Type: "uuid"}},
+				Def:              "SELECT id FROM users",
+				DependsOn:        []string{"public.users"},
+				DependsOnColumns: map[string][]string{"public.users": {"id"}},
+			},
+			{
+				Name:      "v_names",
+				Type:      TypeView,
+				Columns:   []*Column{{Name: "name", Type: nameType}},
+				Def:       "SELECT name FROM v_users",
+				DependsOn: []string{"public.v_users"},
+			},
+		}}
+	}
+
+	s := &PatchSchema{}
+	if err := s.Build(newSchema("text"), newSchema("varchar(100)")); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := strings.Join(s.GenerateSQL(), "\n"); qss != `DROP VIEW IF EXISTS public.v_names
+DROP VIEW IF EXISTS public.v_users
+ALTER TABLE public.users ALTER COLUMN name TYPE varchar(100)
+CREATE VIEW public.v_users AS (
+SELECT id, name FROM users
+)
+CREATE VIEW public.v_names AS (
+SELECT name FROM v_users
+)` {
+		t.Error(qss)
+	}
+}
+
+func TestPatchSchema_BuildFunctionsAndTriggers(t *testing.T) {
+	newSchema := func(body string, events ...string) *Schema {
+		s := &Schema{Tables: []*Table{{Name: "users", Columns: []*Column{
+			{Name: "id", Type: "uuid", PrimaryKey: true},
+			{Name: "updated_at", Type: "timestamptz"},
+		}}}}
+		if body != "" {
+			s.Functions = []*Function{{Name: "set_updated_at", Returns: "trigger", Language: "plpgsql", Body: body}}
+		}
+		if len(events) > 0 {
+			s.Tables[0].Triggers = []*Trigger{{
+				Name:     "users_updated_at",
+				Timing:   "BEFORE",
+				Events:   events,
+				ForEach:  "ROW",
+				Function: "set_updated_at()",
+			}}
+		}
+		return s
+	}
+	body := "BEGIN NEW.updated_at = now(); RETURN NEW; END;"
+
+	// functions are created before the tables and triggers
+	s := &PatchSchema{}
+	to := newSchema(body, "UPDATE")
+	if err := s.Build(&Schema{}, to); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := strings.Join(s.GenerateSQL(), "\n"); qss != `CREATE OR REPLACE FUNCTION public.set_updated_at() RETURNS trigger LANGUAGE plpgsql AS $function$BEGIN NEW.updated_at = now(); RETURN NEW; END;$function$
+CREATE TABLE public.users (
+id uuid NOT NULL PRIMARY KEY,
+updated_at timestamptz NOT NULL)
+CREATE TRIGGER users_updated_at BEFORE UPDATE ON public.users FOR EACH ROW EXECUTE FUNCTION set_updated_at()` {
+		t.Error(qss)
+	}
+
+	// qualified function of trigger is not changed
+	from := newSchema(body, "UPDATE")
+	from.Tables[0].Triggers[0].Function = "public.set_updated_at()"
+	s = &PatchSchema{}
+	if err := s.Build(from, newSchema(body, "UPDATE")); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := s.GenerateSQL(); len(qss) != 0 {
+		t.Error(strings.Join(qss, "\n"))
+	}
+
+	// function is replaced, trigger is recreated
+	s = &PatchSchema{}
+	if err := s.Build(newSchema(body, "UPDATE"), newSchema("BEGIN RETURN NEW; END;", "INSERT", "UPDATE")); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := strings.Join(s.GenerateSQL(), "\n"); qss != `CREATE OR REPLACE FUNCTION public.set_updated_at() RETURNS trigger LANGUAGE plpgsql AS $function$BEGIN RETURN NEW; END;$function$
+DROP TRIGGER IF EXISTS users_updated_at ON public.users
+CREATE TRIGGER users_updated_at BEFORE INSERT OR UPDATE ON public.users FOR EACH ROW EXECUTE FUNCTION set_updated_at()` {
+		t.Error(qss)
+	}
+
+	// function is dropped after the trigger
+	s = &PatchSchema{}
+	if err := s.Build(newSchema(body, "UPDATE"), newSchema("")); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := strings.Join(s.GenerateSQL(), "\n"); qss != `DROP TRIGGER IF EXISTS users_updated_at ON public.users
+DROP FUNCTION IF EXISTS public.set_updated_at()` {
+		t.Error(qss)
+	}
+
+	// function with changed result type is recreated between the drop and the creation of trigger
+	from = newSchema(body, "UPDATE")
+	from.Functions[0].Returns = "opaque"
+	s = &PatchSchema{}
+	if err := s.Build(from, newSchema(body, "UPDATE")); err != nil {
+		t.Error(err)
+		return
+	}
+	plan := s.Plan()
+	if qss := strings.Join(plan.SQL(), "\n"); qss != `DROP TRIGGER IF EXISTS users_updated_at ON public.users
+DROP FUNCTION IF EXISTS public.set_updated_at()
+CREATE OR REPLACE FUNCTION public.set_updated_at() RETURNS trigger LANGUAGE plpgsql AS $function$BEGIN NEW.updated_at = now(); RETURN NEW; END;$function$
+CREATE TRIGGER users_updated_at BEFORE UPDATE ON public.users FOR EACH ROW EXECUTE FUNCTION set_updated_at()` {
+		t.Error(qss)
+	}
+	if !plan.Operations[1].Destructive {
+		t.Error("function drop is not destructive")
+	}
+
+	PatchDropDisable = true
+	defer func() { PatchDropDisable = false }()
+	s = &PatchSchema{}
+	if err := s.Build(from, newSchema(body, "UPDATE")); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := s.GenerateSQL(); len(qss) != 0 {
+		t.Error(strings.Join(qss, "\n"))
+	}
+	if ws := s.Warnings(); len(ws) != 1 || !strings.Contains(ws[0], "result type change") {
+		t.Error(ws)
+	}
+}
+
+func TestPatchSchema_BuildTriggerClauses(t *testing.T) {
+	newSchema := func(triggers ...*Trigger) *Schema {
+		return &Schema{Tables: []*Table{
+			{Name: "accounts", Columns: []*Column{{Name: "id", Type: "uuid", PrimaryKey: true}}},
+			{Name: "orders", Columns: []*Column{{Name: "id", Type: "uuid", PrimaryKey: true}}, Triggers: triggers},
+		}}
+	}
+	triggers := func() []*Trigger {
+		return []*Trigger{
+			{
+				Name: "check_balance", Constraint: true, Timing: "AFTER", Events: []string{"INSERT"},
+				From: "public.accounts", Deferrable: true, InitiallyDeferred: true, ForEach: "ROW", Function: "check_balance()",
+			},
+			{
+				Name: "orders_audit", Timing: "AFTER", Events: []string{"UPDATE"},
+				OldTable: "old_rows", NewTable: "new rows", ForEach: "STATEMENT", Function: "log_changes()",
+			},
+			{
+				Name: "odd", Def: "CREATE TRIGGER odd AFTER INSERT ON public.orders FOR EACH ROW EXECUTE FUNCTION odd()",
+			},
+		}
+	}
+
+	s := &PatchSchema{}
+	if err := s.Build(&Schema{Tables: newSchema().Tables[:1]}, newSchema(triggers()...)); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := strings.Join(s.GenerateSQL(), "\n"); qss != `CREATE TABLE public.orders (
+id uuid NOT NULL PRIMARY KEY)
+CREATE CONSTRAINT TRIGGER check_balance AFTER INSERT ON public.orders FROM public.accounts DEFERRABLE INITIALLY DEFERRED FOR EACH ROW EXECUTE FUNCTION check_balance()
+CREATE TRIGGER orders_audit AFTER UPDATE ON public.orders REFERENCING OLD TABLE AS old_rows NEW TABLE AS "new rows" FOR EACH STATEMENT EXECUTE FUNCTION log_changes()
+CREATE TRIGGER odd AFTER INSERT ON public.orders FOR EACH ROW EXECUTE FUNCTION odd()` {
+		t.Error(qss)
+	}
+
+	// the referenced table of the table namespace may be qualified or not
+	to := newSchema(triggers()...)
+	to.Tables[1].Triggers[0].From = "accounts"
+	s = &PatchSchema{}
+	if err := s.Build(newSchema(triggers()...), to); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := s.GenerateSQL(); len(qss) > 0 {
+		t.Error(strings.Join(qss, "\n"))
+	}
+
+	// changed clause recreates trigger
+	to = newSchema(triggers()...)
+	to.Tables[1].Triggers[0].InitiallyDeferred = false
+	s = &PatchSchema{}
+	if err := s.Build(newSchema(triggers()...), to); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := strings.Join(s.GenerateSQL(), "\n"); qss != `DROP TRIGGER IF EXISTS check_balance ON public.orders
+CREATE CONSTRAINT TRIGGER check_balance AFTER INSERT ON public.orders FROM public.accounts DEFERRABLE FOR EACH ROW EXECUTE FUNCTION check_balance()` {
+		t.Error(qss)
+	}
+}
+
+func TestPatchSchema_BuildFunctionOptions(t *testing.T) {
+	newSchema := func(f *Function) *Schema {
+		f.Name, f.Arguments, f.Returns, f.Language, f.Body = "add", "a integer, b integer", "integer", "sql", "SELECT a + b"
+		return &Schema{Functions: []*Function{f}}
+	}
+	// options are compared in canonical form
+	s := &PatchSchema{}
+	if err := s.Build(
+		newSchema(&Function{Strict: true, Parallel: "SAFE", Cost: 100, Config: []string{"search_path=public, pg_temp"}}),
+		newSchema(&Function{Strict: true, Parallel: "safe", Config: []string{"search_path = public, pg_temp"}}),
+	); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := s.GenerateSQL(); len(qss) != 0 {
+		t.Error(strings.Join(qss, "\n"))
+	}
+
+	s = &PatchSchema{}
+	if err := s.Build(
+		newSchema(&Function{Strict: true}),
+		newSchema(&Function{Strict: true, Leakproof: true, Parallel: "SAFE", Cost: 10, Config: []string{"search_path TO public"}}),
+	); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := strings.Join(s.GenerateSQL(), "\n"); qss != `CREATE OR REPLACE FUNCTION public.add(a integer, b integer) RETURNS integer LANGUAGE sql`+
+		` STRICT LEAKPROOF PARALLEL SAFE COST 10 SET search_path = public AS $function$SELECT a + b$function$` {
+		t.Error(qss)
+	}
+}
+
+func TestPatchSchema_BuildExtensions(t *testing.T) {
+	from := &Schema{Extensions: []*Extension{
+		{Name: "pg_trgm", Version: "1.5", Namespace: "public"},
+		{Name: "btree_gist", Version: "1.6", Namespace: "public"},
+	}}
+	to := &Schema{
+		Extensions: []*Extension{
+			{Name: "pg_trgm", Version: "1.6"},
+			{Name: "citext", Namespace: "ext"},
+		},
+		Tables: []*Table{{Name: "users", Columns: []*Column{{Name: "email", Type: "ext.citext"}}}},
+	}
+	s := &PatchSchema{}
+	if err := s.Build(from, to); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := strings.Join(s.GenerateSQL(), "\n"); qss != `CREATE SCHEMA IF NOT EXISTS ext
+ALTER EXTENSION pg_trgm UPDATE TO '1.6'
+CREATE EXTENSION IF NOT EXISTS citext SCHEMA ext
+CREATE TABLE public.users (
+email ext.citext NOT NULL)
+DROP EXTENSION IF EXISTS btree_gist` {
+		t.Error(qss)
+	}
+}
+
+func TestPatchSchema_BuildDomainsAndComposites(t *testing.T) {
+	from := &Schema{
+		Domains: []*Domain{
+			{Name: "email", BaseType: "text", Checks: []*DomainCheck{
+				{Name: "email_at", Expr: "VALUE ~ '@'"},
+				{Name: "email_len", Expr: "length(VALUE) < 100"},
+			}},
+			{Name: "old_code", BaseType: "text"},
+		},
+		Composites: []*CompositeType{
+			{Name: "address", Attributes: []*Attribute{
+				{Name: "city", Type: "varchar(50)"},
+				{Name: "zip", Type: "text"},
+			}},
+		},
+	}
+	to := &Schema{
+		Domains: []*Domain{
+			{Name: "email", BaseType: "text", NotNull: true, Checks: []*DomainCheck{
+				{Name: "email_at", Expr: "VALUE ~ '@'"},
+				{Name: "email_len", Expr: "length(VALUE) < 200"},
+			}},
+			{Name: "positive", BaseType: "integer", Default: sql.NullString{String: "1", Valid: true},
+				Checks: []*DomainCheck{{Name: "positive_check", Expr: "VALUE > 0"}}},
+		},
+		Composites: []*CompositeType{
+			{Name: "address", Attributes: []*Attribute{
+				{Name: "city", Type: "varchar(100)"},
+				{Name: "street", Type: "text"},
+			}},
+			{Name: "money_amount", Attributes: []*Attribute{
+				{Name: "amount", Type: "numeric"},
+				{Name: "currency", Type: "char(3)"},
+			}},
+		},
+	}
+	s := &PatchSchema{}
+	if err := s.Build(from, to); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := strings.Join(s.GenerateSQL(), "\n"); qss != `ALTER DOMAIN public.email SET NOT NULL
+ALTER DOMAIN public.email DROP CONSTRAINT IF EXISTS email_len
+ALTER DOMAIN public.email ADD CONSTRAINT email_len CHECK (length(VALUE) < 200)
+CREATE DOMAIN public.positive AS integer DEFAULT 1 CONSTRAINT positive_check CHECK (VALUE > 0)
+ALTER TYPE public.address DROP ATTRIBUTE IF EXISTS zip
+ALTER TYPE public.address ALTER ATTRIBUTE city TYPE varchar(100)
+ALTER TYPE public.address ADD ATTRIBUTE street text
+CREATE TYPE public.money_amount AS (amount numeric, currency char(3))
+DROP DOMAIN IF EXISTS public.old_code` {
+		t.Error(qss)
+	}
+}
+
+func TestPatchSchema_BuildPartitions(t *testing.T) {
+	columns := func() []*Column {
+		return []*Column{{Name: "id", Type: "bigint"}, {Name: "created_at", Type: "date"}}
+	}
+	from := &Schema{Tables: []*Table{
+		{Name: "events", Columns: columns(), PartitionBy: "RANGE (created_at)", Partitions: []*Partition{
+			{Name: "events_2023", Bound: "FOR VALUES FROM ('2023-01-01') TO ('2024-01-01')"},
+			{Name: "events_2024_01", Bound: "FOR VALUES FROM ('2024-01-01 00:00:00+00') TO ('2024-02-01 00:00:00+00')"},
+			{Name: "events_old", Bound: "FOR VALUES FROM ('2020-01-01') TO ('2023-01-01')"},
+			{Name: "events_default", Bound: "DEFAULT"},
+		}},
+		{Name: "events_2024_02", Columns: columns()},
+		{Name: "logs", Columns: columns(), PartitionBy: "LIST (id)"},
+	}}
+	to := &Schema{Tables: []*Table{
+		{Name: "events", Columns: columns(), PartitionBy: "RANGE (created_at)", Partitions: []*Partition{
+			{Name: "events_2023", Bound: "FOR VALUES FROM ('2023-01-01') TO ('2023-07-01')"},
+			{Name: "events_2024_01", Bound: "FOR VALUES FROM ('2024-01-01') TO ('2024-02-01')"},
+			{Name: "events_2024_02", Bound: "FOR VALUES FROM ('2024-02-01') TO ('2024-03-01')"},
+			{Name: "events_2024_03", Bound: "FOR VALUES FROM ('2024-03-01') TO ('2024-04-01')"},
+		}},
+		{Name: "events_default", Columns: columns()},
+		{Name: "logs", Columns: columns(), PartitionBy: "HASH (id)"},
+		{Name: "metrics", Columns: columns(), PartitionBy: "LIST (id)", Partitions: []*Partition{
+			{Name: "metrics_1", Bound: "FOR VALUES IN (1)"},
+		}},
+	}}
+	s := &PatchSchema{}
+	if err := s.Build(from, to); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := strings.Join(s.GenerateSQL(), "\n"); qss != `ALTER TABLE public.events DETACH PARTITION public.events_2023
+ALTER TABLE public.events ATTACH PARTITION public.events_2023 FOR VALUES FROM ('2023-01-01') TO ('2023-07-01')
+DROP TABLE IF EXISTS public.events_old
+ALTER TABLE public.events DETACH PARTITION public.events_default
+ALTER TABLE public.events ATTACH PARTITION public.events_2024_02 FOR VALUES FROM ('2024-02-01') TO ('2024-03-01')
+CREATE TABLE public.events_2024_03 PARTITION OF public.events FOR VALUES FROM ('2024-03-01') TO ('2024-04-01')
+CREATE TABLE public.metrics (
+id bigint NOT NULL,
+created_at date NOT NULL) PARTITION BY LIST (id)
+CREATE TABLE public.metrics_1 PARTITION OF public.metrics FOR VALUES IN (1)` {
+		t.Error(qss)
+	}
+	if ws := s.Warnings(); len(ws) != 1 || !strings.Contains(ws[0], "partition key change") {
+		t.Error(ws)
+	}
+}
+
+func TestPatchSchema_BuildPolicies(t *testing.T) {
+	columns := func() []*Column {
+		return []*Column{{Name: "id", Type: "bigint"}, {Name: "tenant_id", Type: "integer"}}
+	}
+	from := &Schema{Tables: []*Table{
+		{Name: "orders", Columns: columns(), RowSecurity: true, Policies: []*Policy{
+			{Name: "tenant_isolation", Command: "ALL", Using: "(tenant_id = (current_setting('app.tenant'::text))::integer)"},
+			{Name: "readers", Command: "SELECT", Roles: []string{"reader"}, Using: "true"},
+			{Name: "admins", Command: "ALL", Roles: []string{"admin"}, Using: "true"},
+			{Name: "old", Command: "DELETE", Using: "false"},
+		}},
+	}}
+	to := &Schema{Tables: []*Table{
+		{Name: "orders", Columns: columns(), RowSecurity: true, ForceRowSecurity: true, Policies: []*Policy{
+			{Name: "tenant_isolation", Using: "tenant_id = current_setting('app.tenant')::integer"},
+			{Name: "readers", Command: "SELECT", Roles: []string{"reader", "auditor"}, Using: "true"},
+			{Name: "admins", Command: "ALL", Roles: []string{"admin"}, Using: "true", Restrictive: true},
+		}},
+		{Name: "invoices", Columns: columns(), RowSecurity: true, Policies: []*Policy{
+			{Name: "tenant_insert", Command: "INSERT", Roles: []string{"app"}, WithCheck: "tenant_id = 1"},
+		}},
+	}}
+	s := &PatchSchema{}
+	if err := s.Build(from, to); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := strings.Join(s.GenerateSQL(), "\n"); qss != `ALTER POLICY readers ON public.orders TO reader, auditor
+DROP POLICY IF EXISTS admins ON public.orders
+CREATE POLICY admins ON public.orders AS RESTRICTIVE TO admin USING (true)
+DROP POLICY IF EXISTS old ON public.orders
+ALTER TABLE public.orders FORCE ROW LEVEL SECURITY
+CREATE TABLE public.invoices (
+id bigint NOT NULL,
+tenant_id integer NOT NULL)
+CREATE POLICY tenant_insert ON public.invoices FOR INSERT TO app WITH CHECK (tenant_id = 1)
+ALTER TABLE public.invoices ENABLE ROW LEVEL SECURITY` {
+		t.Error(qss)
+	}
+
+	// the table without declared row security disables it and drops its policies,
+	// these operations are destructive and are skipped when drops are disabled
+	bare := &Schema{Tables: []*Table{{Name: "orders", Columns: columns()}}}
+	s = &PatchSchema{}
+	if err := s.Build(from, bare); err != nil {
+		t.Error(err)
+		return
+	}
+	plan := s.Plan()
+	for _, op := range plan.Operations {
+		if !op.Destructive {
+			t.Error("not destructive:", op.SQL)
+		}
+	}
+	if len(plan.Operations) != 5 {
+		t.Error(strings.Join(plan.SQL(), "\n"))
+	}
+	PatchDropDisable = true
+	defer func() { PatchDropDisable = false }()
+	s = &PatchSchema{}
+	if err := s.Build(from, bare); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := s.GenerateSQL(); len(qss) > 0 {
+		t.Error(strings.Join(qss, "\n"))
+	}
+}
+
+func TestPatchSchema_BuildGrants(t *testing.T) {
+	from := &Schema{
+		Roles:      []string{"admin", "app", "postgres", "reporting"},
+		Namespaces: []*Namespace{{Name: "public", Owner: "postgres"}},
+		Sequences:  []*Sequence{{Name: "order_no", Owner: "postgres"}},
+		Tables: []*Table{
+			{Name: "users", Owner: "postgres", Columns: []*Column{{Name: "id", Type: "bigint"}, {Name: "email", Type: "text"}},
+				Grants: []*Grant{
+					{Role: "app", Privileges: []string{"SELECT", "INSERT", "UPDATE (email, id)"}},
+					{Role: "reporting", Privileges: []string{"SELECT"}},
+				}},
+		},
+	}
+	to := &Schema{
+		Namespaces: []*Namespace{{Name: "public", Grants: []*Grant{{Role: "reporting", Privileges: []string{"USAGE"}}}}},
+		Sequences: []*Sequence{{Name: "order_no", Owner: "app", Grants: []*Grant{
+			{Role: "app", Privileges: []string{"ALL"}},
+		}}},
+		Tables: []*Table{
+			{Name: "users", Owner: "app", Columns: []*Column{{Name: "id", Type: "bigint"}, {Name: "email", Type: "text"}},
+				Grants: []*Grant{
+					{Role: "app", Privileges: []string{"select", "INSERT", "UPDATE (email)"}},
+					{Role: "PUBLIC", Privileges: []string{"REFERENCES (id)"}},
+				}},
+			{Name: "orders", Columns: []*Column{{Name: "id", Type: "bigint"}}},
+		},
+	}
+	s := &PatchSchema{}
+	if err := s.Build(from, to); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := strings.Join(s.GenerateSQL(), "\n"); qss != `GRANT USAGE ON SCHEMA public TO reporting
+ALTER SEQUENCE public.order_no OWNER TO app
+GRANT SELECT, UPDATE, USAGE ON SEQUENCE public.order_no TO app
+ALTER TABLE public.users OWNER TO app
+REVOKE UPDATE (id) ON TABLE public.users FROM app
+GRANT REFERENCES (id) ON TABLE public.users TO PUBLIC
+REVOKE SELECT ON TABLE public.users FROM reporting
+CREATE TABLE public.orders (
+id bigint NOT NULL)` {
+		t.Error(qss)
+	}
+
+	to.Tables[1].Owner = "ops"
+	to.Tables[1].Grants = []*Grant{{Role: "auditor", Privileges: []string{"SELECT"}}}
+	if err := (&PatchSchema{}).Build(from, to); err == nil || !strings.Contains(err.Error(), "auditor, ops") {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,199 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+type PatchDomain struct {
+	from, to *Domain
+}
+
+func (d *PatchDomain) Operations() []*Operation {
+	if d.from != nil && d.to != nil {
+		return d.alter()
+	}
+	if d.from == nil {
+		return d.create()
+	}
+	return d.drop()
+}
+
+func (d *PatchDomain) create() []*Operation {
+	name := quotedName(d.to.Namespace, d.to.Name)
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "CREATE DOMAIN %s AS %s", name, d.to.BaseType)
+	if d.to.Default.Valid {
+		fmt.Fprint(sb, " DEFAULT ", d.to.Default.String)
+	}
+	if d.to.NotNull {
+		fmt.Fprint(sb, " NOT NULL")
+	}
+	for _, c := range d.to.Checks {
+		fmt.Fprintf(sb, " CONSTRAINT %s CHECK (%s)", QuoteIdent(c.Name), c.Expr)
+	}
+	ret := []*Operation{{
+		Kind:   OpCreate,
+		Object: "DOMAIN",
+		Target: name,
+		SQL:    sb.String(),
+	}}
+	if d.to.Comment != "" {
+		ret = append(ret, commentOp("DOMAIN", name, d.to.Comment))
+	}
+	return ret
+}
+
+// alter changes the default, not null and check constraints of domain,
+// the changed check is dropped and added again, existing values are checked by added constraints.
+// The base type of domain can not be changed.
+func (d *PatchDomain) alter() []*Operation {
+	name := quotedName(d.to.Namespace, d.to.Name)
+	ret := []*Operation{}
+	alter := func(action string) {
+		ret = append(ret, &Operation{
+			Kind:   OpAlter,
+			Object: "DOMAIN",
+			Target: name,
+			SQL:    fmt.Sprintf("ALTER DOMAIN %s %s", name, action),
+			Lock:   LockShare,
+		})
+	}
+	if d.from.Default.Valid != d.to.Default.Valid ||
+		NormalizeExpr(d.from.Default.String) != NormalizeExpr(d.to.Default.String) {
+		if d.to.Default.Valid {
+			alter("SET DEFAULT " + d.to.Default.String)
+		} else {
+			alter("DROP DEFAULT")
+		}
+	}
+	if d.from.NotNull != d.to.NotNull {
+		if d.to.NotNull {
+			alter("SET NOT NULL")
+		} else {
+			alter("DROP NOT NULL")
+		}
+	}
+	for _, c := range d.from.Checks {
+		rc, err := d.to.FindCheckByName(c.Name)
+		if err != nil || NormalizeExpr(rc.Expr) != NormalizeExpr(c.Expr) {
+			alter("DROP CONSTRAINT IF EXISTS " + QuoteIdent(c.Name))
+		}
+	}
+	for _, c := range d.to.Checks {
+		fc, err := d.from.FindCheckByName(c.Name)
+		if err != nil || NormalizeExpr(fc.Expr) != NormalizeExpr(c.Expr) {
+			alter(fmt.Sprintf("ADD CONSTRAINT %s CHECK (%s)", QuoteIdent(c.Name), c.Expr))
+		}
+	}
+	if d.from.Comment != d.to.Comment {
+		ret = append(ret, commentOp("DOMAIN", name, d.to.Comment))
+	}
+	return ret
+}
+
+func (d *PatchDomain) drop() []*Operation {
+	if PatchDropDisable {
+		return nil
+	}
+	name := quotedName(d.from.Namespace, d.from.Name)
+	return []*Operation{{
+		Kind:        OpDrop,
+		Object:      "DOMAIN",
+		Target:      name,
+		SQL:         fmt.Sprintf("DROP DOMAIN IF EXISTS %s", name),
+		Destructive: true,
+		Lock:        LockAccessExclusive,
+	}}
+}
+
+// baseTypeWarning describes the base type change that can not be migrated
+func (d *PatchDomain) baseTypeWarning() string {
+	if d.from == nil || d.to == nil || NormalizeType(d.from.BaseType) == NormalizeType(d.to.BaseType) {
+		return ""
+	}
+	return fmt.Sprintf("domain %s: base type change %s -> %s is not supported",
+		d.to.FullName(), d.from.BaseType, d.to.BaseType)
+}
+
+type PatchCompositeType struct {
+	from, to *CompositeType
+}
+
+func (ct *PatchCompositeType) Operations() []*Operation {
+	if ct.from != nil && ct.to != nil {
+		return ct.alter()
+	}
+	if ct.from == nil {
+		return ct.create()
+	}
+	return ct.drop()
+}
+
+func (ct *PatchCompositeType) create() []*Operation {
+	name := quotedName(ct.to.Namespace, ct.to.Name)
+	attrs := make([]string, len(ct.to.Attributes))
+	for i, a := range ct.to.Attributes {
+		attrs[i] = QuoteIdent(a.Name) + " " + a.Type
+	}
+	ret := []*Operation{{
+		Kind:   OpCreate,
+		Object: "TYPE",
+		Target: name,
+		SQL:    fmt.Sprintf("CREATE TYPE %s AS (%s)", name, strings.Join(attrs, ", ")),
+	}}
+	if ct.to.Comment != "" {
+		ret = append(ret, commentOp("TYPE", name, ct.to.Comment))
+	}
+	return ret
+}
+
+// alter drops removed attributes, adds new attributes to the end and changes attribute types,
+// the values of dropped attributes are lost in the columns of the type
+func (ct *PatchCompositeType) alter() []*Operation {
+	name := quotedName(ct.to.Namespace, ct.to.Name)
+	ret := []*Operation{}
+	alter := func(destructive bool, action string) {
+		ret = append(ret, &Operation{
+			Kind:        OpAlter,
+			Object:      "TYPE",
+			Target:      name,
+			SQL:         fmt.Sprintf("ALTER TYPE %s %s", name, action),
+			Destructive: destructive,
+			Lock:        LockAccessExclusive,
+		})
+	}
+	for _, a := range ct.from.Attributes {
+		if _, err := ct.to.FindAttributeByName(a.Name); err != nil && !PatchDropDisable {
+			alter(true, "DROP ATTRIBUTE IF EXISTS "+QuoteIdent(a.Name))
+		}
+	}
+	for _, a := range ct.to.Attributes {
+		fa, err := ct.from.FindAttributeByName(a.Name)
+		switch {
+		case err != nil:
+			alter(false, fmt.Sprintf("ADD ATTRIBUTE %s %s", QuoteIdent(a.Name), a.Type))
+		case NormalizeType(fa.Type) != NormalizeType(a.Type):
+			alter(false, fmt.Sprintf("ALTER ATTRIBUTE %s TYPE %s", QuoteIdent(a.Name), a.Type))
+		}
+	}
+	if ct.from.Comment != ct.to.Comment {
+		ret = append(ret, commentOp("TYPE", name, ct.to.Comment))
+	}
+	return ret
+}
+
+func (ct *PatchCompositeType) drop() []*Operation {
+	if PatchDropDisable {
+		return nil
+	}
+	name := quotedName(ct.from.Namespace, ct.from.Name)
+	return []*Operation{{
+		Kind:        OpDrop,
+		Object:      "TYPE",
+		Target:      name,
+		SQL:         fmt.Sprintf("DROP TYPE IF EXISTS %s", name),
+		Destructive: true,
+		Lock:        LockAccessExclusive,
+	}}
+}
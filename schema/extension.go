@@ -0,0 +1,75 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+type PatchExtension struct {
+	from, to *Extension
+}
+
+func (e *PatchExtension) Operations() []*Operation {
+	if e.from != nil && e.to != nil {
+		return e.alter()
+	}
+	if e.from == nil {
+		return e.create()
+	}
+	return e.drop()
+}
+
+func (e *PatchExtension) create() []*Operation {
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "CREATE EXTENSION IF NOT EXISTS %s", QuoteIdent(e.to.Name))
+	if e.to.Namespace != "" {
+		fmt.Fprintf(sb, " SCHEMA %s", QuoteIdent(e.to.Namespace))
+	}
+	if e.to.Version != "" {
+		fmt.Fprintf(sb, " VERSION %s", quoteLiteral(e.to.Version))
+	}
+	return []*Operation{{
+		Kind:   OpCreate,
+		Object: "EXTENSION",
+		Target: QuoteIdent(e.to.Name),
+		SQL:    sb.String(),
+	}}
+}
+
+// alter updates the extension to the declared version and moves it to the declared namespace,
+// the extension without declared version or namespace is not changed
+func (e *PatchExtension) alter() []*Operation {
+	ret := []*Operation{}
+	name := QuoteIdent(e.to.Name)
+	if e.to.Version != "" && e.to.Version != e.from.Version {
+		ret = append(ret, &Operation{
+			Kind:   OpAlter,
+			Object: "EXTENSION",
+			Target: name,
+			SQL:    fmt.Sprintf("ALTER EXTENSION %s UPDATE TO %s", name, quoteLiteral(e.to.Version)),
+		})
+	}
+	if e.to.Namespace != "" && e.to.Namespace != e.from.Namespace {
+		ret = append(ret, &Operation{
+			Kind:   OpAlter,
+			Object: "EXTENSION",
+			Target: name,
+			SQL:    fmt.Sprintf("ALTER EXTENSION %s SET SCHEMA %s", name, QuoteIdent(e.to.Namespace)),
+		})
+	}
+	return ret
+}
+
+func (e *PatchExtension) drop() []*Operation {
+	if PatchDropDisable {
+		return nil
+	}
+	name := QuoteIdent(e.from.Name)
+	return []*Operation{{
+		Kind:        OpDrop,
+		Object:      "EXTENSION",
+		Target:      name,
+		SQL:         "DROP EXTENSION IF EXISTS " + name,
+		Destructive: true,
+	}}
+}
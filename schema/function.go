@@ -0,0 +1,326 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type PatchFunction struct {
+	from, to *Function
+}
+
+func (f *PatchFunction) Operations() []*Operation {
+	if f.from != nil && f.to != nil {
+		return f.alter()
+	}
+	if f.from == nil {
+		return f.create()
+	}
+	return f.drop()
+}
+
+func (f *PatchFunction) target() string {
+	if f.to != nil {
+		return quotedName(f.to.Namespace, f.to.Name) + "(" + f.to.Arguments + ")"
+	}
+	return quotedName(f.from.Namespace, f.from.Name) + "(" + f.from.Arguments + ")"
+}
+
+func (f *PatchFunction) create() []*Operation {
+	ret := []*Operation{{
+		Kind:   OpCreate,
+		Object: "FUNCTION",
+		Target: f.target(),
+		SQL:    createFunctionDDL(f.to),
+	}}
+	if f.to.Comment != "" {
+		ret = append(ret, commentOp("FUNCTION", f.target(), f.to.Comment))
+	}
+	return ret
+}
+
+// alter replaces the changed function, the function with changed result type
+// can not be replaced and is dropped and created again, the triggers that call it
+// are dropped before and created again by table patches
+func (f *PatchFunction) alter() []*Operation {
+	if f.recreated() {
+		return append([]*Operation{{
+			Kind:        OpDrop,
+			Object:      "FUNCTION",
+			Target:      f.target(),
+			SQL:         "DROP FUNCTION IF EXISTS " + f.target(),
+			Destructive: true,
+		}}, f.create()...)
+	}
+	ret := []*Operation{}
+	if createFunctionDDL(normalizeFunction(f.from)) != createFunctionDDL(normalizeFunction(f.to)) &&
+		!f.returnsChanged() {
+		ret = append(ret, &Operation{
+			Kind:   OpAlter,
+			Object: "FUNCTION",
+			Target: f.target(),
+			SQL:    createFunctionDDL(f.to),
+		})
+	}
+	if f.from.Comment != f.to.Comment {
+		ret = append(ret, commentOp("FUNCTION", f.target(), f.to.Comment))
+	}
+	return ret
+}
+
+// returnsChanged reports whether the result type of function is changed
+func (f *PatchFunction) returnsChanged() bool {
+	return f.from != nil && f.to != nil && NormalizeExpr(f.from.Returns) != NormalizeExpr(f.to.Returns)
+}
+
+// recreated reports whether the function is dropped and created again,
+// the function is not dropped when drops are disabled
+func (f *PatchFunction) recreated() bool {
+	return f.returnsChanged() && !PatchDropDisable
+}
+
+// returnsWarning describes the result type change that is not applied because drops are disabled
+func (f *PatchFunction) returnsWarning() string {
+	if !f.returnsChanged() || !PatchDropDisable {
+		return ""
+	}
+	return fmt.Sprintf("function %s: result type change %s -> %s requires dropping the function, drops are disabled",
+		f.target(), f.from.Returns, f.to.Returns)
+}
+
+// calledBy reports whether the trigger of table in namespace ns calls the source function
+func (f *PatchFunction) calledBy(tr *Trigger, ns string) bool {
+	i := strings.Index(tr.Function, "(")
+	if i < 0 {
+		return false
+	}
+	fns, name := ns, parseIdent(tr.Function[:i])
+	if j := strings.LastIndex(tr.Function[:i], "."); j >= 0 {
+		fns, name = parseIdent(tr.Function[:j]), parseIdent(tr.Function[j+1:i])
+	}
+	return fns == f.from.Namespace && name == f.from.Name
+}
+
+// recreateFunctionTriggers marks the triggers that call the recreated functions,
+// they are dropped before the functions
+func (s *PatchSchema) recreateFunctionTriggers() {
+	for _, pf := range s.functions {
+		if !pf.recreated() {
+			continue
+		}
+		for _, pt := range s.tables {
+			for _, ptr := range pt.triggers {
+				if ptr.from != nil && pf.calledBy(ptr.from, ptr.namespace) {
+					ptr.recreate = true
+				}
+			}
+		}
+	}
+}
+
+func (f *PatchFunction) drop() []*Operation {
+	if PatchDropDisable {
+		return nil
+	}
+	return []*Operation{{
+		Kind:        OpDrop,
+		Object:      "FUNCTION",
+		Target:      f.target(),
+		SQL:         "DROP FUNCTION IF EXISTS " + f.target(),
+		Destructive: true,
+	}}
+}
+
+func createFunctionDDL(f *Function) string {
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "CREATE OR REPLACE FUNCTION %s(%s) RETURNS %s LANGUAGE %s",
+		quotedName(f.Namespace, f.Name), f.Arguments, f.Returns, f.Language)
+	if v := strings.ToUpper(f.Volatility); v != "" && v != "VOLATILE" {
+		fmt.Fprint(sb, " ", v)
+	}
+	if f.SecurityDefiner {
+		fmt.Fprint(sb, " SECURITY DEFINER")
+	}
+	if f.Strict {
+		fmt.Fprint(sb, " STRICT")
+	}
+	if f.Leakproof {
+		fmt.Fprint(sb, " LEAKPROOF")
+	}
+	if p := strings.ToUpper(f.Parallel); p != "" && p != "UNSAFE" {
+		fmt.Fprint(sb, " PARALLEL ", p)
+	}
+	if f.Cost != 0 {
+		fmt.Fprint(sb, " COST ", strconv.FormatFloat(f.Cost, 'f', -1, 64))
+	}
+	for _, c := range f.Config {
+		name, value := parseFunctionConfig(c)
+		fmt.Fprintf(sb, " SET %s = %s", name, value)
+	}
+	fmt.Fprint(sb, " AS ", dollarQuote(f.Body))
+	return sb.String()
+}
+
+// dollarQuote returns the function body in dollar quotes with the tag that is not used in body
+func dollarQuote(body string) string {
+	tag := "$function$"
+	for i := 1; strings.Contains(body, tag); i++ {
+		tag = fmt.Sprintf("$function%d$", i)
+	}
+	return tag + body + tag
+}
+
+// normalizeFunction returns the copy of function in canonical form for comparison,
+// the comment is not compared
+func normalizeFunction(f *Function) *Function {
+	ret := *f
+	ret.Arguments = NormalizeExpr(f.Arguments)
+	ret.Returns = NormalizeExpr(f.Returns)
+	ret.Language = strings.ToLower(f.Language)
+	ret.Volatility = strings.ToUpper(f.Volatility)
+	if ret.Volatility == "VOLATILE" {
+		ret.Volatility = ""
+	}
+	ret.Parallel = strings.ToUpper(f.Parallel)
+	if ret.Parallel == "UNSAFE" {
+		ret.Parallel = ""
+	}
+	if ret.Cost == DefaultFunctionCost {
+		ret.Cost = 0
+	}
+	ret.Config = make([]string, len(f.Config))
+	for i, c := range f.Config {
+		name, value := parseFunctionConfig(c)
+		ret.Config[i] = name + "=" + value
+	}
+	sort.Strings(ret.Config)
+	ret.Body = strings.TrimSpace(f.Body)
+	ret.Comment = ""
+	return &ret
+}
+
+// DefaultFunctionCost is the cost of SQL and procedural language functions when it is not declared
+const DefaultFunctionCost = 100
+
+// parseFunctionConfig returns the parameter name in lower case and the value of SET clause,
+// the value is written as name=value in pg_proc and as name = value or name TO value in DDL
+func parseFunctionConfig(c string) (string, string) {
+	name, value := c, ""
+	if i := strings.Index(c, "="); i >= 0 {
+		name, value = c[:i], c[i+1:]
+	} else if fs := strings.Fields(c); len(fs) > 2 && strings.EqualFold(fs[1], "TO") {
+		name, value = fs[0], strings.Join(fs[2:], " ")
+	}
+	return strings.ToLower(strings.TrimSpace(name)), strings.TrimSpace(value)
+}
+
+type PatchTrigger struct {
+	from, to  *Trigger
+	namespace string
+	tableName string
+	recreate  bool // the called function is dropped and created again, the trigger is dropped before it
+}
+
+func (tr *PatchTrigger) Operations() []*Operation {
+	if tr.from != nil && tr.to != nil {
+		return tr.alter()
+	}
+	if tr.from == nil {
+		return tr.create()
+	}
+	return tr.drop()
+}
+
+func (tr *PatchTrigger) create() []*Operation {
+	target := QuoteIdent(tr.to.Name) + " ON " + tr.tableName
+	ret := []*Operation{{
+		Kind:   OpCreate,
+		Object: "TRIGGER",
+		Target: target,
+		SQL:    createTriggerDDL(tr.to, tr.tableName),
+		Lock:   LockShareRowExclusive,
+	}}
+	if tr.to.Comment != "" {
+		ret = append(ret, commentOp("TRIGGER", target, tr.to.Comment))
+	}
+	return ret
+}
+
+func (tr *PatchTrigger) alter() []*Operation {
+	if tr.recreate {
+		return tr.create()
+	}
+	if NormalizeExpr(tr.triggerDDL(tr.from)) == NormalizeExpr(tr.triggerDDL(tr.to)) {
+		if tr.from.Comment != tr.to.Comment {
+			return []*Operation{commentOp("TRIGGER", QuoteIdent(tr.to.Name)+" ON "+tr.tableName, tr.to.Comment)}
+		}
+		return nil
+	}
+	return append(tr.drop(), tr.create()...)
+}
+
+// drop always drops unused triggers, they keep no data
+func (tr *PatchTrigger) drop() []*Operation {
+	return []*Operation{{
+		Kind:   OpDrop,
+		Object: "TRIGGER",
+		Target: QuoteIdent(tr.from.Name) + " ON " + tr.tableName,
+		SQL:    fmt.Sprintf("DROP TRIGGER IF EXISTS %s ON %s", QuoteIdent(tr.from.Name), tr.tableName),
+		Lock:   LockAccessExclusive,
+	}}
+}
+
+// triggerDDL returns the trigger definition for comparison,
+// the function and referenced table of the table namespace may be qualified or not
+func (tr *PatchTrigger) triggerDDL(t *Trigger) string {
+	c := *t
+	if i := strings.Index(c.Function, "("); i >= 0 {
+		name := strings.TrimPrefix(c.Function[:i], tr.namespace+".")
+		c.Function = name + c.Function[i:]
+	}
+	c.From = strings.TrimPrefix(c.From, tr.namespace+".")
+	return createTriggerDDL(&c, tr.tableName)
+}
+
+func createTriggerDDL(t *Trigger, tableName string) string {
+	if t.Def != "" {
+		return strings.TrimRight(t.Def, ";")
+	}
+	sb := &strings.Builder{}
+	forEach := strings.ToUpper(t.ForEach)
+	if forEach == "" {
+		forEach = "STATEMENT"
+	}
+	fmt.Fprint(sb, "CREATE ")
+	if t.Constraint {
+		fmt.Fprint(sb, "CONSTRAINT ")
+	}
+	fmt.Fprintf(sb, "TRIGGER %s %s %s ON %s",
+		QuoteIdent(t.Name), strings.ToUpper(t.Timing), strings.Join(t.Events, " OR "), tableName)
+	if t.From != "" {
+		fmt.Fprint(sb, " FROM ", t.From)
+	}
+	if t.Deferrable {
+		fmt.Fprint(sb, " DEFERRABLE")
+		if t.InitiallyDeferred {
+			fmt.Fprint(sb, " INITIALLY DEFERRED")
+		}
+	}
+	if t.OldTable != "" || t.NewTable != "" {
+		fmt.Fprint(sb, " REFERENCING")
+		if t.OldTable != "" {
+			fmt.Fprint(sb, " OLD TABLE AS ", QuoteIdent(t.OldTable))
+		}
+		if t.NewTable != "" {
+			fmt.Fprint(sb, " NEW TABLE AS ", QuoteIdent(t.NewTable))
+		}
+	}
+	fmt.Fprint(sb, " FOR EACH ", forEach)
+	if t.When != "" {
+		fmt.Fprintf(sb, " WHEN (%s)", t.When)
+	}
+	fmt.Fprint(sb, " EXECUTE FUNCTION ", t.Function)
+	return sb.String()
+}
@@ -0,0 +1,246 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// allPrivileges are the privileges granted by ALL for the object type
+var allPrivileges = map[string][]string{
+	"TABLE":    {"SELECT", "INSERT", "UPDATE", "DELETE", "TRUNCATE", "REFERENCES", "TRIGGER"},
+	"SEQUENCE": {"USAGE", "SELECT", "UPDATE"},
+	"SCHEMA":   {"USAGE", "CREATE"},
+}
+
+// sortGrants sorts grants by roles, the privileges order is kept as declared
+func sortGrants(grants []*Grant) {
+	sort.SliceStable(grants, func(i, j int) bool {
+		return grants[i].Role < grants[j].Role
+	})
+}
+
+// privilege is the privilege on object or on the column of table
+type privilege struct {
+	name, column string
+}
+
+// grantPrivileges returns the privileges by roles, ALL is expanded to the privileges of object type
+func grantPrivileges(object string, grants []*Grant) map[string]map[privilege]bool {
+	ret := map[string]map[privilege]bool{}
+	for _, g := range grants {
+		role := g.Role
+		if strings.EqualFold(role, "public") {
+			role = "public"
+		}
+		if ret[role] == nil {
+			ret[role] = map[privilege]bool{}
+		}
+		for _, p := range g.Privileges {
+			name, columns := parsePrivilege(p)
+			names := []string{name}
+			if name == "ALL" || name == "ALL PRIVILEGES" {
+				names = allPrivileges[object]
+			}
+			for _, n := range names {
+				if len(columns) == 0 {
+					ret[role][privilege{name: n}] = true
+				}
+				for _, c := range columns {
+					ret[role][privilege{name: n, column: c}] = true
+				}
+			}
+		}
+	}
+	return ret
+}
+
+// parsePrivilege returns the privilege name in upper case and the column names, e.g. of UPDATE (name, email)
+func parsePrivilege(p string) (string, []string) {
+	p = strings.TrimSpace(p)
+	i := strings.Index(p, "(")
+	if i < 0 || !strings.HasSuffix(p, ")") {
+		return strings.ToUpper(reSpaces.ReplaceAllString(p, " ")), nil
+	}
+	columns := []string{}
+	for _, c := range strings.Split(p[i+1:len(p)-1], ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			columns = append(columns, parseIdent(c))
+		}
+	}
+	return strings.ToUpper(strings.TrimSpace(p[:i])), columns
+}
+
+// formatPrivileges returns the privilege list of GRANT or REVOKE,
+// the column privileges with the same name are grouped
+func formatPrivileges(privs []privilege) string {
+	sort.Slice(privs, func(i, j int) bool {
+		return privs[i].name < privs[j].name || privs[i].name == privs[j].name && privs[i].column < privs[j].column
+	})
+	ret := []string{}
+	for i := 0; i < len(privs); {
+		p := privs[i]
+		if p.column == "" {
+			ret = append(ret, p.name)
+			i++
+			continue
+		}
+		columns := []string{}
+		for ; i < len(privs) && privs[i].name == p.name; i++ {
+			columns = append(columns, QuoteIdent(privs[i].column))
+		}
+		ret = append(ret, fmt.Sprintf("%s (%s)", p.name, strings.Join(columns, ", ")))
+	}
+	return strings.Join(ret, ", ")
+}
+
+// grantOps revokes the privileges that are not declared and grants the new privileges of roles,
+// the privileges are not changed when no grants are declared
+func grantOps(object, name string, from, to []*Grant) []*Operation {
+	if len(to) == 0 {
+		return nil
+	}
+	fromPrivs, toPrivs := grantPrivileges(object, from), grantPrivileges(object, to)
+	roles := []string{}
+	for role := range fromPrivs {
+		roles = append(roles, role)
+	}
+	for role := range toPrivs {
+		if _, ok := fromPrivs[role]; !ok {
+			roles = append(roles, role)
+		}
+	}
+	sort.Strings(roles)
+	diff := func(a, b map[privilege]bool) []privilege {
+		ret := []privilege{}
+		for p := range a {
+			if !b[p] {
+				ret = append(ret, p)
+			}
+		}
+		return ret
+	}
+	ret := []*Operation{}
+	for _, role := range roles {
+		if revoked := diff(fromPrivs[role], toPrivs[role]); len(revoked) > 0 {
+			ret = append(ret, &Operation{
+				Kind:   OpAlter,
+				Object: object,
+				Target: name,
+				SQL: fmt.Sprintf("REVOKE %s ON %s %s FROM %s",
+					formatPrivileges(revoked), object, name, quoteRoles([]string{role})),
+			})
+		}
+		if granted := diff(toPrivs[role], fromPrivs[role]); len(granted) > 0 {
+			ret = append(ret, &Operation{
+				Kind:   OpAlter,
+				Object: object,
+				Target: name,
+				SQL: fmt.Sprintf("GRANT %s ON %s %s TO %s",
+					formatPrivileges(granted), object, name, quoteRoles([]string{role})),
+			})
+		}
+	}
+	return ret
+}
+
+// ownerOp changes the owner of object, the owner is not changed when it is not declared
+func ownerOp(object, name, from, to string) []*Operation {
+	if to == "" || to == from {
+		return nil
+	}
+	return []*Operation{{
+		Kind:   OpAlter,
+		Object: object,
+		Target: name,
+		SQL:    fmt.Sprintf("ALTER %s %s OWNER TO %s", object, name, quoteRoles([]string{to})),
+		Lock:   LockAccessExclusive,
+	}}
+}
+
+// quoteRoles returns the quoted role list, PUBLIC and CURRENT_USER like keywords are not quoted
+func quoteRoles(roles []string) string {
+	ret := make([]string, len(roles))
+	for i, r := range roles {
+		if roleKeyword(r) {
+			ret[i] = strings.ToUpper(r)
+		} else {
+			ret[i] = QuoteIdent(r)
+		}
+	}
+	return strings.Join(ret, ", ")
+}
+
+// roleKeyword reports whether the role is PUBLIC or CURRENT_USER like keyword
+func roleKeyword(role string) bool {
+	switch strings.ToLower(role) {
+	case "public", "current_role", "current_user", "session_user":
+		return true
+	}
+	return false
+}
+
+// privileges changes the owner and grants of table or view, the recreated view is granted again
+func (t *PatchTable) privileges() []*Operation {
+	tp := t.to.Type
+	if tp == "" {
+		tp = "TABLE"
+	}
+	name := quotedName(t.to.Namespace, t.to.Name)
+	var (
+		owner  string
+		grants []*Grant
+	)
+	if t.from != nil {
+		owner, grants = t.from.Owner, t.from.Grants
+	}
+	// GRANT ON TABLE is used for all kinds of relations
+	return append(ownerOp(tp, name, owner, t.to.Owner), grantOps("TABLE", name, grants, t.to.Grants)...)
+}
+
+// validateRoles returns the error when the roles used by target schema do not exist in the source database,
+// the roles are checked only when the source schema is inspected from database
+func validateRoles(from, to *Schema) error {
+	if from.Roles == nil {
+		return nil
+	}
+	exists := make(map[string]bool, len(from.Roles))
+	for _, r := range from.Roles {
+		exists[r] = true
+	}
+	missing := map[string]bool{}
+	check := func(owner string, grants []*Grant, roles ...string) {
+		if owner != "" {
+			roles = append(roles, owner)
+		}
+		for _, g := range grants {
+			roles = append(roles, g.Role)
+		}
+		for _, r := range roles {
+			if !roleKeyword(r) && !exists[r] {
+				missing[r] = true
+			}
+		}
+	}
+	for _, n := range to.Namespaces {
+		check(n.Owner, n.Grants)
+	}
+	for _, sq := range to.Sequences {
+		check(sq.Owner, sq.Grants)
+	}
+	for _, t := range to.Tables {
+		check(t.Owner, t.Grants)
+		for _, p := range t.Policies {
+			check("", nil, p.Roles...)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(missing))
+	for r := range missing {
+		names = append(names, r)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("roles not found in database: %s", strings.Join(names, ", "))
+}
@@ -0,0 +1,133 @@
+package schema
+
+import (
+	"regexp"
+	"strings"
+)
+
+var reIdentPath = regexp.MustCompile(`("(?:[^"]|"")+"|[A-Za-z_][\w$]*)(?:\s*\.\s*("(?:[^"]|"")+"|[A-Za-z_][\w$]*))?`)
+
+// TableDependencies returns full names of tables and views that the table depends on:
+// parent tables of relations, and tables used by the view.
+// View dependencies are taken from DependsOn, or are found in the view definition.
+func (s *Schema) TableDependencies(t *Table) []string {
+	ret := []string{}
+	seen := map[string]bool{t.FullName(): true}
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			ret = append(ret, name)
+		}
+	}
+	for _, r := range s.Relations {
+		if r.Table.FullName() == t.FullName() {
+			add(r.ParentTable.FullName())
+		}
+	}
+	for _, d := range t.DependsOn {
+		add(QualifiedName(s.ParseName(d)))
+	}
+	if len(t.DependsOn) == 0 && t.Def != "" {
+		for _, ss := range reIdentPath.FindAllStringSubmatch(t.Def, -1) {
+			ns, name := s.CurrentSchema, parseIdent(ss[1])
+			if ss[2] != "" {
+				ns, name = name, parseIdent(ss[2])
+			}
+			if dt, err := s.FindTable(ns, name); err == nil {
+				add(dt.FullName())
+			}
+		}
+	}
+	return ret
+}
+
+// sortByDependencies returns names ordered so that dependencies go first,
+// the original order is kept where possible and cycles are broken at the first name of cycle
+func sortByDependencies(names []string, deps func(name string) []string) []string {
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	known := make(map[string]bool, len(names))
+	for _, n := range names {
+		known[n] = true
+	}
+	state := make(map[string]int, len(names))
+	ret := make([]string, 0, len(names))
+	var visit func(n string)
+	visit = func(n string) {
+		if state[n] != 0 {
+			return
+		}
+		state[n] = visiting
+		for _, d := range deps(n) {
+			if known[d] {
+				visit(d)
+			}
+		}
+		state[n] = visited
+		ret = append(ret, n)
+	}
+	for _, n := range names {
+		visit(n)
+	}
+	return ret
+}
+
+// sortTables orders table patches by dependencies: dropped tables go first
+// in reverse dependency order, then created and altered tables in dependency order.
+// Foreign keys are created after all tables, so circular references need no special care.
+func (s *PatchSchema) sortTables(from, to *Schema) {
+	drops := map[string]*PatchTable{}
+	keeps := map[string]*PatchTable{}
+	for _, pt := range s.tables {
+		if pt.to == nil {
+			drops[pt.from.FullName()] = pt
+		} else {
+			keeps[pt.to.FullName()] = pt
+		}
+	}
+	tableDeps := func(sc *Schema) func(name string) []string {
+		tables := make(map[string]*Table, len(sc.Tables))
+		for _, t := range sc.Tables {
+			tables[t.FullName()] = t
+		}
+		return func(name string) []string {
+			if t, ok := tables[name]; ok {
+				return sc.TableDependencies(t)
+			}
+			return nil
+		}
+	}
+	fromNames := make([]string, 0, len(from.Tables))
+	for _, t := range from.Tables {
+		fromNames = append(fromNames, t.FullName())
+	}
+	toNames := make([]string, 0, len(to.Tables))
+	for _, t := range to.Tables {
+		toNames = append(toNames, t.FullName())
+	}
+
+	sorted := make([]*PatchTable, 0, len(s.tables))
+	fromOrder := sortByDependencies(fromNames, tableDeps(from))
+	for i := len(fromOrder) - 1; i >= 0; i-- {
+		if pt, ok := drops[fromOrder[i]]; ok {
+			sorted = append(sorted, pt)
+		}
+	}
+	for _, name := range sortByDependencies(toNames, tableDeps(to)) {
+		if pt, ok := keeps[name]; ok {
+			sorted = append(sorted, pt)
+		}
+	}
+	s.tables = sorted
+}
+
+// parseIdent returns the identifier name as PostgreSQL does:
+// quoted identifier is unquoted, unquoted identifier is folded to lower case
+func parseIdent(ident string) string {
+	if len(ident) > 1 && strings.HasPrefix(ident, `"`) && strings.HasSuffix(ident, `"`) {
+		return strings.ReplaceAll(ident[1:len(ident)-1], `""`, `"`)
+	}
+	return strings.ToLower(ident)
+}
@@ -0,0 +1,107 @@
+package schema
+
+import "strings"
+
+// keywords that can not be used as bare column or table names,
+// the reserved, type/function and column name categories of PostgreSQL keywords
+var sqlKeywords = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true, "array": true,
+	"as": true, "asc": true, "asymmetric": true, "authorization": true, "between": true,
+	"bigint": true, "binary": true, "bit": true, "boolean": true, "both": true, "case": true,
+	"cast": true, "char": true, "character": true, "check": true, "coalesce": true,
+	"collate": true, "collation": true, "column": true, "concurrently": true,
+	"constraint": true, "create": true, "cross": true, "current_catalog": true,
+	"current_date": true, "current_role": true, "current_schema": true, "current_time": true,
+	"current_timestamp": true, "current_user": true, "dec": true, "decimal": true,
+	"default": true, "deferrable": true, "desc": true, "distinct": true, "do": true,
+	"else": true, "end": true, "except": true, "exists": true, "extract": true, "false": true,
+	"fetch": true, "float": true, "for": true, "foreign": true, "freeze": true, "from": true,
+	"full": true, "grant": true, "greatest": true, "group": true, "grouping": true,
+	"having": true, "ilike": true, "in": true, "initially": true, "inner": true, "inout": true,
+	"int": true, "integer": true, "intersect": true, "interval": true, "into": true, "is": true,
+	"isnull": true, "join": true, "lateral": true, "leading": true, "least": true, "left": true,
+	"like": true, "limit": true, "localtime": true, "localtimestamp": true, "national": true,
+	"natural": true, "nchar": true, "none": true, "normalize": true, "not": true,
+	"notnull": true, "null": true, "nullif": true, "numeric": true, "offset": true, "on": true,
+	"only": true, "or": true, "order": true, "out": true, "outer": true, "overlaps": true,
+	"overlay": true, "placing": true, "position": true, "precision": true, "primary": true,
+	"real": true, "references": true, "returning": true, "right": true, "row": true,
+	"select": true, "session_user": true, "setof": true, "similar": true, "smallint": true,
+	"some": true, "substring": true, "symmetric": true, "table": true, "tablesample": true,
+	"then": true, "time": true, "timestamp": true, "to": true, "trailing": true, "treat": true,
+	"trim": true, "true": true, "union": true, "unique": true, "user": true, "using": true,
+	"values": true, "varchar": true, "variadic": true, "verbose": true, "when": true,
+	"where": true, "window": true, "with": true, "xmlattributes": true, "xmlconcat": true,
+	"xmlelement": true, "xmlexists": true, "xmlforest": true, "xmlnamespaces": true,
+	"xmlparse": true, "xmlpi": true, "xmlroot": true, "xmlserialize": true, "xmltable": true,
+}
+
+// QuoteIdent returns the identifier quoted only when PostgreSQL needs it,
+// like the quote_ident function does
+func QuoteIdent(name string) string {
+	if name == "" {
+		return `""`
+	}
+	safe := !sqlKeywords[name]
+	for i, r := range name {
+		if !(r >= 'a' && r <= 'z' || r == '_' || i > 0 && (r >= '0' && r <= '9' || r == '$')) {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteIdents quotes each identifier of the list
+func quoteIdents(names []string) []string {
+	ret := make([]string, len(names))
+	for i, n := range names {
+		ret[i] = QuoteIdent(n)
+	}
+	return ret
+}
+
+// quotedName returns quoted schema-qualified object name
+func quotedName(ns, name string) string {
+	if ns == "" {
+		return QuoteIdent(name)
+	}
+	return QuoteIdent(ns) + "." + QuoteIdent(name)
+}
+
+// QualifiedName returns name prefixed with namespace, the parts with dots or quotes
+// are quoted, so the name is split back by Schema.ParseName
+func QualifiedName(ns, name string) string {
+	if ns == "" {
+		return nameIdent(name)
+	}
+	return nameIdent(ns) + "." + nameIdent(name)
+}
+
+// nameIdent quotes the name only when it can not be split by dots without quotes
+func nameIdent(name string) string {
+	if !strings.ContainsAny(name, `."`) {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// unquoteName returns the name of quoted identifier, other names are kept as they are
+func unquoteName(name string) string {
+	if len(name) > 1 && strings.HasPrefix(name, `"`) && strings.HasSuffix(name, `"`) {
+		return parseIdent(name)
+	}
+	return name
+}
+
+// quoteRef quotes the object reference, that is qualified
+// with namespace by the first dot
+func quoteRef(ref string) string {
+	if i := strings.Index(ref, "."); i >= 0 {
+		return quotedName(ref[:i], ref[i+1:])
+	}
+	return QuoteIdent(ref)
+}
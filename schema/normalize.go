@@ -0,0 +1,257 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var reTypmod = regexp.MustCompile(`^([^(]*)\(([^)]*)\)(.*)$`)
+
+var typeAliases = map[string]string{
+	"character varying":           "varchar",
+	"character":                   "bpchar",
+	"char":                        "bpchar",
+	"int":                         "int4",
+	"integer":                     "int4",
+	"smallint":                    "int2",
+	"bigint":                      "int8",
+	"decimal":                     "numeric",
+	"real":                        "float4",
+	"float":                       "float8",
+	"double precision":            "float8",
+	"boolean":                     "bool",
+	"timestamp without time zone": "timestamp",
+	"timestamp with time zone":    "timestamptz",
+	"time without time zone":      "time",
+	"time with time zone":         "timetz",
+	"bit varying":                 "varbit",
+}
+
+// serial types are integer types with the default of owned sequence
+var serialTypes = map[string]string{
+	"smallserial": "int2",
+	"serial2":     "int2",
+	"serial":      "int4",
+	"serial4":     "int4",
+	"bigserial":   "int8",
+	"serial8":     "int8",
+}
+
+// sqlType is the parsed column type
+type sqlType struct {
+	base  string
+	mods  []int // type modifiers, nil when not declared
+	array bool
+}
+
+func parseSQLType(t string) sqlType {
+	ret := sqlType{}
+	t = lowerUnquoted(strings.TrimSpace(t))
+	for strings.HasSuffix(t, "[]") {
+		ret.array = true
+		t = strings.TrimSpace(strings.TrimSuffix(t, "[]"))
+	}
+	if ss := reTypmod.FindStringSubmatch(t); len(ss) > 0 {
+		for _, m := range strings.Split(ss[2], ",") {
+			n, err := strconv.Atoi(strings.TrimSpace(m))
+			if err != nil {
+				break
+			}
+			ret.mods = append(ret.mods, n)
+		}
+		t = ss[1] + " " + ss[3]
+	}
+	ret.base = strings.TrimPrefix(strings.Join(strings.Fields(t), " "), "pg_catalog.")
+	if ret.base == "float" && len(ret.mods) == 1 {
+		// float(p) is real up to 24 binary digits of precision and double precision above
+		ret.base = "float8"
+		if ret.mods[0] <= 24 {
+			ret.base = "float4"
+		}
+		ret.mods = nil
+	}
+	if a, ok := typeAliases[ret.base]; ok {
+		ret.base = a
+	}
+	if ret.base == "bpchar" && ret.mods == nil {
+		// character without length is character(1)
+		ret.mods = []int{1}
+	}
+	return ret
+}
+
+func (t sqlType) String() string {
+	sb := &strings.Builder{}
+	sb.WriteString(t.base)
+	if len(t.mods) > 0 {
+		mods := make([]string, len(t.mods))
+		for i, m := range t.mods {
+			mods[i] = strconv.Itoa(m)
+		}
+		fmt.Fprintf(sb, "(%s)", strings.Join(mods, ","))
+	}
+	if t.array {
+		sb.WriteString("[]")
+	}
+	return sb.String()
+}
+
+// NormalizeType returns the canonical form of column type for comparison:
+// aliases are replaced by internal type names, e.g. integer is int4,
+// type modifiers and array notation are written in the same way
+func NormalizeType(t string) string {
+	return parseSQLType(t).String()
+}
+
+var (
+	reLiteralCast  = regexp.MustCompile(`('(?:[^']|'')*'|\b\d+(?:\.\d+)?)::(?:"(?:[^"]|"")+"|[a-z_][\w.]*)(?: varying| precision| with(?:out)? time zone)?(?:\(\d+(?:,\s*\d+)?\))?(?:\[\])*`)
+	reQuotedNumber = regexp.MustCompile(`^'(-?\d+(?:\.\d+)?)'$`)
+	reSpaces       = regexp.MustCompile(`\s+`)
+	reSpacedPunct  = regexp.MustCompile(`\s*([(),*/%+=<>|-])\s*`)
+	reCallParens   = regexp.MustCompile(`(^|[^\w.])\(([a-z_][\w.]*\([^()]*\))\)`)
+	reMasked       = regexp.MustCompile(`['"]\x00(\d+)\x00['"]`)
+)
+
+// functions that return the same value in column default expressions
+var exprAliases = map[string]string{
+	"current_timestamp":       "now()",
+	"transaction_timestamp()": "now()",
+}
+
+// NormalizeExpr returns the canonical form of default or generated column expression
+// for comparison: keywords are in lower case, redundant casts of literals are removed,
+// spaces, enclosing parentheses and parentheses around function calls are not significant.
+// Quoted strings and identifiers are kept as they are.
+func NormalizeExpr(expr string) string {
+	expr, quoted := maskQuoted(lowerUnquoted(strings.TrimSpace(expr)))
+	expr = reSpaces.ReplaceAllString(expr, " ")
+	expr = reSpacedPunct.ReplaceAllString(expr, "$1")
+	for {
+		e := reLiteralCast.ReplaceAllString(expr, "$1")
+		e = reCallParens.ReplaceAllString(e, "$1$2")
+		e = trimParens(e)
+		if e == expr {
+			break
+		}
+		expr = e
+	}
+	expr = reMasked.ReplaceAllStringFunc(expr, func(m string) string {
+		i, _ := strconv.Atoi(m[2 : len(m)-2])
+		return reQuotedNumber.ReplaceAllString(quoted[i], "$1")
+	})
+	if a, ok := exprAliases[expr]; ok {
+		expr = a
+	}
+	return expr
+}
+
+// serialDefault is the normalized default of serial column, it is equal to any sequence default
+const serialDefault = "nextval()"
+
+// normalizeColumn returns the copy of column with canonical type, default and generated expression,
+// serial column is expanded to the integer column with sequence default
+func normalizeColumn(c *Column) *Column {
+	ret := *c
+	t := parseSQLType(c.Type)
+	if base, ok := serialTypes[t.base]; ok && !t.array {
+		t.base = base
+		ret.Default.Valid = true
+		ret.Default.String = serialDefault
+	} else if ret.Default.Valid {
+		ret.Default.String = NormalizeExpr(ret.Default.String)
+	}
+	ret.Type = t.String()
+	ret.Generated = NormalizeExpr(ret.Generated)
+	return &ret
+}
+
+// sameDefault reports whether normalized column defaults are equal
+func sameDefault(a, b *Column) bool {
+	if a.Default.Valid != b.Default.Valid {
+		return false
+	}
+	da, db := a.Default.String, b.Default.String
+	switch {
+	case da == serialDefault:
+		return strings.HasPrefix(db, "nextval(")
+	case db == serialDefault:
+		return strings.HasPrefix(da, "nextval(")
+	}
+	return da == db
+}
+
+// trimParens removes parentheses that enclose the whole expression
+func trimParens(expr string) string {
+	for len(expr) > 1 && expr[0] == '(' && expr[len(expr)-1] == ')' {
+		depth := 0
+		for i, r := range expr {
+			switch r {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			}
+			if depth == 0 && i < len(expr)-1 {
+				return expr
+			}
+		}
+		expr = expr[1 : len(expr)-1]
+	}
+	return expr
+}
+
+// maskQuoted replaces quoted strings and identifiers by numbered placeholders in the same quotes,
+// so the spaces and punctuation in them are not normalized, the quoted texts are returned by number
+func maskQuoted(s string) (string, []string) {
+	sb := &strings.Builder{}
+	quoted := []string{}
+	for i := 0; i < len(s); i++ {
+		q := s[i]
+		if q != '\'' && q != '"' {
+			sb.WriteByte(q)
+			continue
+		}
+		j := i + 1
+		for ; j < len(s); j++ {
+			if s[j] == q {
+				if j+1 < len(s) && s[j+1] == q {
+					j++ // doubled quote
+					continue
+				}
+				break
+			}
+		}
+		if j == len(s) {
+			// unterminated quote is kept as is
+			sb.WriteString(s[i:])
+			break
+		}
+		fmt.Fprintf(sb, "%c\x00%d\x00%c", q, len(quoted), q)
+		quoted = append(quoted, s[i:j+1])
+		i = j
+	}
+	return sb.String(), quoted
+}
+
+// lowerUnquoted folds to lower case the text outside of quoted strings and identifiers
+func lowerUnquoted(s string) string {
+	sb := &strings.Builder{}
+	var quote rune
+	for _, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		default:
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
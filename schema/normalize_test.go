@@ -0,0 +1,123 @@
+package schema
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeType(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"integer", "int4"},
+		{"INT", "int4"},
+		{"character(10)", "char(10)"},
+		{"character", "char(1)"},
+		{"character varying(32)", "varchar( 32 )"},
+		{"numeric(10, 2)", "decimal(10,2)"},
+		{"timestamp(3) with time zone", "timestamptz(3)"},
+		{"integer[]", "int4 []"},
+		{"double precision", "float8"},
+		{"float(24)", "real"},
+		{"float(25)", "float8"},
+		{"pg_catalog.text", "text"},
+		{`public."MyType"`, `PUBLIC."MyType"`},
+	}
+	for _, tt := range tests {
+		if a, b := NormalizeType(tt.a), NormalizeType(tt.b); a != b {
+			t.Errorf("%q != %q", a, b)
+		}
+	}
+	if a, b := NormalizeType(`"MyType"`), NormalizeType(`"mytype"`); a == b {
+		t.Errorf("%q == %q", a, b)
+	}
+}
+
+func TestNormalizeExpr(t *testing.T) {
+	tests := []struct {
+		a, b string
+	}{
+		{"'x'::character varying", "'x'"},
+		{"'x'::text::character varying(10)", "'x'"},
+		{"'2020-01-01 00:00:00'::timestamp without time zone", "'2020-01-01 00:00:00'"},
+		{"'-1'::integer", "-1"},
+		{"(0)", "0"},
+		{"now()", "CURRENT_TIMESTAMP"},
+		{"'{}'::jsonb", "'{}'"},
+		{"nextval('items_id_seq'::regclass)", "nextval('items_id_seq')"},
+		{"(price * (quantity)::numeric)", "price*(quantity)::numeric"},
+		{"'A'", "'A'"},
+		{"(tenant_id = (current_setting('app.tenant'::text))::integer)", "tenant_id = current_setting('app.tenant')::integer"},
+		{"'a - b'::text", "'a - b'"},
+		{"name  <>  'a  b'", "name<>'a  b'"},
+		{`"My Col" || 'x'`, `"My Col"||'x'`},
+		{"'it''s a - b'", "'it''s a - b'"},
+	}
+	for _, tt := range tests {
+		if a, b := NormalizeExpr(tt.a), NormalizeExpr(tt.b); a != b {
+			t.Errorf("%q != %q", a, b)
+		}
+	}
+	if a, b := NormalizeExpr("coalesce(lower(name))"), NormalizeExpr("coalescelower(name)"); a == b {
+		t.Errorf("%q == %q", a, b)
+	}
+	if a, b := NormalizeExpr("'A'"), NormalizeExpr("'a'"); a == b {
+		t.Errorf("%q == %q", a, b)
+	}
+	for _, tt := range []struct{ a, b string }{
+		{"'a - b'", "'a-b'"},
+		{"'a  b'", "'a b'"},
+		{"'( a )'", "'a'"},
+		{`"a b" = 1`, `"a  b" = 1`},
+		{"'x''5''y'", "'x''5y'"},
+	} {
+		if a, b := NormalizeExpr(tt.a), NormalizeExpr(tt.b); a == b {
+			t.Errorf("%q == %q", a, b)
+		}
+	}
+}
+
+func TestPatchSchema_BuildNormalized(t *testing.T) {
+	// columns of database are described as they are inspected
+	inspected := &Schema{Tables: []*Table{{
+		Name: "items",
+		Columns: []*Column{
+			{Name: "id", Type: "integer", Default: sql.NullString{String: "nextval('items_id_seq'::regclass)", Valid: true}},
+			{Name: "code", Type: "character(10)", Default: sql.NullString{String: "'x'::bpchar", Valid: true}},
+			{Name: "name", Type: "varchar(32)", Default: sql.NullString{String: "'x'::character varying", Valid: true}},
+			{Name: "tags", Type: "text[]"},
+			{Name: "created_at", Type: "timestamp with time zone", Default: sql.NullString{String: "now()", Valid: true}},
+		},
+	}}}
+	declared := &Schema{Tables: []*Table{{
+		Name: "items",
+		Columns: []*Column{
+			{Name: "id", Type: "serial"},
+			{Name: "code", Type: "char(10)", Default: sql.NullString{String: "'x'", Valid: true}},
+			{Name: "name", Type: "character varying(32)", Default: sql.NullString{String: "'x'", Valid: true}},
+			{Name: "tags", Type: "TEXT []"},
+			{Name: "created_at", Type: "timestamptz", Default: sql.NullString{String: "CURRENT_TIMESTAMP", Valid: true}},
+		},
+	}}}
+	s := &PatchSchema{}
+	if err := s.Build(inspected, declared); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := s.GenerateSQL(); len(qss) != 0 {
+		t.Error(strings.Join(qss, "\n"))
+	}
+
+	declared.Tables[0].Columns[0].Type = "bigserial"
+	declared.Tables[0].Columns[1].Default.String = "'y'"
+	s = &PatchSchema{}
+	if err := s.Build(inspected, declared); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := strings.Join(s.GenerateSQL(), "\n"); qss != `ALTER TABLE public.items ALTER COLUMN id TYPE int8
+ALTER TABLE public.items ALTER COLUMN code SET DEFAULT 'y'` {
+		t.Error(qss)
+	}
+}
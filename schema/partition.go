@@ -0,0 +1,246 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const partitionDateLayout = "2006-01-02"
+
+// partitionIntervals are the intervals of partition rules with the default layout of partition name suffix
+var partitionIntervals = map[string]struct {
+	format string
+	next   func(time.Time) time.Time
+}{
+	"day":   {"2006_01_02", func(t time.Time) time.Time { return t.AddDate(0, 0, 1) }},
+	"week":  {"2006_01_02", func(t time.Time) time.Time { return t.AddDate(0, 0, 7) }},
+	"month": {"2006_01", func(t time.Time) time.Time { return t.AddDate(0, 1, 0) }},
+	"year":  {"2006", func(t time.Time) time.Time { return t.AddDate(1, 0, 0) }},
+}
+
+func (pr *PartitionRule) Validate() error {
+	if _, ok := partitionIntervals[strings.ToLower(pr.Interval)]; !ok {
+		return fmt.Errorf("partition rule: unknown interval %q", pr.Interval)
+	}
+	from, err := time.Parse(partitionDateLayout, pr.From)
+	if err != nil {
+		return fmt.Errorf("partition rule: from: %w", err)
+	}
+	to, err := time.Parse(partitionDateLayout, pr.To)
+	if err != nil {
+		return fmt.Errorf("partition rule: to: %w", err)
+	}
+	if !from.Before(to) {
+		return fmt.Errorf("partition rule: from %s is not before to %s", pr.From, pr.To)
+	}
+	return nil
+}
+
+// Partitions returns the range partitions of table generated by rule,
+// the last partition ends at the end date of rule
+func (pr *PartitionRule) Partitions(table string) ([]*Partition, error) {
+	if err := pr.Validate(); err != nil {
+		return nil, err
+	}
+	interval := partitionIntervals[strings.ToLower(pr.Interval)]
+	format := pr.Format
+	if format == "" {
+		format = interval.format
+	}
+	from, _ := time.Parse(partitionDateLayout, pr.From)
+	to, _ := time.Parse(partitionDateLayout, pr.To)
+	ret := []*Partition{}
+	for start := from; start.Before(to); start = interval.next(start) {
+		end := interval.next(start)
+		if end.After(to) {
+			end = to
+		}
+		ret = append(ret, &Partition{
+			Name: table + "_" + start.Format(format),
+			Bound: fmt.Sprintf("FOR VALUES FROM (%s) TO (%s)",
+				quoteLiteral(start.Format(partitionDateLayout)), quoteLiteral(end.Format(partitionDateLayout))),
+			generated: true,
+		})
+	}
+	return ret, nil
+}
+
+// GeneratePartitions adds the partitions generated by partition rules of table,
+// the declared partitions are not replaced
+func (t *Table) GeneratePartitions() error {
+	for _, pr := range t.PartitionRules {
+		ps, err := pr.Partitions(t.Name)
+		if err != nil {
+			return fmt.Errorf("table %s: %w", t.Name, err)
+		}
+		for _, p := range ps {
+			if _, err := t.FindPartitionByName(p.Name); err != nil {
+				t.Partitions = append(t.Partitions, p)
+			}
+		}
+	}
+	return nil
+}
+
+var reBoundMidnight = regexp.MustCompile(`'(\d{4}-\d{2}-\d{2}) 00:00:00(?:[+-]\d{2}(?::\d{2})?)?'`)
+
+// normalizeBound returns the canonical form of partition bound for comparison,
+// the timestamp bounds at midnight are compared with the date bounds of partition rules
+func normalizeBound(bound string) string {
+	return NormalizeExpr(reBoundMidnight.ReplaceAllString(bound, "'$1'"))
+}
+
+type PatchPartition struct {
+	from, to  *Partition
+	namespace string
+	tableName string // quoted name of partitioned table
+	attach    bool   // the existing table is attached as partition
+	detach    bool   // the detached partition is kept as table
+}
+
+func (p *PatchPartition) Operations() []*Operation {
+	if p.from != nil && p.to != nil {
+		return p.alter()
+	}
+	if p.from == nil {
+		return p.create()
+	}
+	return p.drop()
+}
+
+func (p *PatchPartition) create() []*Operation {
+	name := quotedName(p.namespace, p.to.Name)
+	if p.attach {
+		return []*Operation{p.attachOp()}
+	}
+	return []*Operation{{
+		Kind:   OpCreate,
+		Object: "TABLE",
+		Target: name,
+		SQL:    fmt.Sprintf("CREATE TABLE %s PARTITION OF %s %s", name, p.tableName, p.to.Bound),
+		Lock:   LockAccessExclusive,
+	}}
+}
+
+// alter detaches the partition with changed bound and attaches it again,
+// the rows of partition are checked against the new bound
+func (p *PatchPartition) alter() []*Operation {
+	if normalizeBound(p.from.Bound) == normalizeBound(p.to.Bound) {
+		return nil
+	}
+	return []*Operation{p.detachOp(), p.attachOp()}
+}
+
+func (p *PatchPartition) drop() []*Operation {
+	if p.detach {
+		return []*Operation{p.detachOp()}
+	}
+	if PatchDropDisable {
+		return nil
+	}
+	name := quotedName(p.namespace, p.from.Name)
+	return []*Operation{{
+		Kind:        OpDrop,
+		Object:      "TABLE",
+		Target:      name,
+		SQL:         fmt.Sprintf("DROP TABLE IF EXISTS %s", name),
+		Destructive: true,
+		Lock:        LockAccessExclusive,
+	}}
+}
+
+func (p *PatchPartition) attachOp() *Operation {
+	return &Operation{
+		Kind:   OpAlter,
+		Object: "TABLE",
+		Target: p.tableName,
+		SQL: fmt.Sprintf("ALTER TABLE %s ATTACH PARTITION %s %s",
+			p.tableName, quotedName(p.namespace, p.to.Name), p.to.Bound),
+		Lock: LockShareUpdateExclusive,
+	}
+}
+
+func (p *PatchPartition) detachOp() *Operation {
+	return &Operation{
+		Kind:   OpAlter,
+		Object: "TABLE",
+		Target: p.tableName,
+		SQL:    fmt.Sprintf("ALTER TABLE %s DETACH PARTITION %s", p.tableName, quotedName(p.namespace, p.from.Name)),
+		Lock:   LockAccessExclusive,
+	}
+}
+
+// buildPartitions pairs the partitions of partitioned tables by name.
+// The source table with the name of new partition is attached instead of created,
+// the removed partition with the name of target table is detached instead of dropped,
+// so their table patches are removed.
+func (s *PatchSchema) buildPartitions(from, to *Schema) {
+	removed := map[*PatchTable]bool{}
+	for _, pt := range s.tables {
+		if pt.to == nil || pt.to.PartitionBy == "" && (pt.from == nil || pt.from.PartitionBy == "") {
+			continue
+		}
+		if pt.from != nil && NormalizeExpr(pt.from.PartitionBy) != NormalizeExpr(pt.to.PartitionBy) {
+			s.warnings = append(s.warnings, fmt.Sprintf("table %s: partition key change %q -> %q is not supported",
+				pt.to.FullName(), pt.from.PartitionBy, pt.to.PartitionBy))
+			continue
+		}
+		tableName := quotedName(pt.to.Namespace, pt.to.Name)
+		if pt.from != nil {
+			for _, p := range pt.from.Partitions {
+				pp := &PatchPartition{
+					namespace: pt.to.Namespace,
+					tableName: tableName,
+					from:      p,
+				}
+				pp.to, _ = pt.to.FindPartitionByName(p.Name)
+				if pp.to == nil {
+					if rt, err := to.FindTable(pt.to.Namespace, p.Name); err == nil {
+						pp.detach = true
+						removed[s.findTablePatch(nil, rt)] = true
+					}
+				}
+				pt.partitions = append(pt.partitions, pp)
+			}
+		}
+		for _, p := range pt.to.Partitions {
+			if pt.from != nil {
+				if _, err := pt.from.FindPartitionByName(p.Name); err == nil {
+					continue
+				}
+			}
+			pp := &PatchPartition{
+				namespace: pt.to.Namespace,
+				tableName: tableName,
+				to:        p,
+			}
+			if ft, err := from.FindTable(pt.to.Namespace, p.Name); err == nil {
+				pp.attach = true
+				removed[s.findTablePatch(ft, nil)] = true
+			}
+			pt.partitions = append(pt.partitions, pp)
+		}
+	}
+	if len(removed) == 0 {
+		return
+	}
+	tables := make([]*PatchTable, 0, len(s.tables))
+	for _, pt := range s.tables {
+		if !removed[pt] {
+			tables = append(tables, pt)
+		}
+	}
+	s.tables = tables
+}
+
+// findTablePatch returns the patch of table that is dropped (to is nil) or created (from is nil)
+func (s *PatchSchema) findTablePatch(from, to *Table) *PatchTable {
+	for _, pt := range s.tables {
+		if pt.from == from && pt.to == to {
+			return pt
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package schema
+
+// OpKind is the kind of migration operation
+type OpKind string
+
+const (
+	OpCreate  OpKind = "CREATE"
+	OpAlter   OpKind = "ALTER"
+	OpDrop    OpKind = "DROP"
+	OpComment OpKind = "COMMENT"
+)
+
+// LockLevel is the strongest table lock mode that operation acquires
+type LockLevel string
+
+const (
+	LockNone                 LockLevel = ""
+	LockShareUpdateExclusive LockLevel = "SHARE UPDATE EXCLUSIVE"
+	LockShare                LockLevel = "SHARE"
+	LockShareRowExclusive    LockLevel = "SHARE ROW EXCLUSIVE"
+	LockAccessExclusive      LockLevel = "ACCESS EXCLUSIVE"
+)
+
+// Operation is the single DDL statement of migration
+type Operation struct {
+	Kind   OpKind `json:"kind"`
+	Object string `json:"object"` // object type: TABLE, COLUMN, INDEX, ...
+	Target string `json:"target"` // object name as it is written in SQL
+	SQL    string `json:"sql"`
+	// Destructive operation drops data or objects that are not recreated by the plan
+	Destructive bool `json:"destructive,omitempty"`
+	// OutsideTx operation can not be executed inside a transaction block
+	OutsideTx bool      `json:"outsideTx,omitempty"`
+	Lock      LockLevel `json:"lock,omitempty"`
+	// TypeChange is the classification of column type change
+	TypeChange TypeChange `json:"typeChange,omitempty"`
+}
+
+// Plan is the ordered list of migration operations
+type Plan struct {
+	Operations []*Operation `json:"operations"`
+	Warnings   []string     `json:"warnings,omitempty"`
+}
+
+// SQL returns the SQL queries of plan operations
+func (p *Plan) SQL() []string {
+	ret := make([]string, 0, len(p.Operations))
+	for _, op := range p.Operations {
+		ret = append(ret, op.SQL)
+	}
+	return ret
+}
+
+// HasDestructive reports whether the plan contains destructive operations
+func (p *Plan) HasDestructive() bool {
+	for _, op := range p.Operations {
+		if op.Destructive {
+			return true
+		}
+	}
+	return false
+}
+
+// commentOp returns the COMMENT ON operation, empty comment is removed
+func commentOp(objType, name, comment string) *Operation {
+	return &Operation{
+		Kind:   OpComment,
+		Object: objType,
+		Target: name,
+		SQL:    commentDDL(objType, name, comment),
+		Lock:   LockShareUpdateExclusive,
+	}
+}
@@ -11,13 +11,15 @@ import (
 
 // PlantUML struct
 type PlantUML struct {
-	Distance int
+	Distance    int
+	ShowComment bool
 }
 
 // New return PlantUML
 func NewPlantUML(distance int) *PlantUML {
 	return &PlantUML{
-		Distance: distance,
+		Distance:    distance,
+		ShowComment: true,
 	}
 }
 
@@ -36,30 +38,72 @@ skinparam class {
 
 ' tables
 {{- range $i, $t := .Schema.Tables }}
-rectangle "{{ $t.Name }}" {
+{{- $tn := $.Schema.ShortName $t.Namespace $t.Name }}
+rectangle "{{ $tn }}" {
 	{{- if ne $t.Type "VIEW" }}
-	entity {{ $t.Name }} as "{{ $t.Name }}" << (T,#5DBCD2) >> {
+	entity {{ $tn }} as "{{ $tn }}" << (T,#5DBCD2) >> {
 	{{- else }}
-	entity {{ $t.Name }} as "{{ $t.Name }}" << (V,#C6EDDB) >> {
+	entity {{ $tn }} as "{{ $tn }}" << (V,#C6EDDB) >> {
+	{{- end }}
+	{{- if and $.showComment $t.Comment }}
+		<i>{{ $t.Comment | html | nl2br }}</i>
+		--
+	{{- end }}
+	{{- if $t.PartitionBy }}
+		<font color="#666666">PARTITION BY {{ $t.PartitionBy | html }}, {{ len $t.Partitions }} partitions</font>
+		--
 	{{- end }}
 	{{- range $ii, $c := $t.Columns }}
-		{{if $c.PrimaryKey}}* {{end}}{{ $c.Name | html }} <font color="#666666">[{{ $c.Type | html }}]</font>
+		{{if $c.PrimaryKey}}* {{end}}{{ $c.Name | html }} <font color="#666666">[{{ $c.Type | html }}]</font>{{ if and $.showComment $c.Comment }} <i>{{ $c.Comment | html | nl2space }}</i>{{ end }}
+	{{- end }}
+	{{- if or $t.RowSecurity $t.Policies }}
+		--
+		<font color="#666666">ROW LEVEL SECURITY {{ if $t.RowSecurity }}{{ if $t.ForceRowSecurity }}FORCED{{ else }}ENABLED{{ end }}{{ else }}DISABLED{{ end }}</font>
+	{{- end }}
+	{{- range $ii, $p := $t.Policies }}
+		policy {{ $p.Name | html }} <font color="#666666">[{{ if $p.Restrictive }}RESTRICTIVE {{ end }}{{ if $p.Command }}{{ $p.Command }}{{ else }}ALL{{ end }}{{ range $p.Roles }} {{ . | html }}{{ end }}]</font>
 	{{- end }}
 	}
 	{{- range $ii, $c := $t.Indexes }}
 	entity {{ $c.Name }} as "{{ $c.Name }}" << (I,#D25D8A) >> {
+		{{- if and $.showComment $c.Comment }}
+		<i>{{ $c.Comment | html | nl2br }}</i>
+		--
+		{{- end }}
 		{{- range $iii, $cc := $c.Columns }}
 		{{ $cc | html }}
 		{{- end }}
 	}
-	"{{ $c.Name }}" -- "{{ $t.Name }}" : "{{if $c.IsPrimary}}PRIMARY KEY{{else}}{{if $c.IsUnique}}UNIQUE{{end}} {{$c.MethodName}}{{end}} {{if $c.IsClustered}}CLUSTERED{{end}}"
+	"{{ $c.Name }}" -- "{{ $tn }}" : "{{if $c.IsPrimary}}PRIMARY KEY{{else}}{{if $c.IsUnique}}UNIQUE{{end}} {{$c.MethodName}}{{end}} {{if $c.IsClustered}}CLUSTERED{{end}}"
 	{{- end }}
 }
 {{- end }}
 
+' sequences
+{{- range $i, $s := .Schema.Sequences }}
+{{- $sn := $.Schema.ShortName $s.Namespace $s.Name }}
+entity {{ $sn }} as "{{ $sn }}" << (S,#F5B041) >> {
+	{{- if $s.DataType }}
+	{{ $s.DataType | html }}
+	{{- end }}
+	{{- if $s.Start.Valid }}
+	start {{ $s.Start.Int64 }}
+	{{- end }}
+	{{- if $s.Increment }}
+	increment {{ $s.Increment }}
+	{{- end }}
+	{{- if $s.Cycle }}
+	cycle
+	{{- end }}
+}
+{{- with $s.OwnerTable }}
+"{{ $sn }}" .. "{{ $.Schema.ShortName $s.Namespace . }}"
+{{- end }}
+{{- end }}
+
 ' relations
 {{- range $j, $r := .Schema.Relations }}
-"{{ $r.Table.Name }}" }-- "{{ $r.ParentTable.Name }}" : "{{ $r.OnDelete | html }}"
+"{{ $.Schema.ShortName $r.Table.Namespace $r.Table.Name }}" }-- "{{ $.Schema.ShortName $r.ParentTable.Namespace $r.ParentTable.Name }}" : "{{ $r.OnDelete | html }}"
 {{- end }}
 
 @enduml
@@ -80,7 +124,7 @@ func (p *PlantUML) OutputSchema(wr io.Writer, s *Schema) error {
 	tmpl := template.Must(template.New(s.Name).Funcs(Funcs()).Parse(ts))
 	err := tmpl.Execute(wr, map[string]interface{}{
 		"Schema":      s,
-		"showComment": false,
+		"showComment": p.ShowComment,
 	})
 	if err != nil {
 		return errors.WithStack(err)
@@ -0,0 +1,176 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+type PatchPolicy struct {
+	from, to  *Policy
+	tableName string
+}
+
+func (p *PatchPolicy) Operations() []*Operation {
+	if p.from != nil && p.to != nil {
+		return p.alter()
+	}
+	if p.from == nil {
+		return p.create()
+	}
+	return p.drop()
+}
+
+func (p *PatchPolicy) target(pl *Policy) string {
+	return QuoteIdent(pl.Name) + " ON " + p.tableName
+}
+
+func (p *PatchPolicy) create() []*Operation {
+	sb := &strings.Builder{}
+	fmt.Fprintf(sb, "CREATE POLICY %s", p.target(p.to))
+	if p.to.Restrictive {
+		fmt.Fprint(sb, " AS RESTRICTIVE")
+	}
+	if cmd := strings.ToUpper(p.to.Command); cmd != "" && cmd != "ALL" {
+		fmt.Fprint(sb, " FOR ", cmd)
+	}
+	if len(p.to.Roles) > 0 {
+		fmt.Fprint(sb, " TO ", quoteRoles(p.to.Roles))
+	}
+	if p.to.Using != "" {
+		fmt.Fprintf(sb, " USING (%s)", p.to.Using)
+	}
+	if p.to.WithCheck != "" {
+		fmt.Fprintf(sb, " WITH CHECK (%s)", p.to.WithCheck)
+	}
+	ret := []*Operation{{
+		Kind:   OpCreate,
+		Object: "POLICY",
+		Target: p.target(p.to),
+		SQL:    sb.String(),
+		Lock:   LockAccessExclusive,
+	}}
+	if p.to.Comment != "" {
+		ret = append(ret, commentOp("POLICY", p.target(p.to), p.to.Comment))
+	}
+	return ret
+}
+
+// alter changes the roles and expressions of policy, the policy with changed command or kind,
+// or with removed expression can not be altered and is dropped and created again
+func (p *PatchPolicy) alter() []*Operation {
+	from, to := normalizePolicy(p.from), normalizePolicy(p.to)
+	if from.Command != to.Command || from.Restrictive != to.Restrictive ||
+		to.Using == "" && from.Using != "" || to.WithCheck == "" && from.WithCheck != "" {
+		return append(p.dropOp(false), p.create()...)
+	}
+	sb := &strings.Builder{}
+	if strings.Join(from.Roles, ",") != strings.Join(to.Roles, ",") {
+		roles := p.to.Roles
+		if len(roles) == 0 {
+			roles = []string{"public"}
+		}
+		fmt.Fprint(sb, " TO ", quoteRoles(roles))
+	}
+	if from.Using != to.Using {
+		fmt.Fprintf(sb, " USING (%s)", p.to.Using)
+	}
+	if from.WithCheck != to.WithCheck {
+		fmt.Fprintf(sb, " WITH CHECK (%s)", p.to.WithCheck)
+	}
+	ret := []*Operation{}
+	if sb.Len() > 0 {
+		ret = append(ret, &Operation{
+			Kind:   OpAlter,
+			Object: "POLICY",
+			Target: p.target(p.to),
+			SQL:    "ALTER POLICY " + p.target(p.to) + sb.String(),
+			Lock:   LockAccessExclusive,
+		})
+	}
+	if p.from.Comment != p.to.Comment {
+		ret = append(ret, commentOp("POLICY", p.target(p.to), p.to.Comment))
+	}
+	return ret
+}
+
+// drop drops the policy that is not declared, removed policy opens the rows
+// it restricted, so the drop is destructive and is disabled by PatchDropDisable
+func (p *PatchPolicy) drop() []*Operation {
+	if PatchDropDisable {
+		return nil
+	}
+	return p.dropOp(true)
+}
+
+func (p *PatchPolicy) dropOp(destructive bool) []*Operation {
+	return []*Operation{{
+		Kind:        OpDrop,
+		Object:      "POLICY",
+		Target:      p.target(p.from),
+		SQL:         "DROP POLICY IF EXISTS " + p.target(p.from),
+		Destructive: destructive,
+		Lock:        LockAccessExclusive,
+	}}
+}
+
+// normalizePolicy returns the copy of policy in canonical form for comparison,
+// the comment is not compared
+func normalizePolicy(p *Policy) *Policy {
+	ret := *p
+	ret.Command = strings.ToUpper(p.Command)
+	if ret.Command == "" {
+		ret.Command = "ALL"
+	}
+	ret.Roles = make([]string, 0, len(p.Roles))
+	for _, r := range p.Roles {
+		if r = strings.ToLower(r); r != "public" {
+			ret.Roles = append(ret.Roles, r)
+		}
+	}
+	sort.Strings(ret.Roles)
+	ret.Using = NormalizeExpr(p.Using)
+	ret.WithCheck = NormalizeExpr(p.WithCheck)
+	ret.Comment = ""
+	return &ret
+}
+
+// rowSecurity enables or disables row level security of table, disabling opens
+// the rows to all users, so it is destructive and is disabled by PatchDropDisable
+func (t *PatchTable) rowSecurity() []*Operation {
+	ret := []*Operation{}
+	name := quotedName(t.to.Namespace, t.to.Name)
+	alter := func(action string) {
+		disable := strings.HasPrefix(action, "DISABLE") || strings.HasPrefix(action, "NO ")
+		if disable && PatchDropDisable {
+			return
+		}
+		ret = append(ret, &Operation{
+			Kind:        OpAlter,
+			Object:      "TABLE",
+			Target:      name,
+			SQL:         fmt.Sprintf("ALTER TABLE %s %s ROW LEVEL SECURITY", name, action),
+			Destructive: disable,
+			Lock:        LockAccessExclusive,
+		})
+	}
+	enabled, forced := false, false
+	if t.from != nil {
+		enabled, forced = t.from.RowSecurity, t.from.ForceRowSecurity
+	}
+	if enabled != t.to.RowSecurity {
+		if t.to.RowSecurity {
+			alter("ENABLE")
+		} else {
+			alter("DISABLE")
+		}
+	}
+	if forced != t.to.ForceRowSecurity {
+		if t.to.ForceRowSecurity {
+			alter("FORCE")
+		} else {
+			alter("NO FORCE")
+		}
+	}
+	return ret
+}
@@ -0,0 +1,180 @@
+package schema
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// renameTarget returns the index of target object that is renamed from the source name,
+// or of the object with the same name that is not renamed from other source object, -1 if not found.
+// Source objects are checked by exists.
+func renameTarget(name string, names, renamedFrom []string, exists func(name string) bool) int {
+	for i := range names {
+		if renamedFrom[i] == name {
+			return i
+		}
+	}
+	for i := range names {
+		if names[i] == name && (renamedFrom[i] == "" || !exists(renamedFrom[i])) {
+			return i
+		}
+	}
+	return -1
+}
+
+func findRenamedTable(from, to *Schema, t *Table) *Table {
+	names, renamedFrom := []string{}, []string{}
+	tables := []*Table{}
+	for _, rt := range to.Tables {
+		if rt.Namespace == t.Namespace {
+			names = append(names, rt.Name)
+			renamedFrom = append(renamedFrom, rt.RenamedFrom)
+			tables = append(tables, rt)
+		}
+	}
+	i := renameTarget(t.Name, names, renamedFrom, func(name string) bool {
+		_, err := from.FindTable(t.Namespace, name)
+		return err == nil
+	})
+	if i < 0 {
+		return nil
+	}
+	return tables[i]
+}
+
+func findRenamedColumn(from, to *Table, c *Column) *Column {
+	names, renamedFrom := make([]string, len(to.Columns)), make([]string, len(to.Columns))
+	for i, rc := range to.Columns {
+		names[i], renamedFrom[i] = rc.Name, rc.RenamedFrom
+	}
+	i := renameTarget(c.Name, names, renamedFrom, func(name string) bool {
+		_, err := from.FindColumnByName(name)
+		return err == nil
+	})
+	if i < 0 {
+		return nil
+	}
+	return to.Columns[i]
+}
+
+func findRenamedIndex(from, to *Table, idx *Index) *Index {
+	names, renamedFrom := make([]string, len(to.Indexes)), make([]string, len(to.Indexes))
+	for i, ri := range to.Indexes {
+		names[i], renamedFrom[i] = ri.Name, ri.RenamedFrom
+	}
+	i := renameTarget(idx.Name, names, renamedFrom, func(name string) bool {
+		_, err := from.FindIndexByName(name)
+		return err == nil
+	})
+	if i < 0 {
+		return nil
+	}
+	return to.Indexes[i]
+}
+
+func findRenamedConstraint(from, to *Table, c *Constraint) *Constraint {
+	names, renamedFrom := make([]string, len(to.Constraints)), make([]string, len(to.Constraints))
+	for i, rc := range to.Constraints {
+		names[i], renamedFrom[i] = rc.Name, rc.RenamedFrom
+	}
+	i := renameTarget(c.Name, names, renamedFrom, func(name string) bool {
+		_, err := from.FindConstraintByName(name)
+		return err == nil
+	})
+	if i < 0 {
+		return nil
+	}
+	return to.Constraints[i]
+}
+
+// renameOp returns the operation that renames the object by the rename clause,
+// the new name is not qualified because objects are renamed within the namespace
+func renameOp(object, rename, target, newName string, lock LockLevel) *Operation {
+	return &Operation{
+		Kind:   OpAlter,
+		Object: object,
+		Target: target,
+		SQL:    fmt.Sprintf("%s TO %s", rename, QuoteIdent(newName)),
+		Lock:   lock,
+	}
+}
+
+// renameColumns maps the column names to the new names of renamed columns
+func renameColumns(names []string, renamed map[string]string) []string {
+	if len(renamed) == 0 {
+		return names
+	}
+	ret := make([]string, len(names))
+	for i, n := range names {
+		if nn, ok := renamed[n]; ok {
+			n = nn
+		}
+		ret[i] = n
+	}
+	return ret
+}
+
+// probableRenames describes dropped and added objects that are probably renamed:
+// the single dropped and the single added column of the same type,
+// and the dropped and created tables with the same columns
+func (s *PatchSchema) probableRenames() []string {
+	ret := []string{}
+	dropped, created := map[string][]*Table{}, map[string][]*Table{}
+	for _, pt := range s.tables {
+		switch {
+		case pt.to == nil:
+			dropped[tableSignature(pt.from)] = append(dropped[tableSignature(pt.from)], pt.from)
+		case pt.from == nil:
+			created[tableSignature(pt.to)] = append(created[tableSignature(pt.to)], pt.to)
+		default:
+			ret = append(ret, probableColumnRenames(pt)...)
+		}
+	}
+	for sig, ts := range dropped {
+		cs := created[sig]
+		if len(ts) == 1 && len(cs) == 1 {
+			ret = append(ret, fmt.Sprintf("table %s is dropped and table %s with the same columns is created, set renamedFrom: %s if it is renamed",
+				ts[0].FullName(), cs[0].FullName(), ts[0].Name))
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+func probableColumnRenames(pt *PatchTable) []string {
+	dropped, added := map[string][]*Column{}, map[string][]*Column{}
+	for _, pc := range pt.columns {
+		switch {
+		case pc.to == nil:
+			t := normalizeColumn(pc.from).Type
+			dropped[t] = append(dropped[t], pc.from)
+		case pc.from == nil:
+			t := normalizeColumn(pc.to).Type
+			added[t] = append(added[t], pc.to)
+		}
+	}
+	ret := []string{}
+	for t, cs := range dropped {
+		as := added[t]
+		if len(cs) == 1 && len(as) == 1 {
+			ret = append(ret, fmt.Sprintf("table %s: column %s is dropped and column %s of the same type is added, set renamedFrom: %s if it is renamed",
+				pt.to.FullName(), cs[0].Name, as[0].Name, cs[0].Name))
+		}
+	}
+	return ret
+}
+
+// tableSignature returns the namespace, type and columns of table
+func tableSignature(t *Table) string {
+	cols := make([]string, 0, len(t.Columns))
+	for _, c := range t.Columns {
+		cols = append(cols, c.Name+" "+normalizeColumn(c).Type)
+	}
+	sort.Strings(cols)
+	tp := t.Type
+	if tp == "" {
+		tp = "TABLE"
+	}
+	return t.Namespace + "\n" + tp + "\n" + strings.Join(cols, "\n")
+}
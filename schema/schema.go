@@ -3,6 +3,8 @@ package schema
 import (
 	"database/sql"
 	"fmt"
+	"math"
+	"regexp"
 	"sort"
 	"strings"
 
@@ -15,15 +17,44 @@ const (
 	TypeUQ = "UNIQUE"
 )
 
+const (
+	IdentityAlways    = "ALWAYS"
+	IdentityByDefault = "BY DEFAULT"
+)
+
 // Table is the struct for database table
 type Table struct {
+	Namespace   string        `json:"namespace"`
 	Name        string        `json:"name"`
 	Type        string        `json:"type"`
 	Comment     string        `json:"comment"`
 	Columns     []*Column     `json:"columns"`
 	Indexes     []*Index      `json:"indexes"`
 	Constraints []*Constraint `json:"constraints"`
+	Triggers    []*Trigger    `json:"triggers,omitempty"`
 	Def         string        `json:"def"`
+	DependsOn   []string      `json:"dependsOn,omitempty"` // full names of tables used by view
+	// DependsOnColumns are the columns used by view by the full names of tables,
+	// the view uses all columns of the table that is not listed here
+	DependsOnColumns map[string][]string `json:"dependsOnColumns,omitempty"`
+	RenamedFrom      string              `json:"renamedFrom,omitempty"` // previous name in the same namespace
+	// PartitionBy is the partition key of partitioned table, e.g. RANGE (created_at)
+	PartitionBy string       `json:"partitionBy,omitempty"`
+	Partitions  []*Partition `json:"partitions,omitempty"`
+	// PartitionRules generate the partitions of table
+	PartitionRules []*PartitionRule `json:"partitionRules,omitempty"`
+	// RowSecurity enables row level security policies of table, ForceRowSecurity applies them to the table owner
+	RowSecurity      bool      `json:"rowSecurity,omitempty"`
+	ForceRowSecurity bool      `json:"forceRowSecurity,omitempty"`
+	Policies         []*Policy `json:"policies,omitempty"`
+	// Owner and Grants are not changed when they are not declared
+	Owner  string   `json:"owner,omitempty"`
+	Grants []*Grant `json:"grants,omitempty"`
+}
+
+// FullName returns schema-qualified table name
+func (t *Table) FullName() string {
+	return QualifiedName(t.Namespace, t.Name)
 }
 
 func (t *Table) Validate() error {
@@ -48,9 +79,56 @@ func (t *Table) Validate() error {
 			return err
 		}
 	}
+	for _, tr := range t.Triggers {
+		if err := tr.Validate(); err != nil {
+			return fmt.Errorf("trigger %q: %w", tr.Name, err)
+		}
+	}
+	if t.PartitionBy == "" && (len(t.Partitions) > 0 || len(t.PartitionRules) > 0) {
+		return fmt.Errorf("table %s: partitions of not partitioned table", t.Name)
+	}
+	for _, p := range t.Partitions {
+		if p.Name == "" || p.Bound == "" {
+			return fmt.Errorf("table %s: partition name or bound not defined", t.Name)
+		}
+	}
+	for _, pr := range t.PartitionRules {
+		if err := pr.Validate(); err != nil {
+			return fmt.Errorf("table %s: %w", t.Name, err)
+		}
+	}
+	for _, p := range t.Policies {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("policy %q: %w", p.Name, err)
+		}
+	}
+	for _, g := range t.Grants {
+		if err := g.Validate(); err != nil {
+			return fmt.Errorf("table %s: %w", t.Name, err)
+		}
+	}
 	return nil
 }
 
+// Partition is the partition of partitioned table in the namespace of table,
+// the partitions of partition are not supported
+type Partition struct {
+	Name string `json:"name"`
+	// Bound is the partition bound, e.g. FOR VALUES FROM ('2024-01-01') TO ('2024-02-01') or DEFAULT
+	Bound     string `json:"bound"`
+	generated bool   // generated by partition rule
+}
+
+// PartitionRule generates range partitions of table for each interval from the start date to the end date,
+// the partitions are named by table name and the start of interval
+type PartitionRule struct {
+	Interval string `json:"interval"` // day, week, month or year
+	From     string `json:"from"`     // start date, e.g. 2024-01-01
+	To       string `json:"to"`       // end date, exclusive
+	// Format is the layout of partition name suffix, the default depends on interval, e.g. 2006_01 for month
+	Format string `json:"format,omitempty"`
+}
+
 // Index is the struct for database index
 type Index struct {
 	Name         string `json:"name"`
@@ -67,6 +145,7 @@ type Index struct {
 	Tablespace   string   `json:"tablespace,omitempty"`
 	Where        string   `json:"where,omitempty"`
 	Comment      string   `json:"comment"`
+	RenamedFrom  string   `json:"renamedFrom,omitempty"`
 }
 
 func (idx *Index) Validate() error {
@@ -94,6 +173,13 @@ type Constraint struct {
 	Columns          []string `json:"columns"`
 	ReferenceColumns []string `json:"reference_columns" yaml:"referenceColumns"`
 	Comment          string   `json:"comment"`
+	RenamedFrom      string   `json:"renamedFrom,omitempty"`
+	// foreign key options, see Relation
+	OnUpdate          string `json:"onUpdate,omitempty"`
+	Match             string `json:"match,omitempty"`
+	Deferrable        bool   `json:"deferrable,omitempty"`
+	InitiallyDeferred bool   `json:"initiallyDeferred,omitempty"`
+	NotValid          bool   `json:"notValid,omitempty"`
 }
 
 func (c *Constraint) Validate() error {
@@ -128,7 +214,11 @@ type Column struct {
 	Nullable        bool           `json:"nullable"`
 	PrimaryKey      bool           `json:"pk"`
 	Default         sql.NullString `json:"default"`
+	Identity        *Identity      `json:"identity,omitempty"`
+	Generated       string         `json:"generated,omitempty"` // stored generated expression
+	Using           string         `json:"using,omitempty"`     // conversion expression for type change
 	Comment         string         `json:"comment"`
+	RenamedFrom     string         `json:"renamedFrom,omitempty"`
 	ParentRelations []*Relation    `json:"-"`
 	ChildRelations  []*Relation    `json:"-"`
 }
@@ -140,6 +230,51 @@ func (c *Column) Validate() error {
 	if c.Type == "" {
 		return fmt.Errorf("column type not defined")
 	}
+	if c.Identity != nil {
+		if err := c.Identity.Validate(); err != nil {
+			return fmt.Errorf("column %q: %w", c.Name, err)
+		}
+		if c.Generated != "" {
+			return fmt.Errorf("column %q is both identity and generated", c.Name)
+		}
+	}
+	if c.Default.Valid && (c.Identity != nil || c.Generated != "") {
+		return fmt.Errorf("column %q default is not allowed for identity or generated column", c.Name)
+	}
+	return nil
+}
+
+// IdentitySequence returns the identity sequence options filled by defaults
+// according to the column type
+func (c *Column) IdentitySequence() *Sequence {
+	sq := &Sequence{}
+	if c.Identity != nil && c.Identity.Sequence != nil {
+		*sq = *c.Identity.Sequence
+	}
+	if sq.DataType == "" {
+		switch strings.ToLower(c.Type) {
+		case "smallint", "int2":
+			sq.DataType = "smallint"
+		case "integer", "int", "int4":
+			sq.DataType = "integer"
+		}
+	}
+	return sq.WithDefaults()
+}
+
+// Identity is the struct for identity column
+type Identity struct {
+	Generation string    `json:"generation"`         // ALWAYS or BY DEFAULT
+	Sequence   *Sequence `json:"sequence,omitempty"` // sequence options, nil for defaults
+}
+
+func (idn *Identity) Validate() error {
+	if idn.Generation != IdentityAlways && idn.Generation != IdentityByDefault {
+		return fmt.Errorf("identity generation must be %s or %s", IdentityAlways, IdentityByDefault)
+	}
+	if idn.Sequence != nil {
+		return idn.Sequence.validateOptions()
+	}
 	return nil
 }
 
@@ -151,7 +286,16 @@ type Relation struct {
 	ParentTable   *Table    `json:"parent_table" yaml:"parentTable"`
 	ParentColumns []*Column `json:"parent_columns" yaml:"parentColumns"`
 	OnDelete      string    `json:"onDelete"`
-	Def           string    `json:"def"`
+	OnUpdate      string    `json:"onUpdate"`
+	// Match is the match type of foreign key: FULL or PARTIAL, empty is the default SIMPLE
+	Match             string `json:"match"`
+	Deferrable        bool   `json:"deferrable"`
+	InitiallyDeferred bool   `json:"initiallyDeferred"`
+	// NotValid foreign key is not checked for existing rows
+	NotValid    bool   `json:"notValid"`
+	Def         string `json:"def"`
+	Comment     string `json:"comment"`
+	RenamedFrom string `json:"renamedFrom,omitempty"`
 }
 
 func (r *Relation) Validate() error {
@@ -170,23 +314,510 @@ func (r *Relation) Validate() error {
 	if len(r.ParentColumns) == 0 {
 		return fmt.Errorf("relation parent columns not defined")
 	}
+	switch fkMatch(r.Match) {
+	case "", "FULL", "PARTIAL":
+	default:
+		return fmt.Errorf("relation match type must be SIMPLE, FULL or PARTIAL")
+	}
+	return nil
+}
+
+// Enum is the struct for database enum type
+type Enum struct {
+	Namespace string   `json:"namespace"`
+	Name      string   `json:"name"`
+	Values    []string `json:"values"`
+	Comment   string   `json:"comment"`
+	// RenamedValues are the previous values of renamed values by the new values,
+	// the values without hint are added and never renamed
+	RenamedValues map[string]string `json:"renamedValues,omitempty"`
+}
+
+// FullName returns schema-qualified enum name
+func (e *Enum) FullName() string {
+	return QualifiedName(e.Namespace, e.Name)
+}
+
+func (e *Enum) Validate() error {
+	if e.Name == "" {
+		return fmt.Errorf("enum name not defined")
+	}
+	if len(e.Values) == 0 {
+		return fmt.Errorf("enum values not defined")
+	}
+	for i, v := range e.Values {
+		for _, vv := range e.Values[:i] {
+			if v == vv {
+				return fmt.Errorf("enum value %q is duplicated", v)
+			}
+		}
+	}
+	return nil
+}
+
+// Domain is the struct for database domain type
+type Domain struct {
+	Namespace string         `json:"namespace"`
+	Name      string         `json:"name"`
+	BaseType  string         `json:"baseType"`
+	NotNull   bool           `json:"notNull,omitempty"`
+	Default   sql.NullString `json:"default"`
+	Checks    []*DomainCheck `json:"checks,omitempty"`
+	Comment   string         `json:"comment"`
+}
+
+// DomainCheck is the named CHECK constraint of domain
+type DomainCheck struct {
+	Name string `json:"name"`
+	Expr string `json:"expr"` // boolean expression of VALUE
+}
+
+// FullName returns schema-qualified domain name
+func (d *Domain) FullName() string {
+	return QualifiedName(d.Namespace, d.Name)
+}
+
+func (d *Domain) Validate() error {
+	if d.Name == "" {
+		return fmt.Errorf("domain name not defined")
+	}
+	if d.BaseType == "" {
+		return fmt.Errorf("domain base type not defined")
+	}
+	for i, c := range d.Checks {
+		if c.Name == "" || c.Expr == "" {
+			return fmt.Errorf("domain check name or expression not defined")
+		}
+		for _, cc := range d.Checks[:i] {
+			if c.Name == cc.Name {
+				return fmt.Errorf("domain check %q is duplicated", c.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// FindCheckByName find domain check constraint by name
+func (d *Domain) FindCheckByName(name string) (*DomainCheck, error) {
+	for _, c := range d.Checks {
+		if c.Name == name {
+			return c, nil
+		}
+	}
+	return nil, errors.WithStack(fmt.Errorf("not found check '%s' of domain '%s'", name, d.Name))
+}
+
+// CompositeType is the struct for database composite type
+type CompositeType struct {
+	Namespace  string       `json:"namespace"`
+	Name       string       `json:"name"`
+	Attributes []*Attribute `json:"attributes"`
+	Comment    string       `json:"comment"`
+}
+
+// Attribute is the attribute of composite type
+type Attribute struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// FullName returns schema-qualified composite type name
+func (ct *CompositeType) FullName() string {
+	return QualifiedName(ct.Namespace, ct.Name)
+}
+
+func (ct *CompositeType) Validate() error {
+	if ct.Name == "" {
+		return fmt.Errorf("composite type name not defined")
+	}
+	if len(ct.Attributes) == 0 {
+		return fmt.Errorf("composite type attributes not defined")
+	}
+	for i, a := range ct.Attributes {
+		if a.Name == "" || a.Type == "" {
+			return fmt.Errorf("attribute name or type not defined")
+		}
+		for _, aa := range ct.Attributes[:i] {
+			if a.Name == aa.Name {
+				return fmt.Errorf("attribute %q is duplicated", a.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// FindAttributeByName find composite type attribute by name
+func (ct *CompositeType) FindAttributeByName(name string) (*Attribute, error) {
+	for _, a := range ct.Attributes {
+		if a.Name == name {
+			return a, nil
+		}
+	}
+	return nil, errors.WithStack(fmt.Errorf("not found attribute '%s' of type '%s'", name, ct.Name))
+}
+
+// Sequence is the struct for database sequence
+type Sequence struct {
+	Namespace string        `json:"namespace"`
+	Name      string        `json:"name"`
+	DataType  string        `json:"dataType,omitempty"`
+	Start     sql.NullInt64 `json:"start"`
+	Increment int64         `json:"increment,omitempty"`
+	MinValue  sql.NullInt64 `json:"minValue"`
+	MaxValue  sql.NullInt64 `json:"maxValue"`
+	Cycle     bool          `json:"cycle,omitempty"`
+	Cache     int64         `json:"cache,omitempty"`
+	OwnedBy   string        `json:"ownedBy,omitempty"` // table.column, the table is in the sequence namespace
+	Comment   string        `json:"comment"`
+	Owner     string        `json:"owner,omitempty"`
+	Grants    []*Grant      `json:"grants,omitempty"`
+}
+
+// FullName returns schema-qualified sequence name
+func (sq *Sequence) FullName() string {
+	return QualifiedName(sq.Namespace, sq.Name)
+}
+
+func (sq *Sequence) Validate() error {
+	if sq.Name == "" {
+		return fmt.Errorf("sequence name not defined")
+	}
+	for _, g := range sq.Grants {
+		if err := g.Validate(); err != nil {
+			return fmt.Errorf("sequence %s: %w", sq.Name, err)
+		}
+	}
+	return sq.validateOptions()
+}
+
+func (sq *Sequence) validateOptions() error {
+	switch sq.DataType {
+	case "", "smallint", "integer", "bigint":
+	default:
+		return fmt.Errorf("sequence data type %q not supported", sq.DataType)
+	}
+	if sq.Cache < 0 {
+		return fmt.Errorf("sequence cache must be positive")
+	}
+	if sq.MinValue.Valid && sq.MaxValue.Valid && sq.MinValue.Int64 > sq.MaxValue.Int64 {
+		return fmt.Errorf("sequence min value greater than max value")
+	}
+	if sq.OwnedBy != "" && !strings.Contains(sq.OwnedBy, ".") {
+		return fmt.Errorf("sequence owner must be defined as table.column")
+	}
+	return nil
+}
+
+// OwnerTable returns the name of the table that owns the sequence
+func (sq *Sequence) OwnerTable() string {
+	i := strings.LastIndex(sq.OwnedBy, ".")
+	if i < 0 {
+		return ""
+	}
+	return sq.OwnedBy[:i]
+}
+
+// OwnerColumn returns the name of the column that owns the sequence
+func (sq *Sequence) OwnerColumn() string {
+	i := strings.LastIndex(sq.OwnedBy, ".")
+	if i < 0 {
+		return ""
+	}
+	return sq.OwnedBy[i+1:]
+}
+
+// WithDefaults returns a copy of sequence with omitted options
+// filled by PostgreSQL defaults
+func (sq *Sequence) WithDefaults() *Sequence {
+	ret := *sq
+	if ret.DataType == "" {
+		ret.DataType = "bigint"
+	}
+	if ret.Increment == 0 {
+		ret.Increment = 1
+	}
+	if ret.Cache == 0 {
+		ret.Cache = 1
+	}
+	var tmin, tmax int64
+	switch ret.DataType {
+	case "smallint":
+		tmin, tmax = math.MinInt16, math.MaxInt16
+	case "integer":
+		tmin, tmax = math.MinInt32, math.MaxInt32
+	default:
+		tmin, tmax = math.MinInt64, math.MaxInt64
+	}
+	if !ret.MinValue.Valid {
+		ret.MinValue = sql.NullInt64{Int64: tmin, Valid: true}
+		if ret.Increment > 0 {
+			ret.MinValue.Int64 = 1
+		}
+	}
+	if !ret.MaxValue.Valid {
+		ret.MaxValue = sql.NullInt64{Int64: tmax, Valid: true}
+		if ret.Increment < 0 {
+			ret.MaxValue.Int64 = -1
+		}
+	}
+	if !ret.Start.Valid {
+		ret.Start = ret.MinValue
+		if ret.Increment < 0 {
+			ret.Start = ret.MaxValue
+		}
+	}
+	return &ret
+}
+
+// Function is the struct for database function
+type Function struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	// Arguments is the argument list without defaults, e.g. "a integer, b text"
+	Arguments string `json:"arguments"`
+	Returns   string `json:"returns"`
+	Language  string `json:"language"`
+	// Volatility is IMMUTABLE or STABLE, empty is the default VOLATILE
+	Volatility      string `json:"volatility,omitempty"`
+	SecurityDefiner bool   `json:"securityDefiner,omitempty"`
+	Strict          bool   `json:"strict,omitempty"`    // returns null on null arguments
+	Leakproof       bool   `json:"leakproof,omitempty"` // reveals no information about arguments
+	// Parallel is SAFE or RESTRICTED, empty is the default UNSAFE
+	Parallel string `json:"parallel,omitempty"`
+	// Cost is the estimated execution cost, zero is the default of language
+	Cost float64 `json:"cost,omitempty"`
+	// Config are the parameters set on function call, e.g. search_path = public
+	Config  []string `json:"config,omitempty"`
+	Body    string   `json:"body"`
+	Comment string   `json:"comment"`
+}
+
+// FullName returns schema-qualified function name
+func (f *Function) FullName() string {
+	return QualifiedName(f.Namespace, f.Name)
+}
+
+// Signature returns schema-qualified function name with arguments
+func (f *Function) Signature() string {
+	return f.FullName() + "(" + f.Arguments + ")"
+}
+
+func (f *Function) Validate() error {
+	if f.Name == "" {
+		return fmt.Errorf("function name not defined")
+	}
+	if f.Returns == "" {
+		return fmt.Errorf("function result type not defined")
+	}
+	if f.Language == "" {
+		return fmt.Errorf("function language not defined")
+	}
+	switch strings.ToUpper(f.Volatility) {
+	case "", "VOLATILE", "STABLE", "IMMUTABLE":
+	default:
+		return fmt.Errorf("function volatility %q not supported", f.Volatility)
+	}
+	switch strings.ToUpper(f.Parallel) {
+	case "", "UNSAFE", "RESTRICTED", "SAFE":
+	default:
+		return fmt.Errorf("function parallel mode %q not supported", f.Parallel)
+	}
+	return nil
+}
+
+// Trigger is the struct for table trigger
+type Trigger struct {
+	Name string `json:"name"`
+	// Timing is BEFORE, AFTER or INSTEAD OF
+	Timing string `json:"timing"`
+	// Events are INSERT, UPDATE, UPDATE OF columns, DELETE or TRUNCATE
+	Events []string `json:"events"`
+	// ForEach is ROW or STATEMENT
+	ForEach string `json:"forEach"`
+	When    string `json:"when,omitempty"`
+	// Function is the called function with arguments, e.g. set_updated_at()
+	Function string `json:"function"`
+	// Constraint trigger is created by CREATE CONSTRAINT TRIGGER, it may reference
+	// the From table and be deferred
+	Constraint        bool   `json:"constraint,omitempty"`
+	From              string `json:"from,omitempty"`
+	Deferrable        bool   `json:"deferrable,omitempty"`
+	InitiallyDeferred bool   `json:"initiallyDeferred,omitempty"`
+	// OldTable and NewTable are the transition relations of REFERENCING clause
+	OldTable string `json:"oldTable,omitempty"`
+	NewTable string `json:"newTable,omitempty"`
+	// Def is the definition of trigger that is not recognized by parts, it is created as it is
+	Def     string `json:"def,omitempty"`
+	Comment string `json:"comment"`
+}
+
+func (tr *Trigger) Validate() error {
+	if tr.Name == "" {
+		return fmt.Errorf("trigger name not defined")
+	}
+	if tr.Def != "" {
+		return nil
+	}
+	switch strings.ToUpper(tr.Timing) {
+	case "BEFORE", "AFTER", "INSTEAD OF":
+	default:
+		return fmt.Errorf("trigger timing %q not supported", tr.Timing)
+	}
+	if len(tr.Events) == 0 {
+		return fmt.Errorf("trigger events not defined")
+	}
+	switch strings.ToUpper(tr.ForEach) {
+	case "", "ROW", "STATEMENT":
+	default:
+		return fmt.Errorf("trigger level %q not supported", tr.ForEach)
+	}
+	if tr.Function == "" {
+		return fmt.Errorf("trigger function not defined")
+	}
+	return nil
+}
+
+// Policy is the struct for row level security policy of table
+type Policy struct {
+	Name string `json:"name"`
+	// Command is ALL, SELECT, INSERT, UPDATE or DELETE
+	Command string `json:"command"`
+	// Roles are the roles the policy applies to, no roles means PUBLIC
+	Roles []string `json:"roles,omitempty"`
+	// Using is the expression for the existing rows
+	Using string `json:"using,omitempty"`
+	// WithCheck is the expression for the inserted and updated rows
+	WithCheck   string `json:"withCheck,omitempty"`
+	Restrictive bool   `json:"restrictive,omitempty"`
+	Comment     string `json:"comment"`
+}
+
+func (p *Policy) Validate() error {
+	if p.Name == "" {
+		return fmt.Errorf("policy name not defined")
+	}
+	switch strings.ToUpper(p.Command) {
+	case "", "ALL", "SELECT", "INSERT", "UPDATE", "DELETE":
+	default:
+		return fmt.Errorf("policy command %q not supported", p.Command)
+	}
+	if p.Using == "" && p.WithCheck == "" {
+		return fmt.Errorf("policy expression not defined")
+	}
+	return nil
+}
+
+// Extension is the struct for database extension
+type Extension struct {
+	Name string `json:"name"`
+	// Version is the installed version, empty is the default version of extension
+	Version string `json:"version,omitempty"`
+	// Namespace contains the objects of extension, empty is the current schema
+	Namespace string `json:"namespace,omitempty"`
+}
+
+func (e *Extension) Validate() error {
+	if e.Name == "" {
+		return fmt.Errorf("extension name not defined")
+	}
+	return nil
+}
+
+// Namespace is the struct for database schema (namespace)
+type Namespace struct {
+	Name    string   `json:"name"`
+	Comment string   `json:"comment"`
+	Owner   string   `json:"owner,omitempty"`
+	Grants  []*Grant `json:"grants,omitempty"`
+}
+
+func (n *Namespace) Validate() error {
+	if n.Name == "" {
+		return fmt.Errorf("namespace name not defined")
+	}
+	for _, g := range n.Grants {
+		if err := g.Validate(); err != nil {
+			return fmt.Errorf("namespace %s: %w", n.Name, err)
+		}
+	}
+	return nil
+}
+
+// Grant is the privileges of role on table, view, sequence or namespace
+type Grant struct {
+	Role string `json:"role"` // PUBLIC grants to all roles
+	// Privileges are e.g. SELECT, ALL or UPDATE (name, email) for the columns of table
+	Privileges []string `json:"privileges"`
+}
+
+func (g *Grant) Validate() error {
+	if g.Role == "" {
+		return fmt.Errorf("grant role not defined")
+	}
+	if len(g.Privileges) == 0 {
+		return fmt.Errorf("grant privileges of role %q not defined", g.Role)
+	}
 	return nil
 }
 
 // Schema is the struct for database schema
 type Schema struct {
-	Name          string      `json:"name"`
-	Desc          string      `json:"desc"`
-	Tables        []*Table    `json:"tables"`
-	Relations     []*Relation `json:"relations"`
-	CurrentSchema string      `json:"currentSchema"`
-	SearchPaths   []string    `json:"searchPaths,omitempty"`
+	Name          string           `json:"name"`
+	Desc          string           `json:"desc"`
+	Namespaces    []*Namespace     `json:"namespaces,omitempty"`
+	Extensions    []*Extension     `json:"extensions,omitempty"`
+	Tables        []*Table         `json:"tables"`
+	Relations     []*Relation      `json:"relations"`
+	Enums         []*Enum          `json:"enums,omitempty"`
+	Domains       []*Domain        `json:"domains,omitempty"`
+	Composites    []*CompositeType `json:"composites,omitempty"`
+	Sequences     []*Sequence      `json:"sequences,omitempty"`
+	Functions     []*Function      `json:"functions,omitempty"`
+	CurrentSchema string           `json:"currentSchema"`
+	SearchPaths   []string         `json:"searchPaths,omitempty"`
+	// Roles are the existing roles of inspected database, the roles are not migrated
+	Roles []string `json:"roles,omitempty"`
 }
 
 func (s *Schema) Validate() error {
+	for _, n := range s.Namespaces {
+		if err := n.Validate(); err != nil {
+			return err
+		}
+	}
+	for _, e := range s.Extensions {
+		if err := e.Validate(); err != nil {
+			return fmt.Errorf("extension %q validation error: %w", e.Name, err)
+		}
+	}
+	for _, e := range s.Enums {
+		if err := e.Validate(); err != nil {
+			return fmt.Errorf("enum %q validation error: %w", e.FullName(), err)
+		}
+	}
+	for _, d := range s.Domains {
+		if err := d.Validate(); err != nil {
+			return fmt.Errorf("domain %q validation error: %w", d.FullName(), err)
+		}
+	}
+	for _, ct := range s.Composites {
+		if err := ct.Validate(); err != nil {
+			return fmt.Errorf("composite type %q validation error: %w", ct.FullName(), err)
+		}
+	}
+	for _, sq := range s.Sequences {
+		if err := sq.Validate(); err != nil {
+			return fmt.Errorf("sequence %q validation error: %w", sq.FullName(), err)
+		}
+	}
+	for _, f := range s.Functions {
+		if err := f.Validate(); err != nil {
+			return fmt.Errorf("function %q validation error: %w", f.Signature(), err)
+		}
+	}
 	for _, t := range s.Tables {
 		if err := t.Validate(); err != nil {
-			return fmt.Errorf("table %q validation error: %w", t.Name, err)
+			return fmt.Errorf("table %q validation error: %w", t.FullName(), err)
 		}
 	}
 	for _, r := range s.Relations {
@@ -197,35 +828,462 @@ func (s *Schema) Validate() error {
 	return nil
 }
 
-func (s *Schema) NormalizeTableName(name string) string {
-	if !strings.Contains(name, ".") {
-		return fmt.Sprintf("%s.%s", s.CurrentSchema, name)
+// NormalizeNamespaces sets the current schema namespace to objects without namespace
+func (s *Schema) NormalizeNamespaces() {
+	if s.CurrentSchema == "" {
+		s.CurrentSchema = "public"
+	}
+	for _, t := range s.Tables {
+		if t.Namespace == "" {
+			t.Namespace = s.CurrentSchema
+		}
+	}
+	for _, e := range s.Enums {
+		if e.Namespace == "" {
+			e.Namespace = s.CurrentSchema
+		}
+	}
+	for _, d := range s.Domains {
+		if d.Namespace == "" {
+			d.Namespace = s.CurrentSchema
+		}
+	}
+	for _, ct := range s.Composites {
+		if ct.Namespace == "" {
+			ct.Namespace = s.CurrentSchema
+		}
+	}
+	for _, sq := range s.Sequences {
+		if sq.Namespace == "" {
+			sq.Namespace = s.CurrentSchema
+		}
+	}
+	for _, f := range s.Functions {
+		if f.Namespace == "" {
+			f.Namespace = s.CurrentSchema
+		}
 	}
-	return name
 }
 
-func (s *Schema) NormalizeTableNames(names []string) []string {
+// NamespaceNames returns names of declared namespaces and namespaces used by schema objects
+func (s *Schema) NamespaceNames() []string {
+	ret := []string{}
+	seen := map[string]bool{"": true}
+	add := func(ns string) {
+		if !seen[ns] {
+			seen[ns] = true
+			ret = append(ret, ns)
+		}
+	}
+	for _, n := range s.Namespaces {
+		add(n.Name)
+	}
+	for _, e := range s.Extensions {
+		add(e.Namespace)
+	}
+	for _, e := range s.Enums {
+		add(e.Namespace)
+	}
+	for _, d := range s.Domains {
+		add(d.Namespace)
+	}
+	for _, ct := range s.Composites {
+		add(ct.Namespace)
+	}
+	for _, sq := range s.Sequences {
+		add(sq.Namespace)
+	}
+	for _, f := range s.Functions {
+		add(f.Namespace)
+	}
+	for _, t := range s.Tables {
+		add(t.Namespace)
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// ParseName splits name to namespace and object name by the first dot outside of
+// double quotes, the quoted parts are unquoted, unqualified names belong to the current schema
+func (s *Schema) ParseName(name string) (string, string) {
+	quoted := false
+	for i := 0; i < len(name); i++ {
+		switch {
+		case name[i] == '"':
+			quoted = !quoted
+		case name[i] == '.' && !quoted:
+			return unquoteName(name[:i]), unquoteName(name[i+1:])
+		}
+	}
+	return s.CurrentSchema, unquoteName(name)
+}
+
+// ShortName returns object name that is qualified only outside the current schema
+func (s *Schema) ShortName(ns, name string) string {
+	if ns == "" || ns == s.CurrentSchema {
+		return nameIdent(name)
+	}
+	return QualifiedName(ns, name)
+}
+
+var reNextval = regexp.MustCompile(`nextval\('([^']+)'`)
+
+// Retarget moves all schema objects into the namespace ns,
+// references to enum types, sequences and foreign tables of the schema are moved too,
+// as well as the qualified references in view definitions, triggers and expressions.
+// The bodies of functions are not rewritten. The schema is changed in place, see Clone.
+func (s *Schema) Retarget(ns string) {
+	s.NormalizeNamespaces()
+	var reQualified *regexp.Regexp
+	if names := s.NamespaceNames(); len(names) > 0 {
+		reQualified = regexp.MustCompile(`(^|[^\w."$])("?)(` + strings.Join(quoteMetas(names), "|") +
+			`)("?)\.("?)([A-Za-z_][\w$]*)("?)`)
+	}
+	var ncomment string
+	if n, err := s.FindNamespaceByName(s.CurrentSchema); err == nil {
+		ncomment = n.Comment
+	}
+	if n, err := s.FindNamespaceByName(ns); err == nil && n.Comment != "" {
+		ncomment = n.Comment
+	}
+	s.Namespaces = nil
+	if ncomment != "" {
+		s.Namespaces = []*Namespace{{Name: ns, Comment: ncomment}}
+	}
+
+	types := map[string]bool{}
+	for _, e := range s.Enums {
+		e.Namespace = ns
+		types[e.Name] = true
+	}
+	for _, d := range s.Domains {
+		d.Namespace = ns
+		types[d.Name] = true
+	}
+	for _, ct := range s.Composites {
+		ct.Namespace = ns
+		types[ct.Name] = true
+	}
+	seqs := map[string]bool{}
+	for _, sq := range s.Sequences {
+		sq.Namespace = ns
+		seqs[sq.Name] = true
+	}
+	for _, f := range s.Functions {
+		f.Namespace = ns
+	}
+	tables := map[string]bool{}
+	for _, t := range s.Tables {
+		t.Namespace = ns
+		tables[t.Name] = true
+	}
+	requalify := func(expr string) string {
+		if reQualified == nil {
+			return expr
+		}
+		return reQualified.ReplaceAllStringFunc(expr, func(m string) string {
+			ss := reQualified.FindStringSubmatch(m)
+			name := ss[6]
+			if ss[5] == "" {
+				name = strings.ToLower(name)
+			}
+			if ss[2] != ss[4] || ss[5] != ss[7] || !tables[name] && !types[name] && !seqs[name] && !s.hasFunction(name) {
+				return m
+			}
+			return ss[1] + QuoteIdent(ns) + "." + ss[5] + ss[6] + ss[7]
+		})
+	}
+	// tableName returns the name of table reference, that may be quoted
+	tableName := func(ref string) string {
+		_, name := s.ParseName(ref)
+		return name
+	}
+	bareName := func(name string) string {
+		if i := strings.Index(name, "."); i >= 0 {
+			return name[i+1:]
+		}
+		return name
+	}
+	retype := func(t string) string {
+		tp := strings.TrimRight(t, "[]")
+		if types[bareName(tp)] {
+			return quotedName(ns, bareName(tp)) + t[len(tp):]
+		}
+		return t
+	}
+	for _, d := range s.Domains {
+		d.BaseType = retype(d.BaseType)
+		d.Default.String = requalify(d.Default.String)
+		for _, c := range d.Checks {
+			c.Expr = requalify(c.Expr)
+		}
+	}
+	for _, ct := range s.Composites {
+		for _, a := range ct.Attributes {
+			a.Type = retype(a.Type)
+		}
+	}
+	for _, t := range s.Tables {
+		for _, c := range t.Columns {
+			c.Type = retype(c.Type)
+			c.Default.String = reNextval.ReplaceAllStringFunc(c.Default.String, func(m string) string {
+				name := reNextval.FindStringSubmatch(m)[1]
+				if !seqs[bareName(name)] {
+					return m
+				}
+				return "nextval('" + quotedName(ns, bareName(name)) + "'"
+			})
+			c.Default.String = requalify(c.Default.String)
+			c.Generated = requalify(c.Generated)
+		}
+		for i, d := range t.DependsOn {
+			if tables[tableName(d)] {
+				t.DependsOn[i] = QualifiedName(ns, tableName(d))
+			}
+		}
+		if len(t.DependsOnColumns) > 0 {
+			deps := make(map[string][]string, len(t.DependsOnColumns))
+			for d, cols := range t.DependsOnColumns {
+				if tables[tableName(d)] {
+					d = QualifiedName(ns, tableName(d))
+				}
+				deps[d] = cols
+			}
+			t.DependsOnColumns = deps
+		}
+		for _, cs := range t.Constraints {
+			if cs.ReferenceTable != nil && tables[tableName(*cs.ReferenceTable)] {
+				rt := QualifiedName(ns, tableName(*cs.ReferenceTable))
+				cs.ReferenceTable = &rt
+			}
+			cs.Check = requalify(cs.Check)
+		}
+		t.Def = requalify(t.Def)
+		for _, idx := range t.Indexes {
+			idx.ColDef = requalify(idx.ColDef)
+			idx.Where = requalify(idx.Where)
+		}
+		for _, tr := range t.Triggers {
+			tr.Function = requalify(tr.Function)
+			tr.When = requalify(tr.When)
+			tr.From = requalify(tr.From)
+			tr.Def = requalify(tr.Def)
+		}
+		for _, p := range t.Policies {
+			p.Using = requalify(p.Using)
+			p.WithCheck = requalify(p.WithCheck)
+		}
+	}
+	s.CurrentSchema = ns
+}
+
+// hasFunction reports whether the schema contains the function with name in any namespace
+func (s *Schema) hasFunction(name string) bool {
+	for _, f := range s.Functions {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// quoteMetas returns the names quoted for regular expression
+func quoteMetas(names []string) []string {
+	ret := make([]string, len(names))
 	for i, n := range names {
-		names[i] = s.NormalizeTableName(n)
+		ret[i] = regexp.QuoteMeta(n)
 	}
-	return names
+	return ret
 }
 
-// FindTableByName find table by table name
-func (s *Schema) FindTableByName(name string) (*Table, error) {
+// InNamespace returns a schema that contains only the objects of the namespace ns
+func (s *Schema) InNamespace(ns string) *Schema {
+	s.NormalizeNamespaces()
+	ret := &Schema{
+		Name:          s.Name,
+		Desc:          s.Desc,
+		CurrentSchema: s.CurrentSchema,
+		SearchPaths:   s.SearchPaths,
+		Roles:         s.Roles,
+	}
+	for _, n := range s.Namespaces {
+		if n.Name == ns {
+			ret.Namespaces = append(ret.Namespaces, n)
+		}
+	}
+	// extensions are installed into database, the objects of namespace can use any of them
+	ret.Extensions = s.Extensions
+	for _, e := range s.Enums {
+		if e.Namespace == ns {
+			ret.Enums = append(ret.Enums, e)
+		}
+	}
+	for _, d := range s.Domains {
+		if d.Namespace == ns {
+			ret.Domains = append(ret.Domains, d)
+		}
+	}
+	for _, ct := range s.Composites {
+		if ct.Namespace == ns {
+			ret.Composites = append(ret.Composites, ct)
+		}
+	}
+	for _, sq := range s.Sequences {
+		if sq.Namespace == ns {
+			ret.Sequences = append(ret.Sequences, sq)
+		}
+	}
+	for _, f := range s.Functions {
+		if f.Namespace == ns {
+			ret.Functions = append(ret.Functions, f)
+		}
+	}
+	for _, t := range s.Tables {
+		if t.Namespace == ns {
+			ret.Tables = append(ret.Tables, t)
+		}
+	}
+	for _, r := range s.Relations {
+		if r.Table.Namespace == ns {
+			ret.Relations = append(ret.Relations, r)
+		}
+	}
+	return ret
+}
+
+// FindExtension find extension by name
+func (s *Schema) FindExtension(name string) (*Extension, error) {
+	for _, e := range s.Extensions {
+		if e.Name == name {
+			return e, nil
+		}
+	}
+	return nil, errors.WithStack(fmt.Errorf("not found extension '%s'", name))
+}
+
+// FindNamespaceByName find declared namespace by name
+func (s *Schema) FindNamespaceByName(name string) (*Namespace, error) {
+	for _, n := range s.Namespaces {
+		if n.Name == name {
+			return n, nil
+		}
+	}
+	return nil, errors.WithStack(fmt.Errorf("not found namespace '%s'", name))
+}
+
+// FindTable find table by namespace and table name
+func (s *Schema) FindTable(ns, name string) (*Table, error) {
+	if ns == "" {
+		ns = s.CurrentSchema
+	}
 	for _, t := range s.Tables {
-		if s.NormalizeTableName(t.Name) == s.NormalizeTableName(name) {
+		tns := t.Namespace
+		if tns == "" {
+			tns = s.CurrentSchema
+		}
+		if tns == ns && t.Name == name {
 			return t, nil
 		}
 	}
-	return nil, errors.WithStack(fmt.Errorf("not found table '%s'", name))
+	return nil, errors.WithStack(fmt.Errorf("not found table '%s'", QualifiedName(ns, name)))
+}
+
+// FindTableByName find table by table name, that can be schema-qualified
+func (s *Schema) FindTableByName(name string) (*Table, error) {
+	return s.FindTable(s.ParseName(name))
+}
+
+// FindEnum find enum type by namespace and name
+func (s *Schema) FindEnum(ns, name string) (*Enum, error) {
+	if ns == "" {
+		ns = s.CurrentSchema
+	}
+	for _, e := range s.Enums {
+		ens := e.Namespace
+		if ens == "" {
+			ens = s.CurrentSchema
+		}
+		if ens == ns && e.Name == name {
+			return e, nil
+		}
+	}
+	return nil, errors.WithStack(fmt.Errorf("not found enum '%s'", QualifiedName(ns, name)))
+}
+
+// FindDomain find domain by namespace and name
+func (s *Schema) FindDomain(ns, name string) (*Domain, error) {
+	if ns == "" {
+		ns = s.CurrentSchema
+	}
+	for _, d := range s.Domains {
+		dns := d.Namespace
+		if dns == "" {
+			dns = s.CurrentSchema
+		}
+		if dns == ns && d.Name == name {
+			return d, nil
+		}
+	}
+	return nil, errors.WithStack(fmt.Errorf("not found domain '%s'", QualifiedName(ns, name)))
+}
+
+// FindComposite find composite type by namespace and name
+func (s *Schema) FindComposite(ns, name string) (*CompositeType, error) {
+	if ns == "" {
+		ns = s.CurrentSchema
+	}
+	for _, ct := range s.Composites {
+		cns := ct.Namespace
+		if cns == "" {
+			cns = s.CurrentSchema
+		}
+		if cns == ns && ct.Name == name {
+			return ct, nil
+		}
+	}
+	return nil, errors.WithStack(fmt.Errorf("not found composite type '%s'", QualifiedName(ns, name)))
+}
+
+// FindSequence find sequence by namespace and name
+func (s *Schema) FindSequence(ns, name string) (*Sequence, error) {
+	if ns == "" {
+		ns = s.CurrentSchema
+	}
+	for _, sq := range s.Sequences {
+		sns := sq.Namespace
+		if sns == "" {
+			sns = s.CurrentSchema
+		}
+		if sns == ns && sq.Name == name {
+			return sq, nil
+		}
+	}
+	return nil, errors.WithStack(fmt.Errorf("not found sequence '%s'", QualifiedName(ns, name)))
+}
+
+// FindFunction find function by namespace, name and arguments
+func (s *Schema) FindFunction(ns, name, args string) (*Function, error) {
+	if ns == "" {
+		ns = s.CurrentSchema
+	}
+	for _, f := range s.Functions {
+		fns := f.Namespace
+		if fns == "" {
+			fns = s.CurrentSchema
+		}
+		if fns == ns && f.Name == name && NormalizeExpr(f.Arguments) == NormalizeExpr(args) {
+			return f, nil
+		}
+	}
+	return nil, errors.WithStack(fmt.Errorf("not found function '%s(%s)'", QualifiedName(ns, name), args))
 }
 
 // FindRelation ...
-func (s *Schema) FindRelation(tblName string, cs, pcs []*Column) (*Relation, error) {
+func (s *Schema) FindRelation(tbl *Table, cs, pcs []*Column) (*Relation, error) {
 L:
 	for _, r := range s.Relations {
-		if len(r.Columns) != len(cs) || len(r.ParentColumns) != len(pcs) || r.Table.Name != tblName {
+		if len(r.Columns) != len(cs) || len(r.ParentColumns) != len(pcs) ||
+			r.Table.Name != tbl.Name || r.Table.Namespace != tbl.Namespace {
 			continue
 		}
 		for _, rc := range r.Columns {
@@ -255,6 +1313,100 @@ L:
 	return nil, errors.WithStack(fmt.Errorf("not found relation '%v, %v'", cs, pcs))
 }
 
+// NormalizeForeignKeys moves foreign key constraints of tables to relations,
+// so each foreign key is described by the single relation.
+// Constraint that duplicates the relation of the table with the same name is removed.
+func (s *Schema) NormalizeForeignKeys() error {
+	for _, t := range s.Tables {
+		cs := make([]*Constraint, 0, len(t.Constraints))
+		for _, c := range t.Constraints {
+			if c.Type != TypeFK {
+				cs = append(cs, c)
+				continue
+			}
+			if s.hasRelation(t, c.Name) {
+				continue
+			}
+			ref := ""
+			if c.ReferenceTable != nil {
+				ref = *c.ReferenceTable
+			}
+			r, err := s.NewRelation(t, ref, c.Columns, c.ReferenceColumns)
+			if err != nil {
+				return fmt.Errorf("table %q constraint %q: %w", t.FullName(), c.Name, err)
+			}
+			r.Name = c.Name
+			r.OnDelete = c.OnDelete
+			r.OnUpdate = c.OnUpdate
+			r.Match = c.Match
+			r.Deferrable = c.Deferrable
+			r.InitiallyDeferred = c.InitiallyDeferred
+			r.NotValid = c.NotValid
+			r.Def = c.Def
+			r.Comment = c.Comment
+			r.RenamedFrom = c.RenamedFrom
+			s.Relations = append(s.Relations, r)
+		}
+		t.Constraints = cs
+	}
+	return nil
+}
+
+func (s *Schema) hasRelation(t *Table, name string) bool {
+	for _, r := range s.Relations {
+		if r.Table == t && r.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// NewRelation returns the relation of table columns to the columns of parent table,
+// the primary key of parent table is referenced when parent columns are empty.
+// Relation is linked to the columns but not added to the schema.
+func (s *Schema) NewRelation(t *Table, parent string, columns, parentColumns []string) (*Relation, error) {
+	if parent == "" {
+		return nil, fmt.Errorf("foreign key reference table not defined")
+	}
+	pt, err := s.FindTableByName(parent)
+	if err != nil {
+		return nil, err
+	}
+	if len(parentColumns) == 0 {
+		for _, c := range pt.Columns {
+			if c.PrimaryKey {
+				parentColumns = append(parentColumns, c.Name)
+			}
+		}
+	}
+	if len(columns) == 0 || len(columns) != len(parentColumns) {
+		return nil, fmt.Errorf("foreign key columns do not match columns of table %q", pt.FullName())
+	}
+	r := &Relation{
+		Table:         t,
+		ParentTable:   pt,
+		Columns:       make([]*Column, 0, len(columns)),
+		ParentColumns: make([]*Column, 0, len(parentColumns)),
+	}
+	for _, name := range columns {
+		c, err := t.FindColumnByName(name)
+		if err != nil {
+			return nil, err
+		}
+		r.Columns = append(r.Columns, c)
+		c.ParentRelations = append(c.ParentRelations, r)
+	}
+	for _, name := range parentColumns {
+		c, err := pt.FindColumnByName(name)
+		if err != nil {
+			return nil, err
+		}
+		r.ParentColumns = append(r.ParentColumns, c)
+		c.ChildRelations = append(c.ChildRelations, r)
+	}
+	return r, nil
+}
+
 // FindColumnByName find column by column name
 func (t *Table) FindColumnByName(name string) (*Column, error) {
 	for _, c := range t.Columns {
@@ -285,6 +1437,64 @@ func (t *Table) FindConstraintByName(name string) (*Constraint, error) {
 	return nil, errors.WithStack(fmt.Errorf("not found constraint '%s' on table '%s'", name, t.Name))
 }
 
+func (t *Table) FindTriggerByName(name string) (*Trigger, error) {
+	for _, tr := range t.Triggers {
+		if tr.Name == name {
+			return tr, nil
+		}
+	}
+	return nil, errors.WithStack(fmt.Errorf("not found trigger '%s' on table '%s'", name, t.Name))
+}
+
+func (t *Table) FindPolicyByName(name string) (*Policy, error) {
+	for _, p := range t.Policies {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, errors.WithStack(fmt.Errorf("not found policy '%s' on table '%s'", name, t.Name))
+}
+
+func (t *Table) FindPartitionByName(name string) (*Partition, error) {
+	for _, p := range t.Partitions {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return nil, errors.WithStack(fmt.Errorf("not found partition '%s' of table '%s'", name, t.Name))
+}
+
+// PrimaryKey returns the primary key constraint of table, that is declared in constraints
+// or made of primary key columns with the default name, nil when table has no primary key
+func (t *Table) PrimaryKey() *Constraint {
+	for _, c := range t.Constraints {
+		if c.Type == TypePK {
+			return c
+		}
+	}
+	cols := pkColumns(t)
+	if len(cols) == 0 {
+		return nil
+	}
+	return &Constraint{
+		Name:    t.Name + "_pkey",
+		Type:    TypePK,
+		Table:   &t.Name,
+		Columns: cols,
+	}
+}
+
+// pkColumns returns the names of primary key columns in the order of table columns
+func pkColumns(t *Table) []string {
+	cols := []string{}
+	for _, c := range t.Columns {
+		if c.PrimaryKey {
+			cols = append(cols, c.Name)
+		}
+	}
+	return cols
+}
+
 // FindConstrainsByColumnName find constraint by column name
 func (t *Table) FindConstrainsByColumnName(name string) []*Constraint {
 	cts := []*Constraint{}
@@ -303,34 +1513,72 @@ func (s *Schema) Sort() {
 	for _, t := range s.Tables {
 		for _, c := range t.Columns {
 			sort.SliceStable(c.ParentRelations, func(i, j int) bool {
-				return c.ParentRelations[i].Table.Name < c.ParentRelations[j].Table.Name
+				return c.ParentRelations[i].Table.FullName() < c.ParentRelations[j].Table.FullName()
 			})
 			sort.SliceStable(c.ChildRelations, func(i, j int) bool {
-				return c.ChildRelations[i].Table.Name < c.ChildRelations[j].Table.Name
+				return c.ChildRelations[i].Table.FullName() < c.ChildRelations[j].Table.FullName()
 			})
 		}
-		sort.SliceStable(t.Columns, func(i, j int) bool {
-			return t.Columns[i].Name < t.Columns[j].Name
-		})
+		// columns order is significant, it is the order of columns in table,
+		// the order of index and constraint columns is the order of key
 		sort.SliceStable(t.Indexes, func(i, j int) bool {
 			return t.Indexes[i].Name < t.Indexes[j].Name
 		})
-		for _, idx := range t.Indexes {
-			sort.Strings(idx.Columns)
-		}
 		sort.SliceStable(t.Constraints, func(i, j int) bool {
 			return t.Constraints[i].Name < t.Constraints[j].Name
 		})
-		for _, cs := range t.Constraints {
-			sort.Strings(cs.Columns)
-			sort.Strings(cs.ReferenceColumns)
-		}
+		sort.SliceStable(t.Triggers, func(i, j int) bool {
+			return t.Triggers[i].Name < t.Triggers[j].Name
+		})
+		sort.SliceStable(t.Policies, func(i, j int) bool {
+			return t.Policies[i].Name < t.Policies[j].Name
+		})
+		sortGrants(t.Grants)
+		sort.SliceStable(t.Partitions, func(i, j int) bool {
+			return t.Partitions[i].Name < t.Partitions[j].Name
+		})
 	}
 	sort.SliceStable(s.Tables, func(i, j int) bool {
-		return s.Tables[i].Name < s.Tables[j].Name
+		return s.Tables[i].FullName() < s.Tables[j].FullName()
 	})
 	sort.SliceStable(s.Relations, func(i, j int) bool {
-		return s.Relations[i].Table.Name < s.Relations[j].Table.Name
+		ti, tj := s.Relations[i].Table.FullName(), s.Relations[j].Table.FullName()
+		return ti < tj || ti == tj && s.Relations[i].Name < s.Relations[j].Name
+	})
+	// enum values order is significant, sort types only
+	sort.SliceStable(s.Enums, func(i, j int) bool {
+		return s.Enums[i].FullName() < s.Enums[j].FullName()
+	})
+	sort.SliceStable(s.Domains, func(i, j int) bool {
+		return s.Domains[i].FullName() < s.Domains[j].FullName()
+	})
+	for _, d := range s.Domains {
+		sort.SliceStable(d.Checks, func(i, j int) bool {
+			return d.Checks[i].Name < d.Checks[j].Name
+		})
+	}
+	// attributes order is significant, sort types only
+	sort.SliceStable(s.Composites, func(i, j int) bool {
+		return s.Composites[i].FullName() < s.Composites[j].FullName()
+	})
+	sort.SliceStable(s.Sequences, func(i, j int) bool {
+		return s.Sequences[i].FullName() < s.Sequences[j].FullName()
+	})
+	for _, sq := range s.Sequences {
+		sortGrants(sq.Grants)
+	}
+	sort.SliceStable(s.Functions, func(i, j int) bool {
+		return s.Functions[i].Signature() < s.Functions[j].Signature()
+	})
+	sort.SliceStable(s.Namespaces, func(i, j int) bool {
+		return s.Namespaces[i].Name < s.Namespaces[j].Name
+	})
+	for _, n := range s.Namespaces {
+		sortGrants(n.Grants)
+	}
+	sort.Strings(s.Roles)
+	sort.SliceStable(s.Extensions, func(i, j int) bool {
+		return s.Extensions[i].Name < s.Extensions[j].Name
 	})
 	for _, r := range s.Relations {
 		sort.SliceStable(r.Columns, func(i, j int) bool {
@@ -357,7 +1605,7 @@ func (s *Schema) Repair() error {
 	}
 
 	for _, r := range s.Relations {
-		t, err := s.FindTableByName(r.Table.Name)
+		t, err := s.FindTable(r.Table.Namespace, r.Table.Name)
 		if err != nil {
 			return errors.Wrap(err, "failed to repair relation")
 		}
@@ -370,7 +1618,7 @@ func (s *Schema) Repair() error {
 			r.Columns[i] = c
 		}
 		r.Table = t
-		pt, err := s.FindTableByName(r.ParentTable.Name)
+		pt, err := s.FindTable(r.ParentTable.Namespace, r.ParentTable.Name)
 		if err != nil {
 			return errors.Wrap(err, "failed to repair relation")
 		}
@@ -423,8 +1671,8 @@ func (t *Table) CollectTablesAndRelations(distance int, root bool) ([]*Table, []
 	uTables := []*Table{}
 	encounteredT := make(map[string]bool)
 	for _, t := range tables {
-		if !encounteredT[t.Name] {
-			encounteredT[t.Name] = true
+		if !encounteredT[t.FullName()] {
+			encounteredT[t.FullName()] = true
 			uTables = append(uTables, t)
 		}
 	}
@@ -434,7 +1682,7 @@ func (t *Table) CollectTablesAndRelations(distance int, root bool) ([]*Table, []
 	for _, r := range relations {
 		if !encounteredR[r] {
 			encounteredR[r] = true
-			if !encounteredT[r.ParentTable.Name] || !encounteredT[r.Table.Name] {
+			if !encounteredT[r.ParentTable.FullName()] || !encounteredT[r.Table.FullName()] {
 				continue
 			}
 			uRelations = append(uRelations, r)
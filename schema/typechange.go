@@ -0,0 +1,96 @@
+package schema
+
+// TypeChange is the classification of column type change
+type TypeChange string
+
+const (
+	TypeChangeNone TypeChange = ""
+	// TypeChangeSafe does not rewrite the table and can not fail
+	TypeChangeSafe TypeChange = "safe"
+	// TypeChangeRewrite rewrites the table and its indexes under ACCESS EXCLUSIVE lock
+	TypeChangeRewrite TypeChange = "rewrite"
+	// TypeChangeFailure has no assignment cast between types, USING expression is required
+	TypeChangeFailure TypeChange = "likely failure"
+)
+
+// type categories with assignment casts between types of the same category
+var typeCategories = map[string]string{
+	"text": "string", "varchar": "string", "bpchar": "string", "name": "string", "citext": "string",
+	"int2": "numeric", "int4": "numeric", "int8": "numeric", "numeric": "numeric", "float4": "numeric", "float8": "numeric",
+	"date": "datetime", "timestamp": "datetime", "timestamptz": "datetime",
+	"time": "time", "timetz": "time",
+	"json": "json", "jsonb": "json",
+	"bit": "bit", "varbit": "bit",
+}
+
+// ClassifyTypeChange returns how PostgreSQL changes the column type from one to another,
+// any conversion with USING expression rewrites the table
+func ClassifyTypeChange(from, to, using string) TypeChange {
+	ft, tt := parseSQLType(from), parseSQLType(to)
+	if ft.base == tt.base && ft.array == tt.array && sameMods(ft.mods, tt.mods) {
+		return TypeChangeNone
+	}
+	if using != "" {
+		return TypeChangeRewrite
+	}
+	if ft.array != tt.array {
+		return TypeChangeFailure
+	}
+	if ft.base == tt.base {
+		if widenedMods(ft, tt) {
+			return TypeChangeSafe
+		}
+		return TypeChangeRewrite
+	}
+	// binary coercible conversions
+	if tt.base == "text" && (ft.base == "varchar" || ft.base == "citext") ||
+		tt.base == "varchar" && tt.mods == nil && ft.base == "text" {
+		return TypeChangeSafe
+	}
+	fc, tc := typeCategories[ft.base], typeCategories[tt.base]
+	switch {
+	case tc == "string":
+		// any type has the assignment cast to string types
+		return TypeChangeRewrite
+	case fc != "" && fc == tc:
+		return TypeChangeRewrite
+	}
+	return TypeChangeFailure
+}
+
+func sameMods(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// widenedMods reports whether the new type modifiers accept all values
+// of the old type without conversion
+func widenedMods(from, to sqlType) bool {
+	switch from.base {
+	case "varchar", "varbit", "timestamp", "timestamptz", "time", "timetz", "interval":
+		return to.mods == nil || len(from.mods) == 1 && len(to.mods) == 1 && to.mods[0] >= from.mods[0]
+	case "numeric":
+		if to.mods == nil {
+			return true
+		}
+		if from.mods == nil {
+			return false
+		}
+		fs, ts := 0, 0
+		if len(from.mods) > 1 {
+			fs = from.mods[1]
+		}
+		if len(to.mods) > 1 {
+			ts = to.mods[1]
+		}
+		return fs == ts && to.mods[0] >= from.mods[0]
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClassifyTypeChange(t *testing.T) {
+	tests := []struct {
+		from, to, using string
+		want            TypeChange
+	}{
+		{"varchar(32)", "character varying(32)", "", TypeChangeNone},
+		{"varchar(32)", "varchar(64)", "", TypeChangeSafe},
+		{"varchar(32)", "varchar", "", TypeChangeSafe},
+		{"varchar(32)", "text", "", TypeChangeSafe},
+		{"text", "varchar", "", TypeChangeSafe},
+		{"numeric(10,2)", "numeric(12,2)", "", TypeChangeSafe},
+		{"timestamp(3)", "timestamp", "", TypeChangeSafe},
+		{"varchar(64)", "varchar(32)", "", TypeChangeRewrite},
+		{"numeric(10,2)", "numeric(10,4)", "", TypeChangeRewrite},
+		{"integer", "bigint", "", TypeChangeRewrite},
+		{"timestamp", "timestamptz", "", TypeChangeRewrite},
+		{"integer", "text", "", TypeChangeRewrite},
+		{"text", "integer", "", TypeChangeFailure},
+		{"varchar(32)", "jsonb", "", TypeChangeFailure},
+		{"text", "text[]", "", TypeChangeFailure},
+		{"text", "integer", "code::integer", TypeChangeRewrite},
+		{"varchar(32)", "jsonb", "to_jsonb(data)", TypeChangeRewrite},
+	}
+	for _, tt := range tests {
+		if got := ClassifyTypeChange(tt.from, tt.to, tt.using); got != tt.want {
+			t.Errorf("%s -> %s using %q: %q, want %q", tt.from, tt.to, tt.using, got, tt.want)
+		}
+	}
+}
+
+func TestPatchSchema_BuildTypeChange(t *testing.T) {
+	newSchema := func(code, data string, using string) *Schema {
+		return &Schema{Tables: []*Table{{
+			Name: "items",
+			Columns: []*Column{
+				{Name: "code", Type: code, Using: using},
+				{Name: "data", Type: data},
+			},
+		}}}
+	}
+	s := &PatchSchema{}
+	if err := s.Build(newSchema("text", "varchar(32)", ""), newSchema("integer", "text", "code::integer")); err != nil {
+		t.Error(err)
+		return
+	}
+	plan := s.Plan()
+	if qss := strings.Join(plan.SQL(), "\n"); qss != `ALTER TABLE public.items ALTER COLUMN code TYPE integer USING code::integer
+ALTER TABLE public.items ALTER COLUMN data TYPE text` {
+		t.Error(qss)
+	}
+	if plan.Operations[0].TypeChange != TypeChangeRewrite || plan.Operations[1].TypeChange != TypeChangeSafe {
+		t.Errorf("%q, %q", plan.Operations[0].TypeChange, plan.Operations[1].TypeChange)
+	}
+	if strings.Join(plan.Warnings, "\n") != "column public.items.code: type change text -> integer: rewrite" {
+		t.Error(plan.Warnings)
+	}
+}
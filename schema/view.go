@@ -0,0 +1,204 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	TypeView         = "VIEW"
+	TypeMaterialized = "MATERIALIZED VIEW"
+)
+
+// isView reports whether the table is a view or materialized view
+func isView(t *Table) bool {
+	switch strings.ToUpper(t.Type) {
+	case TypeView, TypeMaterialized:
+		return true
+	}
+	return false
+}
+
+// NormalizeViewDef returns the canonical form of view definition for comparison
+func NormalizeViewDef(def string) string {
+	return NormalizeExpr(strings.TrimRight(strings.TrimSpace(def), ";"))
+}
+
+// viewChanged reports whether the view definition or the kind of relation is changed
+func (t *PatchTable) viewChanged() bool {
+	return !strings.EqualFold(t.from.Type, t.to.Type) ||
+		NormalizeViewDef(t.from.Def) != NormalizeViewDef(t.to.Def)
+}
+
+// viewReplaceable reports whether the changed view can be replaced by CREATE OR REPLACE VIEW:
+// the columns of source view are kept in the same order with the same types,
+// new columns are added to the end of list
+func (t *PatchTable) viewReplaceable() bool {
+	if !strings.EqualFold(t.from.Type, TypeView) || !strings.EqualFold(t.to.Type, TypeView) {
+		return false
+	}
+	if len(t.to.Columns) < len(t.from.Columns) {
+		return false
+	}
+	for i, c := range t.from.Columns {
+		rc := t.to.Columns[i]
+		if c.Name != rc.Name && rc.RenamedFrom != c.Name ||
+			normalizeColumn(c).Type != normalizeColumn(rc).Type {
+			return false
+		}
+	}
+	return true
+}
+
+// alterView replaces the changed view definition, views have no columns to alter,
+// renamed columns are renamed before the view is replaced
+func (t *PatchTable) alterView() []*Operation {
+	ret := []*Operation{}
+	for _, c := range t.columns {
+		if c.from != nil && c.to != nil {
+			ret = append(ret, c.rename()...)
+		}
+	}
+	if t.viewChanged() {
+		name := quotedName(t.to.Namespace, t.to.Name)
+		ret = append(ret, &Operation{
+			Kind:   OpAlter,
+			Object: TypeView,
+			Target: name,
+			SQL:    fmt.Sprintf("CREATE OR REPLACE VIEW %s AS (\n%s\n)", name, strings.TrimRight(t.to.Def, ";")),
+			Lock:   LockAccessExclusive,
+		})
+	}
+	for _, c := range t.columns {
+		if c.to != nil {
+			ret = append(ret, c.comment()...)
+		}
+	}
+	for _, idx := range t.indexes {
+		if idx.from == nil {
+			ret = append(ret, idx.create()...)
+		} else if idx.to == nil {
+			ret = append(ret, idx.drop()...)
+		} else {
+			ret = append(ret, idx.alter()...)
+		}
+	}
+	for _, tr := range t.triggers {
+		ret = append(ret, tr.Operations()...)
+	}
+	return ret
+}
+
+// recreated returns the patch that creates the view again with its comments, indexes and triggers
+func (t *PatchTable) recreated() *PatchTable {
+	ret := &PatchTable{to: t.to, noData: t.noData}
+	tableName := quotedName(t.to.Namespace, t.to.Name)
+	for _, c := range t.to.Columns {
+		ret.columns = append(ret.columns, &PatchColumn{tableName: tableName, to: c})
+	}
+	for _, idx := range t.to.Indexes {
+		ret.indexes = append(ret.indexes, &PatchIndex{namespace: t.to.Namespace, tableName: tableName, to: idx})
+	}
+	for _, tr := range t.to.Triggers {
+		ret.triggers = append(ret.triggers, &PatchTrigger{namespace: t.to.Namespace, tableName: tableName, to: tr})
+	}
+	return ret
+}
+
+// refresh fills the materialized view created WITH NO DATA
+func (t *PatchTable) refresh() []*Operation {
+	if !t.noData || t.to == nil || !strings.EqualFold(t.to.Type, TypeMaterialized) ||
+		t.from != nil && !t.recreate {
+		return nil
+	}
+	name := quotedName(t.to.Namespace, t.to.Name)
+	return []*Operation{{
+		Kind:   OpAlter,
+		Object: TypeMaterialized,
+		Target: name,
+		SQL:    "REFRESH MATERIALIZED VIEW " + name,
+		Lock:   LockAccessExclusive,
+	}}
+}
+
+// viewUsesColumn reports whether the view uses the column of table
+func (s *Schema) viewUsesColumn(v, t *Table, column string) bool {
+	if cols, ok := v.DependsOnColumns[t.FullName()]; ok {
+		for _, c := range cols {
+			if c == column {
+				return true
+			}
+		}
+		return false
+	}
+	for _, d := range s.TableDependencies(v) {
+		if d == t.FullName() {
+			return true
+		}
+	}
+	return false
+}
+
+// blockedByViews reports whether the column is dropped or its type is changed,
+// PostgreSQL refuses both while a view uses the column
+func (c *PatchColumn) blockedByViews() bool {
+	if c.from == nil {
+		return false
+	}
+	if c.to == nil {
+		return !PatchDropDisable
+	}
+	// changed generated column is dropped and added again
+	return normalizeColumn(c.from).Type != normalizeColumn(c.to).Type || c.recreated()
+}
+
+// recreateViews marks the changed views that can not be replaced, the views
+// that use dropped or retyped columns, and the views that depend on the recreated views,
+// to be dropped and created again
+func (s *PatchSchema) recreateViews(from, to *Schema) {
+	recreated := map[string]bool{}
+	for _, pt := range s.tables {
+		if pt.from == nil || pt.to == nil || !isView(pt.from) && !isView(pt.to) {
+			continue
+		}
+		if pt.viewChanged() && !pt.viewReplaceable() {
+			pt.recreate = true
+			recreated[pt.to.FullName()] = true
+		}
+	}
+	for _, pt := range s.tables {
+		if pt.from == nil || pt.to == nil || isView(pt.from) {
+			continue
+		}
+		for _, pc := range pt.columns {
+			if !pc.blockedByViews() {
+				continue
+			}
+			for _, vt := range s.tables {
+				if vt.from == nil || vt.to == nil || vt.recreate || !isView(vt.from) || !isView(vt.to) {
+					continue
+				}
+				if from.viewUsesColumn(vt.from, pt.from, pc.from.Name) {
+					vt.recreate = true
+					recreated[vt.to.FullName()] = true
+				}
+			}
+		}
+	}
+	for changed := len(recreated) > 0; changed; {
+		changed = false
+		for _, pt := range s.tables {
+			if pt.from == nil || pt.to == nil || pt.recreate || !isView(pt.to) {
+				continue
+			}
+			for _, d := range to.TableDependencies(pt.to) {
+				if recreated[d] {
+					pt.recreate = true
+					recreated[pt.to.FullName()] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+}
@@ -2,40 +2,192 @@ package schema
 
 import (
 	"database/sql"
+	"fmt"
 	"io"
+	"strings"
 
 	"github.com/goccy/go-yaml"
 )
 
 type YamlSchema struct {
-	Name   string                `yaml:"name"`
-	Schema string                `yaml:"schema"`
-	Tables map[string]*YamlTable `yaml:"tables"`
+	Name       string                    `yaml:"name"`
+	Schema     string                    `yaml:"schema"`
+	Namespaces map[string]*YamlNamespace `yaml:"namespaces,omitempty"`
+	Extensions map[string]*YamlExtension `yaml:"extensions,omitempty"`
+	Types      map[string]*YamlType      `yaml:"types,omitempty"`
+	Sequences  map[string]*YamlSequence  `yaml:"sequences,omitempty"`
+	Functions  map[string]*YamlFunction  `yaml:"functions,omitempty"` // key = name(arguments)
+	Tables     map[string]*YamlTable     `yaml:"tables"`
+}
+
+type YamlSequence struct {
+	DataType  string `yaml:"type,omitempty"`
+	Start     *int64 `yaml:"start,omitempty"`
+	Increment int64  `yaml:"increment,omitempty"`
+	MinValue  *int64 `yaml:"minValue,omitempty"`
+	MaxValue  *int64 `yaml:"maxValue,omitempty"`
+	Cycle     bool   `yaml:"cycle,omitempty"`
+	Cache     int64  `yaml:"cache,omitempty"`
+	OwnedBy   string `yaml:"ownedBy,omitempty"`
+	Comment   string `yaml:"comment,omitempty"`
+	Owner     string `yaml:"owner,omitempty"`
+	// Grants are the privileges by roles
+	Grants YamlGrants `yaml:"grants,omitempty"`
+}
+
+// YamlGrants are the privileges by roles, e.g. reporting: [SELECT, UPDATE (name)]
+type YamlGrants map[string][]string
+
+func newYamlGrants(grants []*Grant) YamlGrants {
+	if len(grants) == 0 {
+		return nil
+	}
+	ret := make(YamlGrants, len(grants))
+	for _, g := range grants {
+		ret[g.Role] = append(ret[g.Role], g.Privileges...)
+	}
+	return ret
+}
+
+func (yg YamlGrants) grants() []*Grant {
+	if len(yg) == 0 {
+		return nil
+	}
+	ret := make([]*Grant, 0, len(yg))
+	for role, privs := range yg {
+		ret = append(ret, &Grant{Role: role, Privileges: privs})
+	}
+	return ret
+}
+
+type YamlFunction struct {
+	Returns         string   `yaml:"returns"`
+	Language        string   `yaml:"language"`
+	Volatility      string   `yaml:"volatility,omitempty"`
+	SecurityDefiner bool     `yaml:"securityDefiner,omitempty"`
+	Strict          bool     `yaml:"strict,omitempty"`
+	Leakproof       bool     `yaml:"leakproof,omitempty"`
+	Parallel        string   `yaml:"parallel,omitempty"`
+	Cost            float64  `yaml:"cost,omitempty"`
+	Set             []string `yaml:"set,omitempty"`
+	Body            string   `yaml:"body"`
+	Comment         string   `yaml:"comment,omitempty"`
+}
+
+type YamlTrigger struct {
+	Timing            string   `yaml:"timing,omitempty"`
+	Events            []string `yaml:"events,flow,omitempty"`
+	ForEach           string   `yaml:"forEach,omitempty"`
+	When              string   `yaml:"when,omitempty"`
+	Function          string   `yaml:"function,omitempty"`
+	Constraint        bool     `yaml:"constraint,omitempty"`
+	From              string   `yaml:"from,omitempty"`
+	Deferrable        bool     `yaml:"deferrable,omitempty"`
+	InitiallyDeferred bool     `yaml:"initiallyDeferred,omitempty"`
+	OldTable          string   `yaml:"oldTable,omitempty"`
+	NewTable          string   `yaml:"newTable,omitempty"`
+	Def               string   `yaml:"def,omitempty"`
+	Comment           string   `yaml:"comment,omitempty"`
+}
+
+type YamlPolicy struct {
+	Command     string   `yaml:"command,omitempty"`
+	Roles       []string `yaml:"roles,flow,omitempty"`
+	Using       string   `yaml:"using,omitempty"`
+	WithCheck   string   `yaml:"withCheck,omitempty"`
+	Restrictive bool     `yaml:"restrictive,omitempty"`
+	Comment     string   `yaml:"comment,omitempty"`
+}
+
+type YamlExtension struct {
+	Version string `yaml:"version,omitempty"`
+	Schema  string `yaml:"schema,omitempty"`
+}
+
+type YamlNamespace struct {
+	Comment string     `yaml:"comment,omitempty"`
+	Owner   string     `yaml:"owner,omitempty"`
+	Grants  YamlGrants `yaml:"grants,omitempty"`
+}
+
+type YamlType struct {
+	Enum []string `yaml:"enum,flow,omitempty"`
+	// RenamedValues are the previous enum values by the new values
+	RenamedValues map[string]string `yaml:"renamedValues,omitempty"`
+	// Domain is the base type of domain
+	Domain     string            `yaml:"domain,omitempty"`
+	NotNull    bool              `yaml:"notNull,omitempty"`
+	Default    *string           `yaml:"default,omitempty"`
+	Checks     map[string]string `yaml:"checks,omitempty"` // key = constraint name
+	Attributes []*YamlAttribute  `yaml:"attributes,omitempty"`
+	Comment    string            `yaml:"comment,omitempty"`
+}
+
+type YamlAttribute struct {
+	Name string `yaml:"name"`
+	Type string `yaml:"type"`
 }
 
 type YamlTable struct {
 	Type        string                     `yaml:"type,omitempty"`
-	Columns     map[string]*YamlColumn     `yaml:"columns"`
+	Columns     YamlColumns                `yaml:"columns"`
 	Indexes     map[string]*YamlIndex      `yaml:"indexes,omitempty"`
 	Constraints map[string]*YamlConstraint `yaml:"constraints,omitempty"`
 	Relations   map[string]*YamlRelation   `yaml:"relations,omitempty"` // key = parent table
+	Triggers    map[string]*YamlTrigger    `yaml:"triggers,omitempty"`
 	Def         string                     `yaml:"def,omitempty"`
+	DependsOn   []string                   `yaml:"dependsOn,flow,omitempty"`
+	// DependsOnColumns are the columns used by view, key = table
+	DependsOnColumns map[string][]string `yaml:"dependsOnColumns,omitempty"`
+	RenamedFrom      string              `yaml:"renamedFrom,omitempty"`
+	PartitionBy      string              `yaml:"partitionBy,omitempty"`
+	// Partitions are the partition bounds, key = partition name
+	Partitions     map[string]string    `yaml:"partitions,omitempty"`
+	PartitionRules []*YamlPartitionRule `yaml:"partitionRules,omitempty"`
+	// RowSecurity enables row level security, ForceRowSecurity applies it to the table owner
+	RowSecurity      bool                   `yaml:"rowSecurity,omitempty"`
+	ForceRowSecurity bool                   `yaml:"forceRowSecurity,omitempty"`
+	Policies         map[string]*YamlPolicy `yaml:"policies,omitempty"`
+	Owner            string                 `yaml:"owner,omitempty"`
+	Grants           YamlGrants             `yaml:"grants,omitempty"`
+	Comment          string                 `yaml:"comment,omitempty"`
+}
+
+type YamlPartitionRule struct {
+	Interval string `yaml:"interval"`
+	From     string `yaml:"from"`
+	To       string `yaml:"to"`
+	Format   string `yaml:"format,omitempty"`
 }
 
 type YamlRelation struct {
-	Name          string   `yaml:"name"`
-	Columns       []string `yaml:"columns,flow"`
-	ParentColumns []string `yaml:"parentColumns,flow"`
-	OnDelete      string   `yaml:"onDelete,omitempty"`
+	Name              string   `yaml:"name"`
+	Columns           []string `yaml:"columns,flow"`
+	ParentColumns     []string `yaml:"parentColumns,flow"`
+	OnDelete          string   `yaml:"onDelete,omitempty"`
+	OnUpdate          string   `yaml:"onUpdate,omitempty"`
+	Match             string   `yaml:"match,omitempty"`
+	Deferrable        bool     `yaml:"deferrable,omitempty"`
+	InitiallyDeferred bool     `yaml:"initiallyDeferred,omitempty"`
+	NotValid          bool     `yaml:"notValid,omitempty"`
+	RenamedFrom       string   `yaml:"renamedFrom,omitempty"`
+	Comment           string   `yaml:"comment,omitempty"`
 }
 
 type YamlConstraint struct {
-	Type             string   `yaml:"type,omitempty"`
-	Check            string   `json:"check,omitempty"`
-	OnDelete         string   `json:"onDelete,omitempty"`
-	ReferenceTable   string   `yaml:"referenceTable,omitempty"`
-	Columns          []string `yaml:"columns,flow"`
-	ReferenceColumns []string `yaml:"referenceColumns,flow,omitempty"`
+	Type              string   `yaml:"type,omitempty"`
+	Check             string   `json:"check,omitempty"`
+	OnDelete          string   `json:"onDelete,omitempty"`
+	OnUpdate          string   `yaml:"onUpdate,omitempty"`
+	Match             string   `yaml:"match,omitempty"`
+	Deferrable        bool     `yaml:"deferrable,omitempty"`
+	InitiallyDeferred bool     `yaml:"initiallyDeferred,omitempty"`
+	NotValid          bool     `yaml:"notValid,omitempty"`
+	ReferenceTable    string   `yaml:"referenceTable,omitempty"`
+	Columns           []string `yaml:"columns,flow"`
+	ReferenceColumns  []string `yaml:"referenceColumns,flow,omitempty"`
+	RenamedFrom       string   `yaml:"renamedFrom,omitempty"`
+	Comment           string   `yaml:"comment,omitempty"`
 }
 
 type YamlIndex struct {
@@ -49,13 +201,103 @@ type YamlIndex struct {
 	With         string   `yaml:"with,omitempty"`
 	Tablespace   string   `yaml:"tablespace,omitempty"`
 	Where        string   `yaml:"where,omitempty"`
+	RenamedFrom  string   `yaml:"renamedFrom,omitempty"`
+	Comment      string   `yaml:"comment,omitempty"`
 }
 
 type YamlColumn struct {
-	Type       string  `yaml:"type"`
-	Nullable   bool    `yaml:"nullable,omitempty"`
-	PrimaryKey bool    `yaml:"pk,omitempty"`
-	Default    *string `yaml:"default,omitempty"`
+	Name            string        `yaml:"-"`
+	Type            string        `yaml:"type"`
+	Nullable        bool          `yaml:"nullable,omitempty"`
+	PrimaryKey      bool          `yaml:"pk,omitempty"`
+	Default         *string       `yaml:"default,omitempty"`
+	Identity        string        `yaml:"identity,omitempty"` // always or by default
+	IdentityOptions *YamlSequence `yaml:"identityOptions,omitempty"`
+	Generated       string        `yaml:"generated,omitempty"`
+	Using           string        `yaml:"using,omitempty"` // conversion expression for type change
+	RenamedFrom     string        `yaml:"renamedFrom,omitempty"`
+	Comment         string        `yaml:"comment,omitempty"`
+}
+
+// YamlColumns is the mapping of column names to columns that keeps the declaration order
+type YamlColumns []*YamlColumn
+
+func (ycs YamlColumns) MarshalYAML() (interface{}, error) {
+	ms := make(yaml.MapSlice, 0, len(ycs))
+	for _, yc := range ycs {
+		ms = append(ms, yaml.MapItem{Key: yc.Name, Value: yc})
+	}
+	return ms, nil
+}
+
+func (ycs *YamlColumns) UnmarshalYAML(data []byte) error {
+	ms := yaml.MapSlice{}
+	if err := yaml.Unmarshal(data, &ms); err != nil {
+		return err
+	}
+	m := map[string]*YamlColumn{}
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*ycs = make(YamlColumns, 0, len(ms))
+	for _, mi := range ms {
+		name := fmt.Sprint(mi.Key)
+		yc := m[name]
+		if yc == nil {
+			yc = &YamlColumn{}
+		}
+		yc.Name = name
+		*ycs = append(*ycs, yc)
+	}
+	return nil
+}
+
+func newYamlSequence(sq *Sequence) *YamlSequence {
+	ysq := &YamlSequence{
+		DataType:  sq.DataType,
+		Increment: sq.Increment,
+		Cycle:     sq.Cycle,
+		Cache:     sq.Cache,
+		OwnedBy:   sq.OwnedBy,
+		Comment:   sq.Comment,
+		Owner:     sq.Owner,
+		Grants:    newYamlGrants(sq.Grants),
+	}
+	if sq.Start.Valid {
+		ysq.Start = &(sq.Start.Int64)
+	}
+	if sq.MinValue.Valid {
+		ysq.MinValue = &(sq.MinValue.Int64)
+	}
+	if sq.MaxValue.Valid {
+		ysq.MaxValue = &(sq.MaxValue.Int64)
+	}
+	return ysq
+}
+
+func (ysq *YamlSequence) sequence(ns, name string) *Sequence {
+	sq := &Sequence{
+		Namespace: ns,
+		Name:      name,
+		DataType:  ysq.DataType,
+		Increment: ysq.Increment,
+		Cycle:     ysq.Cycle,
+		Cache:     ysq.Cache,
+		OwnedBy:   ysq.OwnedBy,
+		Comment:   ysq.Comment,
+		Owner:     ysq.Owner,
+		Grants:    ysq.Grants.grants(),
+	}
+	if ysq.Start != nil {
+		sq.Start = sql.NullInt64{Int64: *ysq.Start, Valid: true}
+	}
+	if ysq.MinValue != nil {
+		sq.MinValue = sql.NullInt64{Int64: *ysq.MinValue, Valid: true}
+	}
+	if ysq.MaxValue != nil {
+		sq.MaxValue = sql.NullInt64{Int64: *ysq.MaxValue, Valid: true}
+	}
+	return sq
 }
 
 func (s *Schema) MarshalYAML() ([]byte, error) {
@@ -64,14 +306,107 @@ func (s *Schema) MarshalYAML() ([]byte, error) {
 		Schema: s.CurrentSchema,
 		Tables: make(map[string]*YamlTable, len(s.Tables)),
 	}
+	if len(s.Namespaces) > 0 {
+		ys.Namespaces = make(map[string]*YamlNamespace, len(s.Namespaces))
+	}
+	for _, n := range s.Namespaces {
+		ys.Namespaces[n.Name] = &YamlNamespace{
+			Comment: n.Comment,
+			Owner:   n.Owner,
+			Grants:  newYamlGrants(n.Grants),
+		}
+	}
+	if len(s.Extensions) > 0 {
+		ys.Extensions = make(map[string]*YamlExtension, len(s.Extensions))
+	}
+	for _, e := range s.Extensions {
+		ys.Extensions[e.Name] = &YamlExtension{
+			Version: e.Version,
+			Schema:  e.Namespace,
+		}
+	}
+	if n := len(s.Enums) + len(s.Domains) + len(s.Composites); n > 0 {
+		ys.Types = make(map[string]*YamlType, n)
+	}
+	for _, e := range s.Enums {
+		ys.Types[s.ShortName(e.Namespace, e.Name)] = &YamlType{
+			Enum:          e.Values,
+			RenamedValues: e.RenamedValues,
+			Comment:       e.Comment,
+		}
+	}
+	for _, d := range s.Domains {
+		yt := &YamlType{
+			Domain:  d.BaseType,
+			NotNull: d.NotNull,
+			Comment: d.Comment,
+		}
+		if d.Default.Valid {
+			yt.Default = &(d.Default.String)
+		}
+		if len(d.Checks) > 0 {
+			yt.Checks = make(map[string]string, len(d.Checks))
+		}
+		for _, c := range d.Checks {
+			yt.Checks[c.Name] = c.Expr
+		}
+		ys.Types[s.ShortName(d.Namespace, d.Name)] = yt
+	}
+	for _, ct := range s.Composites {
+		yt := &YamlType{Comment: ct.Comment}
+		for _, a := range ct.Attributes {
+			yt.Attributes = append(yt.Attributes, &YamlAttribute{Name: a.Name, Type: a.Type})
+		}
+		ys.Types[s.ShortName(ct.Namespace, ct.Name)] = yt
+	}
+	if len(s.Sequences) > 0 {
+		ys.Sequences = make(map[string]*YamlSequence, len(s.Sequences))
+	}
+	for _, sq := range s.Sequences {
+		ys.Sequences[s.ShortName(sq.Namespace, sq.Name)] = newYamlSequence(sq)
+	}
+	if len(s.Functions) > 0 {
+		ys.Functions = make(map[string]*YamlFunction, len(s.Functions))
+	}
+	for _, f := range s.Functions {
+		ys.Functions[s.ShortName(f.Namespace, f.Name)+"("+f.Arguments+")"] = &YamlFunction{
+			Returns:         f.Returns,
+			Language:        f.Language,
+			Volatility:      f.Volatility,
+			SecurityDefiner: f.SecurityDefiner,
+			Strict:          f.Strict,
+			Leakproof:       f.Leakproof,
+			Parallel:        f.Parallel,
+			Cost:            f.Cost,
+			Set:             f.Config,
+			Body:            f.Body,
+			Comment:         f.Comment,
+		}
+	}
 	for _, t := range s.Tables {
 		yt := &YamlTable{
-			Def:         t.Def,
-			Columns:     make(map[string]*YamlColumn, len(t.Columns)),
-			Constraints: make(map[string]*YamlConstraint, len(t.Constraints)),
-			Indexes:     make(map[string]*YamlIndex, len(t.Indexes)),
-			Relations:   make(map[string]*YamlRelation, len(t.Constraints)),
-			Type:        t.Type,
+			Def:              t.Def,
+			Columns:          make(YamlColumns, 0, len(t.Columns)),
+			Constraints:      make(map[string]*YamlConstraint, len(t.Constraints)),
+			Indexes:          make(map[string]*YamlIndex, len(t.Indexes)),
+			Relations:        make(map[string]*YamlRelation, len(t.Constraints)),
+			Type:             t.Type,
+			RenamedFrom:      t.RenamedFrom,
+			PartitionBy:      t.PartitionBy,
+			Comment:          t.Comment,
+			RowSecurity:      t.RowSecurity,
+			ForceRowSecurity: t.ForceRowSecurity,
+			Owner:            t.Owner,
+			Grants:           newYamlGrants(t.Grants),
+		}
+		for _, d := range t.DependsOn {
+			yt.DependsOn = append(yt.DependsOn, s.ShortName(s.ParseName(d)))
+		}
+		if len(t.DependsOnColumns) > 0 {
+			yt.DependsOnColumns = make(map[string][]string, len(t.DependsOnColumns))
+			for d, cols := range t.DependsOnColumns {
+				yt.DependsOnColumns[s.ShortName(s.ParseName(d))] = cols
+			}
 		}
 		var defval *string
 		for _, c := range t.Columns {
@@ -79,12 +414,24 @@ func (s *Schema) MarshalYAML() ([]byte, error) {
 			if c.Default.Valid {
 				defval = &(c.Default.String)
 			}
-			yt.Columns[c.Name] = &YamlColumn{
-				Type:       c.Type,
-				Default:    defval,
-				Nullable:   c.Nullable,
-				PrimaryKey: c.PrimaryKey,
+			yc := &YamlColumn{
+				Name:        c.Name,
+				Type:        c.Type,
+				Default:     defval,
+				Nullable:    c.Nullable,
+				PrimaryKey:  c.PrimaryKey,
+				Generated:   c.Generated,
+				Using:       c.Using,
+				RenamedFrom: c.RenamedFrom,
+				Comment:     c.Comment,
+			}
+			if c.Identity != nil {
+				yc.Identity = strings.ToLower(c.Identity.Generation)
+				if c.Identity.Sequence != nil {
+					yc.IdentityOptions = newYamlSequence(c.Identity.Sequence)
+				}
 			}
+			yt.Columns = append(yt.Columns, yc)
 		}
 		for _, idx := range t.Indexes {
 			yt.Indexes[idx.Name] = &YamlIndex{
@@ -98,19 +445,79 @@ func (s *Schema) MarshalYAML() ([]byte, error) {
 				Concurrently: idx.Concurrently,
 				ColDef:       idx.ColDef,
 				Tablespace:   idx.Tablespace,
+				RenamedFrom:  idx.RenamedFrom,
+				Comment:      idx.Comment,
+			}
+		}
+		if len(t.Triggers) > 0 {
+			yt.Triggers = make(map[string]*YamlTrigger, len(t.Triggers))
+		}
+		for _, tr := range t.Triggers {
+			yt.Triggers[tr.Name] = &YamlTrigger{
+				Timing:            tr.Timing,
+				Events:            tr.Events,
+				ForEach:           tr.ForEach,
+				When:              tr.When,
+				Function:          tr.Function,
+				Constraint:        tr.Constraint,
+				From:              tr.From,
+				Deferrable:        tr.Deferrable,
+				InitiallyDeferred: tr.InitiallyDeferred,
+				OldTable:          tr.OldTable,
+				NewTable:          tr.NewTable,
+				Def:               tr.Def,
+				Comment:           tr.Comment,
 			}
 		}
+		if len(t.Policies) > 0 {
+			yt.Policies = make(map[string]*YamlPolicy, len(t.Policies))
+		}
+		for _, p := range t.Policies {
+			yt.Policies[p.Name] = &YamlPolicy{
+				Command:     p.Command,
+				Roles:       p.Roles,
+				Using:       p.Using,
+				WithCheck:   p.WithCheck,
+				Restrictive: p.Restrictive,
+				Comment:     p.Comment,
+			}
+		}
+		// generated partitions are declared by rules
+		for _, p := range t.Partitions {
+			if p.generated {
+				continue
+			}
+			if yt.Partitions == nil {
+				yt.Partitions = make(map[string]string, len(t.Partitions))
+			}
+			yt.Partitions[p.Name] = p.Bound
+		}
+		for _, pr := range t.PartitionRules {
+			yt.PartitionRules = append(yt.PartitionRules, &YamlPartitionRule{
+				Interval: pr.Interval,
+				From:     pr.From,
+				To:       pr.To,
+				Format:   pr.Format,
+			})
+		}
 		for _, cs := range t.Constraints {
-			if cs.Type == TypePK {
-				// present as 'pk: true' in columns
+			if cs.Type == TypePK && equalNames(cs.Columns, pkColumns(t)) {
+				// present as 'pk: true' in columns, the key of other order is kept as constraint
 				continue
 			}
 			ycs := &YamlConstraint{
-				Type:             cs.Type,
-				Check:            cs.Check,
-				OnDelete:         cs.OnDelete,
-				Columns:          cs.Columns,
-				ReferenceColumns: cs.ReferenceColumns,
+				Type:              cs.Type,
+				Check:             cs.Check,
+				OnDelete:          cs.OnDelete,
+				OnUpdate:          cs.OnUpdate,
+				Match:             cs.Match,
+				Deferrable:        cs.Deferrable,
+				InitiallyDeferred: cs.InitiallyDeferred,
+				NotValid:          cs.NotValid,
+				Columns:           cs.Columns,
+				ReferenceColumns:  cs.ReferenceColumns,
+				RenamedFrom:       cs.RenamedFrom,
+				Comment:           cs.Comment,
 			}
 			if cs.ReferenceTable != nil {
 				ycs.ReferenceTable = *cs.ReferenceTable
@@ -120,14 +527,21 @@ func (s *Schema) MarshalYAML() ([]byte, error) {
 			yt.Constraints[cs.Name] = ycs
 		}
 		for _, r := range s.Relations {
-			if r.Table.Name != t.Name {
+			if r.Table != t {
 				continue
 			}
 			yr := &YamlRelation{
-				Name:          r.Name,
-				OnDelete:      r.OnDelete,
-				Columns:       make([]string, len(r.Columns)),
-				ParentColumns: make([]string, len(r.ParentColumns)),
+				Name:              r.Name,
+				OnDelete:          r.OnDelete,
+				OnUpdate:          r.OnUpdate,
+				Match:             r.Match,
+				Deferrable:        r.Deferrable,
+				InitiallyDeferred: r.InitiallyDeferred,
+				NotValid:          r.NotValid,
+				RenamedFrom:       r.RenamedFrom,
+				Comment:           r.Comment,
+				Columns:           make([]string, len(r.Columns)),
+				ParentColumns:     make([]string, len(r.ParentColumns)),
 			}
 			for j, v := range r.Columns {
 				yr.Columns[j] = v.Name
@@ -135,9 +549,28 @@ func (s *Schema) MarshalYAML() ([]byte, error) {
 			for j, v := range r.ParentColumns {
 				yr.ParentColumns[j] = v.Name
 			}
-			yt.Relations[r.ParentTable.Name] = yr
+			parent := s.ShortName(r.ParentTable.Namespace, r.ParentTable.Name)
+			if _, ok := yt.Relations[parent]; !ok {
+				yt.Relations[parent] = yr
+				continue
+			}
+			// relations are keyed by parent table, other foreign keys to the same table are constraints
+			yt.Constraints[r.Name] = &YamlConstraint{
+				Type:              TypeFK,
+				OnDelete:          yr.OnDelete,
+				OnUpdate:          yr.OnUpdate,
+				Match:             yr.Match,
+				Deferrable:        yr.Deferrable,
+				InitiallyDeferred: yr.InitiallyDeferred,
+				NotValid:          yr.NotValid,
+				ReferenceTable:    parent,
+				Columns:           yr.Columns,
+				ReferenceColumns:  yr.ParentColumns,
+				RenamedFrom:       yr.RenamedFrom,
+				Comment:           yr.Comment,
+			}
 		}
-		ys.Tables[t.Name] = yt
+		ys.Tables[s.ShortName(t.Namespace, t.Name)] = yt
 	}
 
 	return yaml.Marshal(ys)
@@ -152,23 +585,132 @@ func (s *Schema) UnmarshalYAML(data []byte) error {
 		Name:          ys.Name,
 		CurrentSchema: ys.Schema,
 	}
+	for yname, yn := range ys.Namespaces {
+		s.Namespaces = append(s.Namespaces, &Namespace{
+			Name:    yname,
+			Comment: yn.Comment,
+			Owner:   yn.Owner,
+			Grants:  yn.Grants.grants(),
+		})
+	}
+	for yname, ye := range ys.Extensions {
+		s.Extensions = append(s.Extensions, &Extension{
+			Name:      yname,
+			Version:   ye.Version,
+			Namespace: ye.Schema,
+		})
+	}
+	for yname, yt := range ys.Types {
+		ns, name := s.ParseName(yname)
+		switch {
+		case len(yt.Enum) > 0:
+			s.Enums = append(s.Enums, &Enum{
+				Namespace:     ns,
+				Name:          name,
+				Values:        yt.Enum,
+				Comment:       yt.Comment,
+				RenamedValues: yt.RenamedValues,
+			})
+		case yt.Domain != "":
+			d := &Domain{
+				Namespace: ns,
+				Name:      name,
+				BaseType:  yt.Domain,
+				NotNull:   yt.NotNull,
+				Comment:   yt.Comment,
+			}
+			if yt.Default != nil {
+				d.Default = sql.NullString{String: *yt.Default, Valid: true}
+			}
+			for cname, expr := range yt.Checks {
+				d.Checks = append(d.Checks, &DomainCheck{Name: cname, Expr: expr})
+			}
+			s.Domains = append(s.Domains, d)
+		case len(yt.Attributes) > 0:
+			ct := &CompositeType{
+				Namespace: ns,
+				Name:      name,
+				Comment:   yt.Comment,
+			}
+			for _, ya := range yt.Attributes {
+				ct.Attributes = append(ct.Attributes, &Attribute{Name: ya.Name, Type: ya.Type})
+			}
+			s.Composites = append(s.Composites, ct)
+		}
+	}
+	for yname, ysq := range ys.Sequences {
+		s.Sequences = append(s.Sequences, ysq.sequence(s.ParseName(yname)))
+	}
+	for yname, yf := range ys.Functions {
+		i, j := strings.Index(yname, "("), strings.LastIndex(yname, ")")
+		if i < 0 || j < i {
+			return fmt.Errorf("function %q: arguments not defined", yname)
+		}
+		ns, name := s.ParseName(yname[:i])
+		s.Functions = append(s.Functions, &Function{
+			Namespace:       ns,
+			Name:            name,
+			Arguments:       yname[i+1 : j],
+			Returns:         yf.Returns,
+			Language:        yf.Language,
+			Volatility:      yf.Volatility,
+			SecurityDefiner: yf.SecurityDefiner,
+			Strict:          yf.Strict,
+			Leakproof:       yf.Leakproof,
+			Parallel:        yf.Parallel,
+			Cost:            yf.Cost,
+			Config:          yf.Set,
+			Body:            yf.Body,
+			Comment:         yf.Comment,
+		})
+	}
 	s.Tables = make([]*Table, 0, len(ys.Tables))
 	for tname, yt := range ys.Tables {
+		ns, name := s.ParseName(tname)
 		t := &Table{
-			Name:        tname,
-			Type:        yt.Type,
-			Def:         yt.Def,
-			Columns:     make([]*Column, 0, len(yt.Columns)),
-			Indexes:     make([]*Index, 0, len(yt.Indexes)),
-			Constraints: make([]*Constraint, 0, len(yt.Constraints)),
+			Namespace:        ns,
+			Name:             name,
+			Type:             yt.Type,
+			Def:              yt.Def,
+			RenamedFrom:      yt.RenamedFrom,
+			PartitionBy:      yt.PartitionBy,
+			Comment:          yt.Comment,
+			RowSecurity:      yt.RowSecurity,
+			ForceRowSecurity: yt.ForceRowSecurity,
+			Owner:            yt.Owner,
+			Grants:           yt.Grants.grants(),
+			Columns:          make([]*Column, 0, len(yt.Columns)),
+			Indexes:          make([]*Index, 0, len(yt.Indexes)),
+			Constraints:      make([]*Constraint, 0, len(yt.Constraints)),
+		}
+		for _, d := range yt.DependsOn {
+			t.DependsOn = append(t.DependsOn, QualifiedName(s.ParseName(d)))
+		}
+		if len(yt.DependsOnColumns) > 0 {
+			t.DependsOnColumns = make(map[string][]string, len(yt.DependsOnColumns))
+			for d, cols := range yt.DependsOnColumns {
+				t.DependsOnColumns[QualifiedName(s.ParseName(d))] = cols
+			}
 		}
 
-		for ycname, yc := range yt.Columns {
+		for _, yc := range yt.Columns {
 			c := &Column{
-				Name:       ycname,
-				Type:       yc.Type,
-				Nullable:   yc.Nullable,
-				PrimaryKey: yc.PrimaryKey,
+				Name:        yc.Name,
+				Type:        yc.Type,
+				Nullable:    yc.Nullable,
+				PrimaryKey:  yc.PrimaryKey,
+				Generated:   yc.Generated,
+				Using:       yc.Using,
+				RenamedFrom: yc.RenamedFrom,
+				Comment:     yc.Comment,
+			}
+			if yc.Identity != "" {
+				c.Identity = &Identity{
+					Generation: strings.ToUpper(yc.Identity),
+				}
+				if yc.IdentityOptions != nil {
+					c.Identity.Sequence = yc.IdentityOptions.sequence("", "")
+				}
 			}
 			defnul := sql.NullString{}
 			if yc.Default != nil {
@@ -181,15 +723,19 @@ func (s *Schema) UnmarshalYAML(data []byte) error {
 		}
 
 		for ycname, yc := range yt.Constraints {
+			if yc.Type == TypeFK {
+				continue
+			}
 			c := &Constraint{
 				Name:             ycname,
 				Type:             yc.Type,
 				Check:            yc.Check,
 				OnDelete:         yc.OnDelete,
 				Table:            &t.Name,
-				ReferenceTable:   &yc.ReferenceTable,
 				Columns:          yc.Columns,
 				ReferenceColumns: yc.ReferenceColumns,
+				RenamedFrom:      yc.RenamedFrom,
+				Comment:          yc.Comment,
 			}
 			t.Constraints = append(t.Constraints, c)
 		}
@@ -208,48 +754,75 @@ func (s *Schema) UnmarshalYAML(data []byte) error {
 				With:         yi.With,
 				Tablespace:   yi.Tablespace,
 				Where:        yi.Where,
+				RenamedFrom:  yi.RenamedFrom,
+				Comment:      yi.Comment,
 			}
 			t.Indexes = append(t.Indexes, idx)
 		}
+		for ytname, ytr := range yt.Triggers {
+			t.Triggers = append(t.Triggers, &Trigger{
+				Name:              ytname,
+				Timing:            ytr.Timing,
+				Events:            ytr.Events,
+				ForEach:           ytr.ForEach,
+				When:              ytr.When,
+				Function:          ytr.Function,
+				Constraint:        ytr.Constraint,
+				From:              ytr.From,
+				Deferrable:        ytr.Deferrable,
+				InitiallyDeferred: ytr.InitiallyDeferred,
+				OldTable:          ytr.OldTable,
+				NewTable:          ytr.NewTable,
+				Def:               ytr.Def,
+				Comment:           ytr.Comment,
+			})
+		}
+		for ypname, yp := range yt.Policies {
+			t.Policies = append(t.Policies, &Policy{
+				Name:        ypname,
+				Command:     yp.Command,
+				Roles:       yp.Roles,
+				Using:       yp.Using,
+				WithCheck:   yp.WithCheck,
+				Restrictive: yp.Restrictive,
+				Comment:     yp.Comment,
+			})
+		}
+		for ypname, bound := range yt.Partitions {
+			t.Partitions = append(t.Partitions, &Partition{Name: ypname, Bound: bound})
+		}
+		for _, ypr := range yt.PartitionRules {
+			t.PartitionRules = append(t.PartitionRules, &PartitionRule{
+				Interval: ypr.Interval,
+				From:     ypr.From,
+				To:       ypr.To,
+				Format:   ypr.Format,
+			})
+		}
+		if err := t.GeneratePartitions(); err != nil {
+			return err
+		}
 		s.Tables = append(s.Tables, t)
 	}
 
+	// relations and foreign key constraints are loaded as relations
 	for tname, yt := range ys.Tables {
 		t, err := s.FindTableByName(tname)
 		if err != nil {
 			return err
 		}
 		for yrname, yr := range yt.Relations {
-			relt, err := s.FindTableByName(yrname)
-			if err != nil {
+			if err := s.addYamlRelation(t, yrname, yr); err != nil {
 				return err
 			}
-			r := &Relation{
-				Name:          yr.Name,
-				Table:         t,
-				ParentTable:   relt,
-				Columns:       make([]*Column, 0, len(yr.Columns)),
-				ParentColumns: make([]*Column, 0, len(yr.ParentColumns)),
-				OnDelete:      yr.OnDelete,
-			}
-			for _, yrcl := range yr.Columns {
-				cl, err := t.FindColumnByName(yrcl)
-				if err != nil {
-					return err
-				}
-				r.Columns = append(r.Columns, cl)
-				cl.ParentRelations = append(cl.ParentRelations, r)
+		}
+		for ycname, yc := range yt.Constraints {
+			if yc.Type != TypeFK {
+				continue
 			}
-			for _, yrpcl := range yr.ParentColumns {
-				pcl, err := relt.FindColumnByName(yrpcl)
-				if err != nil {
-					return err
-				}
-				r.ParentColumns = append(r.ParentColumns, pcl)
-				pcl.ChildRelations = append(pcl.ChildRelations, r)
+			if err := s.addYamlRelation(t, yc.ReferenceTable, yc.relation(ycname)); err != nil {
+				return err
 			}
-
-			s.Relations = append(s.Relations, r)
 		}
 	}
 
@@ -258,6 +831,41 @@ func (s *Schema) UnmarshalYAML(data []byte) error {
 	return nil
 }
 
+func (s *Schema) addYamlRelation(t *Table, parent string, yr *YamlRelation) error {
+	r, err := s.NewRelation(t, parent, yr.Columns, yr.ParentColumns)
+	if err != nil {
+		return fmt.Errorf("table %q relation %q: %w", t.FullName(), yr.Name, err)
+	}
+	r.Name = yr.Name
+	r.OnDelete = yr.OnDelete
+	r.OnUpdate = yr.OnUpdate
+	r.Match = yr.Match
+	r.Deferrable = yr.Deferrable
+	r.InitiallyDeferred = yr.InitiallyDeferred
+	r.NotValid = yr.NotValid
+	r.RenamedFrom = yr.RenamedFrom
+	r.Comment = yr.Comment
+	s.Relations = append(s.Relations, r)
+	return nil
+}
+
+// relation returns the foreign key constraint as relation
+func (yc *YamlConstraint) relation(name string) *YamlRelation {
+	return &YamlRelation{
+		Name:              name,
+		Columns:           yc.Columns,
+		ParentColumns:     yc.ReferenceColumns,
+		OnDelete:          yc.OnDelete,
+		OnUpdate:          yc.OnUpdate,
+		Match:             yc.Match,
+		Deferrable:        yc.Deferrable,
+		InitiallyDeferred: yc.InitiallyDeferred,
+		NotValid:          yc.NotValid,
+		RenamedFrom:       yc.RenamedFrom,
+		Comment:           yc.Comment,
+	}
+}
+
 func (s *Schema) SaveYaml(wr io.Writer) error {
 	enc := yaml.NewEncoder(wr)
 	return enc.Encode(s)
@@ -0,0 +1,502 @@
+package schema
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSchema_YamlColumnsOrder(t *testing.T) {
+	src := `name: shop
+schema: public
+tables:
+  products:
+    columns:
+      id:
+        type: uuid
+        pk: true
+      name:
+        type: text
+      code:
+        type: varchar(32)
+      category_id:
+        type: uuid
+      created_at:
+        type: timestamptz
+`
+	s := &Schema{}
+	if err := s.UnmarshalYAML([]byte(src)); err != nil {
+		t.Error(err)
+		return
+	}
+	names := []string{}
+	for _, c := range s.Tables[0].Columns {
+		names = append(names, c.Name)
+	}
+	if strings.Join(names, ",") != "id,name,code,category_id,created_at" {
+		t.Error(names)
+	}
+
+	b, err := s.MarshalYAML()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(b) != src {
+		t.Error(string(b))
+	}
+
+	ps := &PatchSchema{}
+	if err := ps.Build(&Schema{}, s); err != nil {
+		t.Error(err)
+		return
+	}
+	qss := strings.Join(ps.GenerateSQL(), "\n")
+	if qss != `CREATE TABLE public.products (
+id uuid NOT NULL PRIMARY KEY,
+name text NOT NULL,
+code varchar(32) NOT NULL,
+category_id uuid NOT NULL,
+created_at timestamptz NOT NULL)` {
+		t.Error(qss)
+	}
+}
+
+func TestPatchSchema_ColumnOrderDrift(t *testing.T) {
+	newTable := func(names ...string) *Table {
+		tbl := &Table{Name: "t1"}
+		for _, n := range names {
+			tbl.Columns = append(tbl.Columns, &Column{Name: n, Type: "integer"})
+		}
+		return tbl
+	}
+	s := &PatchSchema{}
+	if err := s.Build(
+		&Schema{Tables: []*Table{newTable("id", "b", "a")}},
+		&Schema{Tables: []*Table{newTable("id", "a", "b", "c")}},
+	); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := strings.Join(s.GenerateSQL(), "\n"); qss != "ALTER TABLE public.t1 ADD COLUMN c integer NOT NULL" {
+		t.Error(qss)
+	}
+	if ws := strings.Join(s.Warnings(), "\n"); ws != "table public.t1: columns order (id, b, a) differs from (id, a, b)" {
+		t.Error(ws)
+	}
+}
+
+func TestSchema_YamlForeignKeys(t *testing.T) {
+	src := `name: shop
+schema: public
+tables:
+  orders:
+    columns:
+      id:
+        type: uuid
+        pk: true
+      buyer_id:
+        type: uuid
+      seller_id:
+        type: uuid
+    constraints:
+      orders_seller_fk:
+        type: FOREIGN KEY
+        onUpdate: CASCADE
+        referenceTable: users
+        columns: [seller_id]
+    relations:
+      users:
+        name: orders_buyer_fk
+        columns: [buyer_id]
+        parentColumns: [id]
+        onDelete: CASCADE
+  users:
+    columns:
+      id:
+        type: uuid
+        pk: true
+`
+	s := &Schema{}
+	if err := s.UnmarshalYAML([]byte(src)); err != nil {
+		t.Error(err)
+		return
+	}
+	if len(s.Relations) != 2 || len(s.Tables[0].Constraints) != 0 {
+		t.Errorf("relations %d, constraints %d", len(s.Relations), len(s.Tables[0].Constraints))
+		return
+	}
+	if r := s.Relations[1]; r.Name != "orders_seller_fk" || r.OnUpdate != "CASCADE" || r.ParentColumns[0].Name != "id" {
+		t.Errorf("%+v", r)
+	}
+
+	b, err := s.MarshalYAML()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(b) != strings.Replace(src, "        columns: [seller_id]\n", "        columns: [seller_id]\n        referenceColumns: [id]\n", 1) {
+		t.Error(string(b))
+	}
+
+	// the same foreign keys declared as constraints are not changed
+	users := &Table{Name: "users", Columns: []*Column{{Name: "id", Type: "uuid", PrimaryKey: true}}}
+	orders := &Table{
+		Name: "orders",
+		Columns: []*Column{
+			{Name: "id", Type: "uuid", PrimaryKey: true},
+			{Name: "buyer_id", Type: "uuid"},
+			{Name: "seller_id", Type: "uuid"},
+		},
+		Constraints: []*Constraint{
+			{Name: "orders_buyer_fk", Type: TypeFK, OnDelete: "cascade", ReferenceTable: &users.Name, Columns: []string{"buyer_id"}},
+			{Name: "orders_seller_fk", Type: TypeFK, ReferenceTable: &users.Name, Columns: []string{"seller_id"}},
+		},
+	}
+	ps := &PatchSchema{}
+	if err := ps.Build(s, &Schema{Tables: []*Table{orders, users}}); err != nil {
+		t.Error(err)
+		return
+	}
+	qss := strings.Join(ps.GenerateSQL(), "\n")
+	if qss != `ALTER TABLE public.orders DROP CONSTRAINT IF EXISTS orders_seller_fk
+ALTER TABLE public.orders ADD CONSTRAINT orders_seller_fk FOREIGN KEY (seller_id) REFERENCES public.users (id)` {
+		t.Error(qss)
+	}
+}
+
+func TestSchema_YamlFunctionsAndTriggers(t *testing.T) {
+	src := `name: shop
+schema: public
+functions:
+  set_updated_at():
+    returns: trigger
+    language: plpgsql
+    body: |
+      BEGIN
+        NEW.updated_at = now();
+        RETURN NEW;
+      END;
+tables:
+  users:
+    columns:
+      id:
+        type: uuid
+        pk: true
+      updated_at:
+        type: timestamptz
+    triggers:
+      users_updated_at:
+        timing: BEFORE
+        events: [UPDATE]
+        forEach: ROW
+        function: set_updated_at()
+`
+	s := &Schema{}
+	if err := s.UnmarshalYAML([]byte(src)); err != nil {
+		t.Error(err)
+		return
+	}
+	if len(s.Functions) != 1 || s.Functions[0].Name != "set_updated_at" || s.Functions[0].Arguments != "" {
+		t.Errorf("%+v", s.Functions)
+		return
+	}
+	if err := s.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	b, err := s.MarshalYAML()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(b) != src {
+		t.Error(string(b))
+	}
+}
+
+func TestSchema_YamlDomainsAndComposites(t *testing.T) {
+	src := `name: shop
+schema: public
+types:
+  address:
+    attributes:
+    - name: city
+      type: text
+    - name: zip
+      type: varchar(10)
+  email:
+    domain: text
+    notNull: true
+    checks:
+      email_at: VALUE ~ '@'
+  status:
+    enum: [active, blocked]
+tables:
+  users:
+    columns:
+      email:
+        type: email
+      address:
+        type: address
+        nullable: true
+`
+	s := &Schema{}
+	if err := s.UnmarshalYAML([]byte(src)); err != nil {
+		t.Error(err)
+		return
+	}
+	if len(s.Domains) != 1 || len(s.Domains[0].Checks) != 1 || !s.Domains[0].NotNull {
+		t.Errorf("%+v", s.Domains)
+		return
+	}
+	if len(s.Composites) != 1 || len(s.Composites[0].Attributes) != 2 {
+		t.Errorf("%+v", s.Composites)
+		return
+	}
+	if err := s.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	b, err := s.MarshalYAML()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(b) != src {
+		t.Error(string(b))
+	}
+}
+
+func TestSchema_YamlPartitions(t *testing.T) {
+	src := `name: shop
+schema: public
+tables:
+  events:
+    columns:
+      id:
+        type: bigint
+      created_at:
+        type: date
+    partitionBy: RANGE (created_at)
+    partitions:
+      events_default: DEFAULT
+    partitionRules:
+    - interval: month
+      from: 2024-01-01
+      to: 2024-03-15
+`
+	s := &Schema{}
+	if err := s.UnmarshalYAML([]byte(src)); err != nil {
+		t.Error(err)
+		return
+	}
+	tb, err := s.FindTableByName("events")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	names := []string{}
+	for _, p := range tb.Partitions {
+		names = append(names, p.Name+" "+p.Bound)
+	}
+	if got := strings.Join(names, "\n"); got != `events_2024_01 FOR VALUES FROM ('2024-01-01') TO ('2024-02-01')
+events_2024_02 FOR VALUES FROM ('2024-02-01') TO ('2024-03-01')
+events_2024_03 FOR VALUES FROM ('2024-03-01') TO ('2024-03-15')
+events_default DEFAULT` {
+		t.Error(got)
+	}
+	if err := s.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	b, err := s.MarshalYAML()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(b) != src {
+		t.Error(string(b))
+	}
+}
+
+func TestSchema_YamlPolicies(t *testing.T) {
+	src := `name: shop
+schema: public
+tables:
+  orders:
+    columns:
+      id:
+        type: bigint
+      tenant_id:
+        type: integer
+    rowSecurity: true
+    forceRowSecurity: true
+    policies:
+      readers:
+        command: SELECT
+        roles: [auditor, reader]
+        using: "true"
+      tenant_isolation:
+        using: tenant_id = current_setting('app.tenant')::integer
+        restrictive: true
+`
+	s := &Schema{}
+	if err := s.UnmarshalYAML([]byte(src)); err != nil {
+		t.Error(err)
+		return
+	}
+	tb, err := s.FindTableByName("orders")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !tb.RowSecurity || !tb.ForceRowSecurity || len(tb.Policies) != 2 || !tb.Policies[1].Restrictive {
+		t.Errorf("%+v", tb)
+		return
+	}
+	if err := s.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	b, err := s.MarshalYAML()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(b) != src {
+		t.Error(string(b))
+	}
+}
+
+func TestSchema_YamlGrants(t *testing.T) {
+	src := `name: shop
+schema: public
+namespaces:
+  public:
+    owner: app
+    grants:
+      reporting:
+      - USAGE
+tables:
+  users:
+    columns:
+      id:
+        type: bigint
+      email:
+        type: text
+    owner: app
+    grants:
+      app:
+      - SELECT
+      - INSERT
+      - UPDATE (email)
+      reporting:
+      - SELECT
+`
+	s := &Schema{}
+	if err := s.UnmarshalYAML([]byte(src)); err != nil {
+		t.Error(err)
+		return
+	}
+	tb, err := s.FindTableByName("users")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if tb.Owner != "app" || len(tb.Grants) != 2 || tb.Grants[0].Role != "app" || len(tb.Grants[0].Privileges) != 3 {
+		t.Errorf("%+v", tb.Grants)
+		return
+	}
+	if err := s.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	b, err := s.MarshalYAML()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(b) != src {
+		t.Error(string(b))
+	}
+}
+
+func TestSchema_ParseName(t *testing.T) {
+	s := &Schema{CurrentSchema: "public"}
+	tests := []struct {
+		name, ns, obj string
+	}{
+		{"orders", "public", "orders"},
+		{"sales.orders", "sales", "orders"},
+		{`"a.b"`, "public", "a.b"},
+		{`sales."a.b"`, "sales", "a.b"},
+		{`"my.schema"."a ""b"""`, "my.schema", `a "b"`},
+		{"sales.a.b", "sales", "a.b"},
+	}
+	for _, tt := range tests {
+		ns, obj := s.ParseName(tt.name)
+		if ns != tt.ns || obj != tt.obj {
+			t.Errorf("%s: %q %q", tt.name, ns, obj)
+		}
+		if tt.name != "sales.a.b" && s.ShortName(ns, obj) != tt.name {
+			t.Errorf("%s: short name %s", tt.name, s.ShortName(ns, obj))
+		}
+	}
+}
+
+func TestSchema_YamlDottedNames(t *testing.T) {
+	src := `name: shop
+schema: public
+tables:
+  "\"a.b\"":
+    columns:
+      id:
+        type: uuid
+        pk: true
+    relations:
+      sales."a.b":
+        name: ab_sales_fk
+        columns: [id]
+        parentColumns: [id]
+  sales."a.b":
+    columns:
+      id:
+        type: uuid
+        pk: true
+`
+	s := &Schema{}
+	if err := s.UnmarshalYAML([]byte(src)); err != nil {
+		t.Error(err)
+		return
+	}
+	names := []string{}
+	for _, tbl := range s.Tables {
+		names = append(names, tbl.Namespace+"|"+tbl.Name)
+	}
+	if strings.Join(names, ",") != "public|a.b,sales|a.b" {
+		t.Error(names)
+	}
+
+	b, err := s.MarshalYAML()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(b) != src {
+		t.Error(string(b))
+	}
+
+	ps := &PatchSchema{}
+	if err := ps.Build(&Schema{}, s); err != nil {
+		t.Error(err)
+		return
+	}
+	if qss := strings.Join(ps.GenerateSQL(), "\n"); qss != `CREATE SCHEMA IF NOT EXISTS sales
+CREATE TABLE sales."a.b" (
+id uuid NOT NULL PRIMARY KEY)
+CREATE TABLE public."a.b" (
+id uuid NOT NULL PRIMARY KEY)
+ALTER TABLE public."a.b" ADD CONSTRAINT ab_sales_fk FOREIGN KEY (id) REFERENCES sales."a.b" (id)` {
+		t.Error(qss)
+	}
+}